@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/migrations"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// migrationTarget associe une base ouverte à son jeu de migrations.
+type migrationTarget struct {
+	label string
+	set   string
+	db    *sqlx.DB
+}
+
+// runMigrateCommand implémente `server migrate [up|status]` :
+// applique (ou liste) les migrations de la base PostgreSQL locale et des
+// fichiers SQLite, puis rend la main sans démarrer le serveur HTTP.
+func runMigrateCommand(localDB *sqlx.DB, devicesPath, alertsPath string, args []string) error {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	if action != "up" && action != "status" {
+		return fmt.Errorf("usage: server migrate [up|status]")
+	}
+
+	devicesDB, err := dboperations.OpenSQLite(devicesPath)
+	if err != nil {
+		return err
+	}
+	defer devicesDB.Close()
+
+	alertsDB, err := dboperations.OpenSQLite(alertsPath)
+	if err != nil {
+		return err
+	}
+	defer alertsDB.Close()
+
+	targets := []migrationTarget{
+		{label: "postgres (local)", set: migrations.Postgres, db: localDB},
+		{label: devicesPath, set: migrations.Devices, db: devicesDB},
+		{label: alertsPath, set: migrations.Alerts, db: alertsDB},
+	}
+
+	ctx := context.Background()
+	if action == "up" {
+		for _, t := range targets {
+			applied, err := migrations.Up(ctx, t.db, t.set)
+			if err != nil {
+				return fmt.Errorf("%s: %w", t.label, err)
+			}
+			fmt.Printf("%s: %d migration(s) appliquée(s)\n", t.label, len(applied))
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "BASE\tVERSION\tNOM\tAPPLIQUÉE")
+	for _, t := range targets {
+		st, err := migrations.StatusOf(ctx, t.db, t.set)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.label, err)
+		}
+		for _, s := range st {
+			at := "en attente"
+			if s.AppliedAt != nil {
+				at = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%s\t%04d\t%s\t%s\n", t.label, s.Version, s.Name, at)
+		}
+	}
+	return w.Flush()
+}
+
+// migrateAtStartup applique les migrations de la base locale et du cache des
+// devices avant le chargement de ce dernier. (alerts.sqlite est migrée par
+// dboperations.OpenAlertsDB.)
+func migrateAtStartup(localDB *sqlx.DB, devicesPath string) error {
+	ctx := context.Background()
+	if _, err := migrations.Up(ctx, localDB, migrations.Postgres); err != nil {
+		return fmt.Errorf("postgres (local): %w", err)
+	}
+
+	devicesDB, err := dboperations.OpenSQLite(devicesPath)
+	if err != nil {
+		return err
+	}
+	defer devicesDB.Close()
+	if _, err := migrations.Up(ctx, devicesDB, migrations.Devices); err != nil {
+		return fmt.Errorf("%s: %w", devicesPath, err)
+	}
+	return nil
+}
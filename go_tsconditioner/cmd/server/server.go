@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"go_tsconditioner/internal/config"
 	"io/fs"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -13,10 +16,14 @@ import (
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 
+	"go_tsconditioner/internal/alerting"
 	"go_tsconditioner/internal/auth"
 	"go_tsconditioner/internal/authswitch"
 	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/ingest"
+	"go_tsconditioner/internal/registry"
 	"go_tsconditioner/internal/routeshandlers"
+	"go_tsconditioner/internal/store"
 	staticreact "go_tsconditioner/ui/static-react"
 )
 
@@ -85,22 +92,126 @@ func main() {
 	}
 	defer localpgconn.Close()
 
-	remotepg, err := config.LoadConfigGeneric[config.PGConfig](
-		"",                   // filename (vide -> prend le défaut)
-		"config_remote.json", // defaultFilename
-		true,                 // strict: DisallowUnknownFields
-	)
+	// Cache des devices (config_devices.json optionnel) et, à côté, les règles d'alerte.
+	devicesCfg, found, err := config.LoadOptionalConfig[config.DevicesRegistry]("config_devices.json", true)
+	if err != nil {
+		log.Fatalf("❌ config_devices.json: %v", err)
+	}
+	if !found {
+		log.Printf("ℹ️ Cache devices par défaut (config_devices.json absent)")
+		devicesCfg = config.DefaultDevicesRegistry()
+	}
+	devicesSqlitePath, err := devicesCfg.AbsSQLitePath()
+	if err != nil {
+		log.Fatalf("Chemin du cache devices invalide: %v", err)
+	}
+	alertsSqlitePath := filepath.Join(filepath.Dir(devicesSqlitePath), "alerts.sqlite")
+
+	// Sous-commande : `server migrate [up|status]`
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(localpgconn, devicesSqlitePath, alertsSqlitePath, os.Args[2:]); err != nil {
+			log.Fatalf("❌ migrate: %v", err)
+		}
+		return
+	}
+
+	// Telemetry : PostgreSQL distant, ou fichier SQLite local pour tourner hors ligne
+	// (TELEMETRY_SQLITE=/chemin/telemetry.sqlite, table créée si absente).
+	// Les points poussés (line protocol) vont dans la Telemetry locale :
+	// PostgreSQL local, ou le même fichier SQLite hors ligne.
 	start := time.Now().UTC()
-	remotepgconn, err := dboperations.ConnectDB(&remotepg)
+	var telemetry dboperations.TelemetryRepository
+	var telemetryWriter dboperations.TelemetryWriter = dboperations.NewPostgresTelemetryWriter(localpgconn)
+	// relue par /stream en plus de telemetry quand les points poussés n'y vont pas
+	var localTelemetry dboperations.TelemetryRepository = dboperations.NewPostgresTelemetryRepository(localpgconn)
+	lastSeen, liveHub := ingest.NewLastSeen(), ingest.NewHub()
+	if p := os.Getenv("TELEMETRY_SQLITE"); p != "" {
+		repo, err := dboperations.OpenSQLiteTelemetry(p)
+		if err != nil {
+			log.Fatalf("Impossible d'ouvrir la Telemetry SQLite: %v", err)
+		}
+		defer repo.Close()
+		telemetry, telemetryWriter, localTelemetry = repo, repo, nil
+		log.Printf("🧪 Telemetry hors ligne : %s", p)
+	} else {
+		remotepg, err := config.LoadConfigGeneric[config.PGConfig](
+			"",                   // filename (vide -> prend le défaut)
+			"config_remote.json", // defaultFilename
+			true,                 // strict: DisallowUnknownFields
+		)
+		remotepgconn, err := dboperations.ConnectDB(&remotepg)
+		if err != nil {
+			log.Fatalf("Erreur de connexion DB: %v", err)
+		}
+		defer remotepgconn.Close()
+		telemetry = dboperations.NewPostgresTelemetryRepository(remotepgconn)
+	}
+
+	if err := migrateAtStartup(localpgconn, devicesSqlitePath); err != nil {
+		log.Fatalf("❌ Migrations au démarrage: %v", err)
+	}
+
+	// Registre des devices : cache SQLite au démarrage, puis Telemetry en arrière-plan
+	devices := registry.New(telemetry, devicesSqlitePath, devicesCfg.RefreshInterval())
+	if err := devices.LoadLocal(context.Background()); err != nil {
+		log.Printf("⚠️ Cache devices vide ou illisible (%s), lecture de Telemetry: %v", devicesSqlitePath, err)
+		if _, err := devices.Refresh(context.Background()); err != nil {
+			log.Fatalf("Impossible de charger la liste des devices au démarrage: %v", err)
+		}
+	}
+	log.Printf("✅ %d devices chargés depuis %s", len(devices.Devices()), devicesSqlitePath)
+
+	// Règles et événements d'alerte : SQLite à côté de alldatasources.sqlite
+	alertsdb, err := dboperations.OpenAlertsDB(alertsSqlitePath)
 	if err != nil {
-		log.Fatalf("Erreur de connexion DB: %v", err)
+		log.Fatalf("Impossible d'ouvrir la base des alertes: %v", err)
+	}
+	defer alertsdb.Close()
+
+	// Catalogue des datasources (unités, plages physiques) : même fichier que le cache devices
+	catalogdb, err := dboperations.OpenCatalogDB(devicesSqlitePath)
+	if err != nil {
+		log.Fatalf("Impossible d'ouvrir le catalogue des datasources: %v", err)
 	}
-	defer remotepgconn.Close()
+	defer catalogdb.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go alerting.NewEngine(telemetry, alertsdb, time.Minute).Run(ctx)
+	go devices.Run(ctx)
 
-	devices, err := dboperations.LoadDevicesWithDataSourcesLocal("../../data/alldatasources.sqlite")
+	// Ingestion MQTT (config_mqtt.json optionnel) ; comme /write, elle met à jour
+	// les derniers points vus du rapport et alimente les flux /stream.
+	telemetryWriter = ingest.Tracking(telemetryWriter, lastSeen, liveHub)
+	mqttCfg, found, err := config.LoadOptionalConfig[config.MQTT]("config_mqtt.json", true)
 	if err != nil {
-		log.Fatalf("Impossible de charger la liste des devices au démarrage: %v", err)
+		log.Fatalf("❌ config_mqtt.json: %v", err)
 	}
+	if !found {
+		log.Printf("ℹ️ Ingestion MQTT désactivée (config_mqtt.json absent)")
+	} else {
+		sub, err := ingest.NewMQTTSubscriber(mqttCfg, telemetryWriter)
+		if err != nil {
+			log.Fatalf("❌ config_mqtt.json: %v", err)
+		}
+		go sub.Run(ctx)
+	}
+
+	// TsStore : compression, TTL et budget mémoire (config_store.json optionnel)
+	storeCfg, found, err := config.LoadOptionalConfig[config.Store]("config_store.json", true)
+	if err != nil {
+		log.Fatalf("❌ config_store.json: %v", err)
+	}
+	if !found {
+		log.Printf("ℹ️ TsStore par défaut (config_store.json absent)")
+		storeCfg = config.DefaultStore()
+	}
+	if storeCfg.Compress {
+		store.GlobalTsStore.SetCompression(storeCfg.ChunkPoints)
+	}
+	store.GlobalTsStore.SetLimits(storeCfg.TTL(), storeCfg.BudgetBytes())
+	go store.GlobalTsStore.Run(ctx, time.Minute)
+
 	// Routes publiques
 	router.GET("/timeseries/homecards/:page", routeshandlers.HomeCards())
 	router.POST(spaBaseURL+"timeseries/bulksimul", routeshandlers.BulkSimulator)
@@ -142,6 +253,17 @@ func main() {
 		restricted.Use(verifier.RequireAuth())
 	}
 
+	// Timeouts des requêtes DB par route (config_timeouts.json optionnel)
+	timeouts, found, err := config.LoadOptionalConfig[config.QueryTimeouts]("config_timeouts.json", true)
+	if err != nil {
+		log.Fatalf("❌ config_timeouts.json: %v", err)
+	}
+	if !found {
+		log.Printf("ℹ️ Timeouts DB par défaut (config_timeouts.json absent)")
+		timeouts = config.DefaultQueryTimeouts()
+	}
+	restricted.Use(routeshandlers.QueryTimeout(timeouts))
+
 	// READ group : ouvert en noauth, protégé par rôles en auth
 	read := restricted.Group("")
 	if enabled {
@@ -157,19 +279,51 @@ func main() {
 		})
 	})
 
-	read.GET("/report/latest", routeshandlers.LastSeenPoints_json(remotepgconn))
-	read.POST("/polishing", routeshandlers.Polishing)
-	read.POST("/remotedata", routeshandlers.OneDeviceOneDataSource(remotepgconn))
-	read.POST("/getdatasources", routeshandlers.ListDataSources(remotepgconn))
-	read.GET("/getdevices", func(c *gin.Context) { c.JSON(http.StatusOK, devices) })
-	read.GET("/today/:device", routeshandlers.TodayContainer(remotepgconn, &devices))
-	read.GET("/refreshdevices", routeshandlers.RefreshDevicesDB(remotepgconn))
+	read.GET("/report/latest", routeshandlers.LastSeenPoints_json(telemetry, lastSeen))
+	read.GET("/stream", routeshandlers.StreamTelemetry(telemetry, localTelemetry, liveHub)) // SSE
+	read.POST("/polishing", routeshandlers.Polishing(catalogdb))
+	read.POST("/energy", routeshandlers.EnergyTransform)
+	read.POST("/degreedays", routeshandlers.DegreeDays(telemetry, catalogdb))
+	read.POST("/prometheus/read", routeshandlers.PromRemoteRead(telemetry, catalogdb))
+	read.GET("/export/:file", routeshandlers.Export) // /export/<memId>.csv|.parquet|.arrow
+	read.GET("/store", routeshandlers.ListStore)
+	read.POST("/remotedata", routeshandlers.OneDeviceOneDataSource(telemetry, catalogdb))
+	read.POST("/remotedata/aggregated", routeshandlers.OneDeviceOneDataSourceAggregated(telemetry, catalogdb))
+	read.POST("/getdatasources", routeshandlers.ListDataSources(telemetry))
+	read.GET("/getdevices", func(c *gin.Context) { c.JSON(http.StatusOK, devices.Devices()) })
+	read.GET("/today/:device", routeshandlers.TodayContainer(telemetry, devices, catalogdb))
+	read.GET("/refreshdevices", routeshandlers.RefreshDevicesDB(devices))
+	read.GET("/alerts/rules", routeshandlers.ListAlertRules(alertsdb))
+	read.GET("/alerts/rules/:id", routeshandlers.GetAlertRule(alertsdb))
+	read.GET("/alerts/events", routeshandlers.ListAlertEvents(alertsdb))
+	read.GET("/saved", routeshandlers.ListSavedTimeSeries(localpgconn))
+	read.GET("/saved/:id", routeshandlers.LoadSavedTimeSeries(localpgconn))
+	read.GET("/saved/:id/export/:format", routeshandlers.ExportSaved(localpgconn))
+	read.GET("/catalog", routeshandlers.ListCatalog(catalogdb))
+	read.GET("/catalog/:device/:datasource", routeshandlers.GetCatalogEntry(catalogdb))
+	read.GET("/catalog/:device/:datasource/polishing", routeshandlers.PolishingDefaults(catalogdb))
 
 	// WRITE group : ouvert en noauth, protégé readwrite en auth
 	write := restricted.Group("")
 	if enabled {
 		write.Use(verifier.RequireRoles("readwrite"))
 	}
+
+	write.POST("/save", routeshandlers.SaveTimeSeries(localpgconn))
+	write.POST("/import/csv", routeshandlers.ImportCSV)
+	write.POST("/store", routeshandlers.UploadToStore)
+	write.DELETE("/store/:memId", routeshandlers.DeleteFromStore)
+	write.POST("/write", routeshandlers.WriteLineProtocol(telemetryWriter)) // line protocol InfluxDB
+	write.POST("/prometheus/write", routeshandlers.PromRemoteWrite(telemetryWriter))
+	write.DELETE("/saved/:id", routeshandlers.DeleteSavedTimeSeries(localpgconn))
+	write.POST("/alerts/rules", routeshandlers.CreateAlertRule(alertsdb))
+	write.PUT("/alerts/rules/:id", routeshandlers.UpdateAlertRule(alertsdb))
+	write.DELETE("/alerts/rules/:id", routeshandlers.DeleteAlertRule(alertsdb))
+	write.POST("/alerts/events/:id/ack", routeshandlers.AcknowledgeAlertEvent(alertsdb))
+	write.POST("/alerts/events/:id/resolve", routeshandlers.ResolveAlertEvent(alertsdb))
+	write.PUT("/catalog/:device/:datasource", routeshandlers.PutCatalogEntry(catalogdb))
+	write.DELETE("/catalog/:device/:datasource", routeshandlers.DeleteCatalogEntry(catalogdb))
+
 	elapsed := time.Since(start)
 	log.Printf("⏱️ Temps écoulé depuis start : %v", elapsed)
 
@@ -0,0 +1,156 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/types"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// noDataLookback borne la recherche du dernier point pour les règles "nodata",
+// afin d'éviter un scan complet de Telemetry.
+const noDataLookback = 10
+
+// Engine évalue périodiquement les règles actives contre la table Telemetry
+// (via le TelemetryRepository) et tient à jour les événements d'alerte dans la base SQLite.
+type Engine struct {
+	remote   dboperations.TelemetryRepository
+	alerts   *sqlx.DB
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewEngine(remote dboperations.TelemetryRepository, alerts *sqlx.DB, interval time.Duration) *Engine {
+	return &Engine{
+		remote:   remote,
+		alerts:   alerts,
+		interval: interval,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run évalue les règles toutes les `interval` jusqu'à l'annulation de ctx.
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	log.Printf("🔔 Moteur d'alertes démarré (intervalle %s)", e.interval)
+	for {
+		if err := e.EvaluateAll(ctx); err != nil {
+			log.Printf("❌ Évaluation des alertes: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			log.Println("🔕 Moteur d'alertes arrêté")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EvaluateAll évalue une fois toutes les règles actives. Une règle en erreur
+// n'empêche pas l'évaluation des suivantes.
+func (e *Engine) EvaluateAll(ctx context.Context) error {
+	rules, err := dboperations.ListEnabledAlertRules(ctx, e.alerts)
+	if err != nil {
+		return err
+	}
+	now := e.now()
+	failed := 0
+	for _, rule := range rules {
+		if err := e.evaluateRule(ctx, rule, now); err != nil {
+			log.Printf("❌ Règle %d (%s/%s): %v", rule.ID, rule.Device, rule.DataSource, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d/%d rules failed", failed, len(rules))
+	}
+	return nil
+}
+
+func (e *Engine) evaluateRule(ctx context.Context, rule types.AlertRule, now time.Time) error {
+	outcome, err := e.Evaluate(ctx, rule, now)
+	if err != nil {
+		return err
+	}
+	return e.apply(ctx, rule, outcome, now)
+}
+
+// Evaluate calcule l'état d'une règle à l'instant now, sans modifier les événements.
+func (e *Engine) Evaluate(ctx context.Context, rule types.AlertRule, now time.Time) (Outcome, error) {
+	d := time.Duration(rule.DurationSeconds) * time.Second
+
+	switch rule.Kind {
+	case types.AlertKindThreshold:
+		points, err := e.window(ctx, rule, now.Add(-2*d), now)
+		if err != nil {
+			return Outcome{}, err
+		}
+		return EvalThreshold(points, rule.Operator, rule.Threshold, d, now), nil
+
+	case types.AlertKindRateOfChange:
+		points, err := e.window(ctx, rule, now.Add(-d), now)
+		if err != nil {
+			return Outcome{}, err
+		}
+		return EvalRateOfChange(points, rule.Threshold), nil
+
+	case types.AlertKindNoData:
+		last, found, err := e.remote.LastTime(ctx, rule.Device, rule.DataSource, now.Add(-noDataLookback*d))
+		if err != nil {
+			return Outcome{}, err
+		}
+		return EvalNoData(last, found, d, now), nil
+
+	default:
+		return Outcome{}, fmt.Errorf("unknown rule kind: %s", rule.Kind)
+	}
+}
+
+// window charge les points [from, to] de la règle, triés chronologiquement.
+func (e *Engine) window(ctx context.Context, rule types.AlertRule, from, to time.Time) ([]timeseries.DataUnit, error) {
+	ts, err := e.remote.ReadRange(ctx, dboperations.TelemetryQuery{
+		Device:     rule.Device,
+		DataSource: rule.DataSource,
+		From:       from,
+		To:         to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ts.DataSeries, nil
+}
+
+// apply fait avancer le cycle de vie de l'événement associé à la règle :
+//   - déclenchée sans événement actif  -> ouverture (open)
+//   - déclenchée avec événement actif  -> mise à jour du message / valeur
+//   - non déclenchée avec événement    -> résolution (resolved)
+func (e *Engine) apply(ctx context.Context, rule types.AlertRule, outcome Outcome, now time.Time) error {
+	active, found, err := dboperations.GetActiveAlertEvent(ctx, e.alerts, rule.ID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case outcome.Firing && !found:
+		id, err := dboperations.OpenAlertEvent(ctx, e.alerts, rule, outcome.Message, outcome.Value, now)
+		if err != nil {
+			return err
+		}
+		log.Printf("🚨 Alerte %d ouverte (règle %d %s/%s): %s", id, rule.ID, rule.Device, rule.DataSource, outcome.Message)
+	case outcome.Firing && found:
+		return dboperations.TouchAlertEvent(ctx, e.alerts, active.ID, outcome.Message, outcome.Value, now)
+	case !outcome.Firing && found:
+		if err := dboperations.ResolveAlertEvent(ctx, e.alerts, active.ID, now); err != nil {
+			return err
+		}
+		log.Printf("✅ Alerte %d résolue (règle %d %s/%s)", active.ID, rule.ID, rule.Device, rule.DataSource)
+	}
+	return nil
+}
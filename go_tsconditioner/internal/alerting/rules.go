@@ -0,0 +1,151 @@
+package alerting
+
+import (
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/types"
+	"math"
+	"strings"
+	"time"
+)
+
+// Outcome est le résultat de l'évaluation d'une règle à un instant donné.
+type Outcome struct {
+	Firing  bool
+	Value   *float64 // dernière valeur significative (nil pour "nodata")
+	Message string
+}
+
+// ValidateRule vérifie la cohérence d'une règle avant enregistrement.
+func ValidateRule(r types.AlertRule) error {
+	if strings.TrimSpace(r.Device) == "" {
+		return errors.New("device is required")
+	}
+	if strings.TrimSpace(r.DataSource) == "" {
+		return errors.New("datasource is required")
+	}
+	if r.DurationSeconds <= 0 {
+		return fmt.Errorf("durationSeconds must be > 0, got %d", r.DurationSeconds)
+	}
+	switch r.Kind {
+	case types.AlertKindThreshold:
+		if r.Operator != types.AlertOpAbove && r.Operator != types.AlertOpBelow {
+			return fmt.Errorf("operator must be %q or %q for threshold rules", types.AlertOpAbove, types.AlertOpBelow)
+		}
+	case types.AlertKindRateOfChange:
+		if r.Threshold <= 0 {
+			return errors.New("threshold must be > 0 for rateofchange rules")
+		}
+	case types.AlertKindNoData:
+	default:
+		return fmt.Errorf("unknown rule kind: %s", r.Kind)
+	}
+	if math.IsNaN(r.Threshold) || math.IsInf(r.Threshold, 0) {
+		return errors.New("threshold must be a finite number")
+	}
+	return nil
+}
+
+// EvalThreshold déclenche quand toutes les mesures valides des dernières
+// `d` secondes sont au-delà du seuil. points doit couvrir au moins
+// [now-2d, now] et être trié chronologiquement : le début de la violation
+// est le premier point après la dernière mesure conforme.
+func EvalThreshold(points []timeseries.DataUnit, op string, threshold float64, d time.Duration, now time.Time) Outcome {
+	breaches := func(v float64) bool {
+		if op == types.AlertOpBelow {
+			return v < threshold
+		}
+		return v > threshold
+	}
+
+	var (
+		breachStart time.Time
+		inBreach    bool
+		last        float64
+		haveLast    bool
+	)
+	for _, du := range points {
+		if math.IsNaN(du.Meas) {
+			continue
+		}
+		last, haveLast = du.Meas, true
+		if breaches(du.Meas) {
+			if !inBreach {
+				breachStart = du.Chron
+				inBreach = true
+			}
+		} else {
+			inBreach = false
+		}
+	}
+
+	if !haveLast {
+		return Outcome{Message: "no valid data in window"}
+	}
+	value := last
+	if !inBreach {
+		return Outcome{Value: &value, Message: fmt.Sprintf("value %g within threshold", last)}
+	}
+	since := now.Sub(breachStart)
+	if since < d {
+		return Outcome{Value: &value, Message: fmt.Sprintf("value %g %s %g since %s (< %s)", last, op, threshold, since.Round(time.Second), d)}
+	}
+	return Outcome{
+		Firing:  true,
+		Value:   &value,
+		Message: fmt.Sprintf("value %g %s %g for %s", last, op, threshold, since.Round(time.Second)),
+	}
+}
+
+// EvalRateOfChange déclenche si, entre deux mesures valides consécutives,
+// la variation absolue par seconde dépasse threshold.
+func EvalRateOfChange(points []timeseries.DataUnit, threshold float64) Outcome {
+	var (
+		prev     timeseries.DataUnit
+		havePrev bool
+		maxRate  float64
+		maxAt    time.Time
+	)
+	for _, du := range points {
+		if math.IsNaN(du.Meas) {
+			continue
+		}
+		if havePrev {
+			dt := du.Chron.Sub(prev.Chron).Seconds()
+			if dt > 0 {
+				rate := math.Abs(du.Meas-prev.Meas) / dt
+				if rate > maxRate {
+					maxRate = rate
+					maxAt = du.Chron
+				}
+			}
+		}
+		prev, havePrev = du, true
+	}
+
+	if !havePrev {
+		return Outcome{Message: "no valid data in window"}
+	}
+	value := maxRate
+	if maxRate > threshold {
+		return Outcome{
+			Firing:  true,
+			Value:   &value,
+			Message: fmt.Sprintf("rate of change %g/s above %g/s at %s", maxRate, threshold, maxAt.Format(time.RFC3339)),
+		}
+	}
+	return Outcome{Value: &value, Message: fmt.Sprintf("max rate of change %g/s", maxRate)}
+}
+
+// EvalNoData déclenche si le dernier point reçu date de plus de d.
+func EvalNoData(last time.Time, found bool, d time.Duration, now time.Time) Outcome {
+	if !found {
+		return Outcome{Firing: true, Message: fmt.Sprintf("no data for more than %s", d)}
+	}
+	silence := now.Sub(last)
+	if silence >= d {
+		return Outcome{Firing: true, Message: fmt.Sprintf("no data since %s (%s)", last.Format(time.RFC3339), silence.Round(time.Second))}
+	}
+	return Outcome{Message: fmt.Sprintf("last data at %s", last.Format(time.RFC3339))}
+}
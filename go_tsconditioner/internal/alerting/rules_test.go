@@ -0,0 +1,98 @@
+package alerting
+
+import (
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/types"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2025, 11, 10, 10, 0, 0, 0, time.UTC)
+
+// pts builds one point per minute starting at t0.
+func pts(values ...float64) []timeseries.DataUnit {
+	out := make([]timeseries.DataUnit, len(values))
+	for i, v := range values {
+		out[i] = timeseries.NewDataUnit(t0.Add(time.Duration(i)*time.Minute), v)
+	}
+	return out
+}
+
+func TestEvalThreshold(t *testing.T) {
+	d := 10 * time.Minute
+
+	t.Run("above long enough", func(t *testing.T) {
+		p := pts(70, 85, 85, 85, 85, 85, 85, 85, 85, 85, 85, 85)
+		now := t0.Add(11 * time.Minute)
+		if got := EvalThreshold(p, types.AlertOpAbove, 80, d, now); !got.Firing {
+			t.Fatalf("expected firing, got %+v", got)
+		}
+	})
+	t.Run("above but too recent", func(t *testing.T) {
+		p := pts(70, 70, 70, 70, 70, 70, 85, 85)
+		now := t0.Add(7 * time.Minute)
+		if got := EvalThreshold(p, types.AlertOpAbove, 80, d, now); got.Firing {
+			t.Fatalf("expected not firing, got %+v", got)
+		}
+	})
+	t.Run("back under threshold", func(t *testing.T) {
+		p := pts(85, 85, 85, 85, 85, 85, 85, 85, 85, 85, 85, 60)
+		now := t0.Add(11 * time.Minute)
+		if got := EvalThreshold(p, types.AlertOpAbove, 80, d, now); got.Firing {
+			t.Fatalf("expected not firing, got %+v", got)
+		}
+	})
+	t.Run("below", func(t *testing.T) {
+		p := pts(5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5)
+		now := t0.Add(10 * time.Minute)
+		if got := EvalThreshold(p, types.AlertOpBelow, 10, d, now); !got.Firing {
+			t.Fatalf("expected firing, got %+v", got)
+		}
+	})
+	t.Run("empty", func(t *testing.T) {
+		if got := EvalThreshold(nil, types.AlertOpAbove, 80, d, t0); got.Firing || got.Value != nil {
+			t.Fatalf("expected no outcome, got %+v", got)
+		}
+	})
+}
+
+func TestEvalRateOfChange(t *testing.T) {
+	// 60 units in one minute = 1 unit/s
+	p := pts(0, 1, 61, 62)
+	if got := EvalRateOfChange(p, 0.5); !got.Firing || *got.Value != 1 {
+		t.Fatalf("expected firing at 1/s, got %+v", got)
+	}
+	if got := EvalRateOfChange(p, 2); got.Firing {
+		t.Fatalf("expected not firing, got %+v", got)
+	}
+}
+
+func TestEvalNoData(t *testing.T) {
+	d := 2 * time.Hour
+	if got := EvalNoData(time.Time{}, false, d, t0); !got.Firing {
+		t.Fatalf("no row at all must fire")
+	}
+	if got := EvalNoData(t0.Add(-3*time.Hour), true, d, t0); !got.Firing {
+		t.Fatalf("3h of silence must fire")
+	}
+	if got := EvalNoData(t0.Add(-time.Hour), true, d, t0); got.Firing {
+		t.Fatalf("1h of silence must not fire")
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	ok := types.AlertRule{Device: "d", DataSource: "x", Kind: types.AlertKindThreshold, Operator: types.AlertOpAbove, DurationSeconds: 600}
+	if err := ValidateRule(ok); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bad := ok
+	bad.Operator = "equals"
+	if err := ValidateRule(bad); err == nil {
+		t.Fatalf("expected error for unknown operator")
+	}
+	bad = ok
+	bad.Kind = "whatever"
+	if err := ValidateRule(bad); err == nil {
+		t.Fatalf("expected error for unknown kind")
+	}
+}
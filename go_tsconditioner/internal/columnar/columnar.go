@@ -0,0 +1,223 @@
+// Package columnar exporte les TsContainer au format Arrow (flux IPC) et
+// Parquet, pour une lecture directe dans pandas / polars.
+//
+// Deux dispositions :
+//   - Long : une ligne par point, colonnes series, time, value, status ;
+//   - Wide : une ligne par horodatage, colonne time puis, par série (triées
+//     par clé), <clé> (valeur) et <clé>_status. Une série sans point à cet
+//     horodatage laisse des cellules nulles.
+//
+// Les NaN restent des NaN (et non des nulls) ; time est un timestamp UTC en
+// nanosecondes.
+package columnar
+
+import (
+	"encoding/json"
+	"fmt"
+	"go_tsconditioner/internal/timeseries"
+	"io"
+	"sort"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+type Layout int
+
+const (
+	Long Layout = iota
+	Wide
+)
+
+func (l Layout) String() string {
+	if l == Wide {
+		return "wide"
+	}
+	return "long"
+}
+
+// ParseLayout : "" ou "long" (défaut), "wide".
+func ParseLayout(s string) (Layout, error) {
+	switch s {
+	case "", "long":
+		return Long, nil
+	case "wide":
+		return Wide, nil
+	default:
+		return Long, fmt.Errorf("unknown layout %q (long | wide)", s)
+	}
+}
+
+const statusSuffix = "_status"
+
+var timeType = &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}
+
+// Record construit le RecordBatch du conteneur (à libérer par Release).
+func Record(c *timeseries.TsContainer, layout Layout, mem memory.Allocator) (arrow.RecordBatch, error) {
+	keys := make([]string, 0, len(c.Ts))
+	for k, ts := range c.Ts {
+		if ts != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	md, err := schemaMetadata(c, keys)
+	if err != nil {
+		return nil, err
+	}
+	if layout == Wide {
+		return wideRecord(c, keys, md, mem), nil
+	}
+	return longRecord(c, keys, md, mem), nil
+}
+
+// schemaMetadata garde le nom du conteneur et les unités des séries.
+func schemaMetadata(c *timeseries.TsContainer, keys []string) (arrow.Metadata, error) {
+	units := make(map[string]string)
+	for _, k := range keys {
+		if u := c.Ts[k].Unit; u != "" {
+			units[k] = u
+		}
+	}
+	b, err := json.Marshal(units)
+	if err != nil {
+		return arrow.Metadata{}, err
+	}
+	return arrow.NewMetadata(
+		[]string{"name", "comment", "units"},
+		[]string{c.Name, c.Comment, string(b)},
+	), nil
+}
+
+func longRecord(c *timeseries.TsContainer, keys []string, md arrow.Metadata, mem memory.Allocator) arrow.RecordBatch {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "series", Type: arrow.BinaryTypes.String},
+		{Name: "time", Type: timeType},
+		{Name: "value", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "status", Type: arrow.BinaryTypes.String},
+	}, &md)
+
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	series := b.Field(0).(*array.StringBuilder)
+	times := b.Field(1).(*array.TimestampBuilder)
+	values := b.Field(2).(*array.Float64Builder)
+	status := b.Field(3).(*array.StringBuilder)
+
+	n := 0
+	for _, k := range keys {
+		n += len(c.Ts[k].DataSeries)
+	}
+	for _, fb := range b.Fields() {
+		fb.Reserve(n)
+	}
+	for _, k := range keys {
+		for _, du := range c.Ts[k].DataSeries {
+			series.Append(k)
+			times.Append(arrow.Timestamp(du.Chron.UnixNano()))
+			values.Append(du.Meas)
+			status.Append(du.Status.String())
+		}
+	}
+	return b.NewRecordBatch()
+}
+
+func wideRecord(c *timeseries.TsContainer, keys []string, md arrow.Metadata, mem memory.Allocator) arrow.RecordBatch {
+	fields := []arrow.Field{{Name: "time", Type: timeType}}
+	for _, k := range keys {
+		fields = append(fields,
+			arrow.Field{Name: k, Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+			arrow.Field{Name: k + statusSuffix, Type: arrow.BinaryTypes.String, Nullable: true},
+		)
+	}
+	schema := arrow.NewSchema(fields, &md)
+
+	// union des horodatages, dernier point gagnant en cas de doublon
+	byTime := make([]map[int64]timeseries.DataUnit, len(keys))
+	seen := make(map[int64]struct{})
+	for i, k := range keys {
+		byTime[i] = make(map[int64]timeseries.DataUnit, len(c.Ts[k].DataSeries))
+		for _, du := range c.Ts[k].DataSeries {
+			ns := du.Chron.UnixNano()
+			byTime[i][ns] = du
+			seen[ns] = struct{}{}
+		}
+	}
+	stamps := make([]int64, 0, len(seen))
+	for ns := range seen {
+		stamps = append(stamps, ns)
+	}
+	sort.Slice(stamps, func(i, j int) bool { return stamps[i] < stamps[j] })
+
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	for _, fb := range b.Fields() {
+		fb.Reserve(len(stamps))
+	}
+	times := b.Field(0).(*array.TimestampBuilder)
+	for _, ns := range stamps {
+		times.Append(arrow.Timestamp(ns))
+		for i := range keys {
+			values := b.Field(1 + 2*i).(*array.Float64Builder)
+			status := b.Field(2 + 2*i).(*array.StringBuilder)
+			du, ok := byTime[i][ns]
+			if !ok {
+				values.AppendNull()
+				status.AppendNull()
+				continue
+			}
+			values.Append(du.Meas)
+			status.Append(du.Status.String())
+		}
+	}
+	return b.NewRecordBatch()
+}
+
+// WriteArrowIPC écrit le conteneur en flux Arrow IPC (pyarrow.ipc.open_stream,
+// polars.read_ipc_stream).
+func WriteArrowIPC(w io.Writer, c *timeseries.TsContainer, layout Layout) error {
+	mem := memory.NewGoAllocator()
+	rec, err := Record(c, layout, mem)
+	if err != nil {
+		return err
+	}
+	defer rec.Release()
+
+	iw := ipc.NewWriter(w, ipc.WithSchema(rec.Schema()), ipc.WithAllocator(mem))
+	if err := iw.Write(rec); err != nil {
+		iw.Close()
+		return fmt.Errorf("write arrow ipc: %w", err)
+	}
+	return iw.Close()
+}
+
+// WriteParquet écrit le conteneur en Parquet compressé zstd ; le schéma Arrow
+// est embarqué pour conserver le fuseau de time et les métadonnées.
+func WriteParquet(w io.Writer, c *timeseries.TsContainer, layout Layout) error {
+	mem := memory.NewGoAllocator()
+	rec, err := Record(c, layout, mem)
+	if err != nil {
+		return err
+	}
+	defer rec.Release()
+
+	props := parquet.NewWriterProperties(
+		parquet.WithCompression(compress.Codecs.Zstd),
+		parquet.WithAllocator(mem),
+	)
+	fw, err := pqarrow.NewFileWriter(rec.Schema(), w, props, pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+	if err != nil {
+		return fmt.Errorf("parquet writer: %w", err)
+	}
+	if err := fw.Write(rec); err != nil {
+		fw.Close()
+		return fmt.Errorf("write parquet: %w", err)
+	}
+	return fw.Close()
+}
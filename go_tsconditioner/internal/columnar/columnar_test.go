@@ -0,0 +1,115 @@
+package columnar
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"go_tsconditioner/internal/timeseries"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+func testContainer() *timeseries.TsContainer {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := &timeseries.TimeSeries{Name: "power", Unit: "kW"}
+	p.AddData(t0, 1.5)
+	p.AddDataUnit(timeseries.DataUnit{Chron: t0.Add(time.Minute), Meas: math.NaN(), Status: timeseries.StMissing})
+	t := &timeseries.TimeSeries{Name: "temp", Unit: "°C"}
+	t.AddData(t0.Add(time.Minute), 4)
+	t.AddDataUnit(timeseries.DataUnit{Chron: t0.Add(2 * time.Minute), Meas: 40, Status: timeseries.StOutlier})
+	return &timeseries.TsContainer{Name: "site", Ts: map[string]*timeseries.TimeSeries{"power": p, "temp": t}}
+}
+
+func TestParquetLongRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, testContainer(), Long); err != nil {
+		t.Fatal(err)
+	}
+	rdr, err := file.NewParquetReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Close()
+	fr, err := pqarrow.NewFileReader(rdr, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl, err := fr.ReadTable(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tbl.Release()
+
+	if tbl.NumRows() != 4 || tbl.NumCols() != 4 {
+		t.Fatalf("shape: %d x %d", tbl.NumRows(), tbl.NumCols())
+	}
+	if units := rdr.MetaData().KeyValueMetadata().FindValue("units"); units == nil || *units != `{"power":"kW","temp":"°C"}` {
+		t.Fatalf("units metadata: %v", units)
+	}
+	if tt := tbl.Schema().Field(1).Type.(*arrow.TimestampType); tt.TimeZone != "UTC" {
+		t.Fatalf("time type: %v", tt)
+	}
+	series := tbl.Column(0).Data().Chunk(0).(*array.String)
+	values := tbl.Column(2).Data().Chunk(0).(*array.Float64)
+	status := tbl.Column(3).Data().Chunk(0).(*array.String)
+	if series.Value(0) != "power" || series.Value(2) != "temp" {
+		t.Fatalf("series column: %v", series)
+	}
+	if values.Value(0) != 1.5 || !math.IsNaN(values.Value(1)) || values.IsNull(1) {
+		t.Fatalf("values column: %v", values)
+	}
+	if status.Value(1) != "StMissing" || status.Value(3) != "StOutlier" {
+		t.Fatalf("status column: %v", status)
+	}
+}
+
+func TestArrowIPCWide(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteArrowIPC(&buf, testContainer(), Wide); err != nil {
+		t.Fatal(err)
+	}
+	r, err := ipc.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Release()
+	if !r.Next() {
+		t.Fatal("no record")
+	}
+	rec := r.RecordBatch()
+	names := []string{"time", "power", "power_status", "temp", "temp_status"}
+	if int(rec.NumCols()) != len(names) || rec.NumRows() != 3 {
+		t.Fatalf("shape: %d x %d", rec.NumRows(), rec.NumCols())
+	}
+	for i, n := range names {
+		if rec.ColumnName(i) != n {
+			t.Fatalf("column %d: %s, want %s", i, rec.ColumnName(i), n)
+		}
+	}
+	power := rec.Column(1).(*array.Float64)
+	temp := rec.Column(3).(*array.Float64)
+	// t0 : pas de temp ; t0+1m : power NaN ; t0+2m : pas de power
+	if !temp.IsNull(0) || power.IsNull(1) || !math.IsNaN(power.Value(1)) || !power.IsNull(2) || temp.Value(2) != 40 {
+		t.Fatalf("wide values: power=%v temp=%v", power, temp)
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	if l, err := ParseLayout(""); err != nil || l != Long {
+		t.Fatalf("default: %v %v", l, err)
+	}
+	if l, err := ParseLayout("wide"); err != nil || l != Wide {
+		t.Fatalf("wide: %v %v", l, err)
+	}
+	if _, err := ParseLayout("tall"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DevicesRegistry configure le cache des devices (config_devices.json, optionnel).
+//
+//	{ "SQLITE_PATH": "/srv/tsconditioner/data/alldatasources.sqlite", "REFRESH_MINUTES": 15 }
+//
+// REFRESH_MINUTES <= 0 désactive le rafraîchissement en arrière-plan.
+type DevicesRegistry struct {
+	SQLitePath     string `json:"SQLITE_PATH"`
+	RefreshMinutes int    `json:"REFRESH_MINUTES"`
+}
+
+// DefaultDevicesRegistry reprend l'emplacement historique du cache.
+func DefaultDevicesRegistry() DevicesRegistry {
+	return DevicesRegistry{
+		SQLitePath:     "../../data/alldatasources.sqlite",
+		RefreshMinutes: 15,
+	}
+}
+
+func (d DevicesRegistry) Validate() error {
+	if strings.TrimSpace(d.SQLitePath) == "" {
+		return errors.New("SQLITE_PATH is required")
+	}
+	return nil
+}
+
+// AbsSQLitePath renvoie le chemin absolu du cache, résolu une fois au démarrage
+// pour que toutes les écritures visent le même fichier.
+func (d DevicesRegistry) AbsSQLitePath() (string, error) {
+	return filepath.Abs(d.SQLitePath)
+}
+
+func (d DevicesRegistry) RefreshInterval() time.Duration {
+	if d.RefreshMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(d.RefreshMinutes) * time.Minute
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -69,10 +70,17 @@ func LoadConfigGeneric[T any](filename, defaultFilename string, strict bool) (T,
 		return cfg, nil
 	}
 
+	// fs.ErrNotExist seulement si aucun candidat n'existe : un fichier présent
+	// mais illisible n'est pas un fichier absent
+	reason := errors.New("unreadable")
+	if notFound(tries) {
+		reason = fs.ErrNotExist
+	}
 	var zero T
 	return zero, fmt.Errorf(
-		"config file %q not found/readable.\nexe=%q\nexeDir=%q\ncwd=%q\ntried:\n%s",
+		"config file %q not found/readable (%w).\nexe=%q\nexeDir=%q\ncwd=%q\ntried:\n%s",
 		filename,
+		reason,
 		meta.ExePath,
 		meta.ExeDir,
 		meta.Cwd,
@@ -80,6 +88,31 @@ func LoadConfigGeneric[T any](filename, defaultFilename string, strict bool) (T,
 	)
 }
 
+// LoadOptionalConfig charge un fichier de config facultatif (cherché comme
+// LoadConfigGeneric). found vaut false uniquement si le fichier n'existe pas ;
+// un fichier illisible, mal formé ou refusé par Validate renvoie une erreur,
+// pour ne jamais retomber en silence sur les valeurs par défaut.
+func LoadOptionalConfig[T any](defaultFilename string, strict bool) (cfg T, found bool, err error) {
+	cfg, err = LoadConfigGeneric[T]("", defaultFilename, strict)
+	if errors.Is(err, fs.ErrNotExist) {
+		var zero T
+		return zero, false, nil
+	}
+	if err != nil {
+		return cfg, false, err
+	}
+	return cfg, true, nil
+}
+
+func notFound(tries []triedPath) bool {
+	for _, t := range tries {
+		if !errors.Is(t.Err, fs.ErrNotExist) {
+			return false
+		}
+	}
+	return len(tries) > 0
+}
+
 type triedPath struct {
 	Label string
 	Path  string
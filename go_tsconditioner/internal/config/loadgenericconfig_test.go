@@ -0,0 +1,38 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadOptionalConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	if _, found, err := LoadOptionalConfig[QueryTimeouts](filepath.Join(dir, "absent.json"), true); found || err != nil {
+		t.Fatalf("missing file: found=%v err=%v", found, err)
+	}
+
+	cfg, found, err := LoadOptionalConfig[QueryTimeouts](write("ok.json", `{"DEFAULT_SECONDS": 5}`), true)
+	if err != nil || !found || cfg.DefaultSeconds != 5 {
+		t.Fatalf("valid file: %+v found=%v err=%v", cfg, found, err)
+	}
+
+	// un fichier présent mais faux ne doit jamais donner les valeurs par défaut
+	for name, body := range map[string]string{
+		"typo.json":    `{"DEFAULT_SECONDS": 5,}`,
+		"unknown.json": `{"DEFAULT_SECOND": 5}`,
+		"invalid.json": `{"DEFAULT_SECONDS": -1}`,
+	} {
+		if _, found, err := LoadOptionalConfig[QueryTimeouts](write(name, body), true); err == nil || found {
+			t.Fatalf("%s: found=%v err=%v, want an error", name, found, err)
+		}
+	}
+}
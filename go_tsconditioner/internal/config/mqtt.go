@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MQTT configure l'ingestion en direct (config_mqtt.json, optionnel : absent,
+// le service ne démarre pas).
+//
+//	{
+//	  "BROKER": "tcp://mqtt.local:1883",
+//	  "CLIENT_ID": "tsconditioner",
+//	  "QOS": 1,
+//	  "BATCH_SIZE": 500,
+//	  "FLUSH_SECONDS": 2,
+//	  "TOPICS": [
+//	    { "FILTER": "sites/+/meters/+/power", "DEVICE": "{2}", "DATASOURCE": "power" },
+//	    { "FILTER": "zigbee/+", "DEVICE": "6f1c2a0e-5b7d-4c3e-9a41-0d2b8e7f1a23", "TIME_FIELD": "ts", "TIME_UNIT": "ms" }
+//	  ]
+//	}
+//
+// DEVICE, DATASOURCE et SERIAL sont des modèles : {1}, {2}... reprennent les
+// niveaux du topic captés par les '+' du filtre, dans l'ordre, et {#} la suite
+// captée par '#'. DEVICE doit donner un UUID. Sans DATASOURCE, chaque champ
+// numérique du message JSON devient une datasource du même nom.
+type MQTT struct {
+	Broker       string      `json:"BROKER"`
+	ClientID     string      `json:"CLIENT_ID"`
+	Username     string      `json:"USERNAME"`
+	Password     string      `json:"PASSWORD"`
+	QoS          byte        `json:"QOS"`
+	BatchSize    int         `json:"BATCH_SIZE"`
+	FlushSeconds int         `json:"FLUSH_SECONDS"`
+	Topics       []MQTTTopic `json:"TOPICS"`
+}
+
+// MQTTTopic : VALUE_FIELD vaut "value" par défaut, TIME_FIELD "time" (RFC 3339,
+// ou nombre en TIME_UNIT : "s" par défaut, "ms", "us", "ns"). Sans horodatage
+// dans le message, l'heure de réception est utilisée.
+type MQTTTopic struct {
+	Filter     string `json:"FILTER"`
+	Device     string `json:"DEVICE"`
+	DataSource string `json:"DATASOURCE"`
+	Serial     string `json:"SERIAL"`
+	ValueField string `json:"VALUE_FIELD"`
+	TimeField  string `json:"TIME_FIELD"`
+	TimeUnit   string `json:"TIME_UNIT"`
+}
+
+func (m MQTT) Validate() error {
+	if strings.TrimSpace(m.Broker) == "" {
+		return errors.New("BROKER is required")
+	}
+	if m.QoS > 2 {
+		return fmt.Errorf("QOS must be 0, 1 or 2, got %d", m.QoS)
+	}
+	if m.BatchSize < 0 || m.FlushSeconds < 0 {
+		return errors.New("BATCH_SIZE and FLUSH_SECONDS must be >= 0")
+	}
+	if len(m.Topics) == 0 {
+		return errors.New("at least one entry in TOPICS is required")
+	}
+	for i, t := range m.Topics {
+		if strings.TrimSpace(t.Filter) == "" || strings.TrimSpace(t.Device) == "" {
+			return fmt.Errorf("TOPICS[%d]: FILTER and DEVICE are required", i)
+		}
+		switch t.TimeUnit {
+		case "", "s", "ms", "us", "ns":
+		default:
+			return fmt.Errorf("TOPICS[%d]: TIME_UNIT must be s, ms, us or ns, got %q", i, t.TimeUnit)
+		}
+	}
+	return nil
+}
+
+// Batch renvoie la taille de lot et la période d'écriture (500 lignes, 2 s par défaut).
+func (m MQTT) Batch() (int, time.Duration) {
+	size, flush := m.BatchSize, time.Duration(m.FlushSeconds)*time.Second
+	if size <= 0 {
+		size = 500
+	}
+	if flush <= 0 {
+		flush = 2 * time.Second
+	}
+	return size, flush
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Store configure le TsStore en mémoire (config_store.json, optionnel).
+//
+//	{ "COMPRESS": true, "CHUNK_POINTS": 4096, "TTL_MINUTES": 1440, "MEMORY_BUDGET_MB": 1024 }
+//
+// COMPRESS range les séries en blocs compressés (delta-of-delta et XOR),
+// environ 7 fois plus petits que les []DataUnit, décompressés à chaque accès.
+// CHUNK_POINTS : points par bloc (0 = valeur par défaut du store).
+// TTL_MINUTES : une série non lue depuis ce délai est retirée (0 = jamais).
+// MEMORY_BUDGET_MB : au-delà, les séries les moins récemment utilisées sont
+// évincées (0 = illimité).
+type Store struct {
+	Compress       bool `json:"COMPRESS"`
+	ChunkPoints    int  `json:"CHUNK_POINTS"`
+	TTLMinutes     int  `json:"TTL_MINUTES"`
+	MemoryBudgetMB int  `json:"MEMORY_BUDGET_MB"`
+}
+
+// DefaultStore est utilisé quand config_store.json est absent.
+func DefaultStore() Store {
+	return Store{TTLMinutes: 24 * 60, MemoryBudgetMB: 1024}
+}
+
+func (s Store) Validate() error {
+	if s.ChunkPoints < 0 {
+		return fmt.Errorf("CHUNK_POINTS must be >= 0, got %d", s.ChunkPoints)
+	}
+	if s.TTLMinutes < 0 {
+		return fmt.Errorf("TTL_MINUTES must be >= 0, got %d", s.TTLMinutes)
+	}
+	if s.MemoryBudgetMB < 0 {
+		return fmt.Errorf("MEMORY_BUDGET_MB must be >= 0, got %d", s.MemoryBudgetMB)
+	}
+	return nil
+}
+
+func (s Store) TTL() time.Duration { return time.Duration(s.TTLMinutes) * time.Minute }
+
+func (s Store) BudgetBytes() int64 { return int64(s.MemoryBudgetMB) << 20 }
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// QueryTimeouts borne la durée des requêtes DB par route (config_timeouts.json, optionnel).
+//
+//	{
+//	  "DEFAULT_SECONDS": 30,
+//	  "ROUTES": { "POST /timeseries/remotedata": 120, "GET /timeseries/report/latest": 10 }
+//	}
+//
+// Les clés de ROUTES sont "<METHODE> <motif gin>" (ex: "GET /timeseries/today/:device").
+// Une valeur <= 0 désactive le timeout pour la route. Les flux (untimedRoutes)
+// n'en ont jamais, quoi que dise le fichier.
+type QueryTimeouts struct {
+	DefaultSeconds int            `json:"DEFAULT_SECONDS"`
+	Routes         map[string]int `json:"ROUTES"`
+}
+
+// DefaultQueryTimeouts est utilisé quand config_timeouts.json est absent.
+func DefaultQueryTimeouts() QueryTimeouts {
+	return QueryTimeouts{
+		DefaultSeconds: 30,
+		Routes: map[string]int{
+			"POST /timeseries/remotedata":            120,
+			"POST /timeseries/remotedata/aggregated": 120,
+			"GET /timeseries/refreshdevices":         120,
+		},
+	}
+}
+
+func (t QueryTimeouts) Validate() error {
+	if t.DefaultSeconds < 0 {
+		return fmt.Errorf("DEFAULT_SECONDS must be >= 0, got %d", t.DefaultSeconds)
+	}
+	return nil
+}
+
+// untimedRoutes restent ouvertes indéfiniment (flux SSE) : chaque lecture
+// DB y est bornée à part par le handler.
+var untimedRoutes = map[string]bool{
+	"GET /timeseries/stream": true,
+}
+
+// For renvoie le timeout de la route (0 = pas de timeout).
+func (t QueryTimeouts) For(method, route string) time.Duration {
+	key := method + " " + route
+	if untimedRoutes[key] {
+		return 0
+	}
+	s, ok := t.Routes[key]
+	if !ok {
+		s = t.DefaultSeconds
+	}
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s) * time.Second
+}
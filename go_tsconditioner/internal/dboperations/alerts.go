@@ -0,0 +1,224 @@
+package dboperations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/migrations"
+	"go_tsconditioner/internal/types"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+// ErrAlertNotFound est renvoyée quand une règle ou un événement n'existe pas.
+var ErrAlertNotFound = errors.New("alert rule or event not found")
+
+// OpenAlertsDB ouvre (ou crée) le fichier SQLite des règles d'alerte
+// et applique ses migrations.
+func OpenAlertsDB(sqlitePath string) (*sqlx.DB, error) {
+	db, err := OpenSQLite(sqlitePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := migrations.Up(context.Background(), db, migrations.Alerts); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate alerts db (%s): %w", sqlitePath, err)
+	}
+
+	log.Printf("✅ alerts db prête -> %s", sqlitePath)
+	return db, nil
+}
+
+// ---------------------------------------------------------------- RULES ----
+
+func ListAlertRules(ctx context.Context, db *sqlx.DB) ([]types.AlertRule, error) {
+	rules := []types.AlertRule{}
+	if err := db.SelectContext(ctx, &rules, `SELECT * FROM alert_rules ORDER BY id`); err != nil {
+		return nil, fmt.Errorf("select alert_rules: %w", err)
+	}
+	return rules, nil
+}
+
+func ListEnabledAlertRules(ctx context.Context, db *sqlx.DB) ([]types.AlertRule, error) {
+	rules := []types.AlertRule{}
+	if err := db.SelectContext(ctx, &rules, `SELECT * FROM alert_rules WHERE enabled = 1 ORDER BY id`); err != nil {
+		return nil, fmt.Errorf("select enabled alert_rules: %w", err)
+	}
+	return rules, nil
+}
+
+func GetAlertRule(ctx context.Context, db *sqlx.DB, id int64) (types.AlertRule, error) {
+	var rule types.AlertRule
+	err := db.GetContext(ctx, &rule, `SELECT * FROM alert_rules WHERE id = ?`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return rule, ErrAlertNotFound
+	}
+	if err != nil {
+		return rule, fmt.Errorf("select alert_rule %d: %w", id, err)
+	}
+	return rule, nil
+}
+
+// InsertAlertRule crée la règle et renvoie son ID.
+func InsertAlertRule(ctx context.Context, db *sqlx.DB, rule types.AlertRule) (int64, error) {
+	now := time.Now().UTC()
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO alert_rules (name, device, datasource, kind, operator, threshold, duration_seconds, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rule.Name, rule.Device, rule.DataSource, rule.Kind, rule.Operator,
+		rule.Threshold, rule.DurationSeconds, rule.Enabled, now, now,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("insert alert_rule: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+func UpdateAlertRule(ctx context.Context, db *sqlx.DB, rule types.AlertRule) error {
+	res, err := db.ExecContext(ctx, `
+		UPDATE alert_rules
+		SET name = ?, device = ?, datasource = ?, kind = ?, operator = ?,
+		    threshold = ?, duration_seconds = ?, enabled = ?, updated_at = ?
+		WHERE id = ?`,
+		rule.Name, rule.Device, rule.DataSource, rule.Kind, rule.Operator,
+		rule.Threshold, rule.DurationSeconds, rule.Enabled, time.Now().UTC(), rule.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update alert_rule %d: %w", rule.ID, err)
+	}
+	return expectOneRow(res, ErrAlertNotFound)
+}
+
+// DeleteAlertRule supprime la règle et tout son historique d'événements.
+func DeleteAlertRule(ctx context.Context, db *sqlx.DB, id int64) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin sqlite transaction: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM alert_events WHERE rule_id = ?`, id); err != nil {
+		return fmt.Errorf("delete alert_events (rule %d): %w", id, err)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM alert_rules WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete alert_rule %d: %w", id, err)
+	}
+	if err := expectOneRow(res, ErrAlertNotFound); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit sqlite transaction: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+// --------------------------------------------------------------- EVENTS ----
+
+// ListAlertEvents renvoie les événements, les plus récents d'abord.
+// state vide -> tous les états.
+func ListAlertEvents(ctx context.Context, db *sqlx.DB, state string, limit int) ([]types.AlertEvent, error) {
+	events := []types.AlertEvent{}
+	q := `SELECT * FROM alert_events`
+	args := []any{}
+	if state != "" {
+		q += ` WHERE state = ?`
+		args = append(args, state)
+	}
+	q += ` ORDER BY opened_at DESC, id DESC`
+	if limit > 0 {
+		q += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	if err := db.SelectContext(ctx, &events, q, args...); err != nil {
+		return nil, fmt.Errorf("select alert_events: %w", err)
+	}
+	return events, nil
+}
+
+// GetActiveAlertEvent renvoie l'événement non résolu (open ou acknowledged) d'une règle.
+func GetActiveAlertEvent(ctx context.Context, db *sqlx.DB, ruleID int64) (types.AlertEvent, bool, error) {
+	var ev types.AlertEvent
+	err := db.GetContext(ctx, &ev, `
+		SELECT * FROM alert_events
+		WHERE rule_id = ? AND state <> ?
+		ORDER BY id DESC
+		LIMIT 1`, ruleID, types.AlertStateResolved)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ev, false, nil
+	}
+	if err != nil {
+		return ev, false, fmt.Errorf("select active alert_event (rule %d): %w", ruleID, err)
+	}
+	return ev, true, nil
+}
+
+func OpenAlertEvent(ctx context.Context, db *sqlx.DB, rule types.AlertRule, message string, value *float64, at time.Time) (int64, error) {
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO alert_events (rule_id, device, datasource, state, message, last_value, opened_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		rule.ID, rule.Device, rule.DataSource, types.AlertStateOpen, message, value, at, at,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("insert alert_event (rule %d): %w", rule.ID, err)
+	}
+	return res.LastInsertId()
+}
+
+// TouchAlertEvent met à jour le message et la dernière valeur d'un événement toujours actif.
+func TouchAlertEvent(ctx context.Context, db *sqlx.DB, id int64, message string, value *float64, at time.Time) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE alert_events SET message = ?, last_value = ?, updated_at = ?
+		WHERE id = ?`, message, value, at, id)
+	if err != nil {
+		return fmt.Errorf("update alert_event %d: %w", id, err)
+	}
+	return nil
+}
+
+// AcknowledgeAlertEvent passe un événement "open" à "acknowledged".
+func AcknowledgeAlertEvent(ctx context.Context, db *sqlx.DB, id int64, at time.Time) error {
+	res, err := db.ExecContext(ctx, `
+		UPDATE alert_events SET state = ?, acknowledged_at = ?, updated_at = ?
+		WHERE id = ? AND state = ?`,
+		types.AlertStateAcknowledged, at, at, id, types.AlertStateOpen)
+	if err != nil {
+		return fmt.Errorf("acknowledge alert_event %d: %w", id, err)
+	}
+	return expectOneRow(res, ErrAlertNotFound)
+}
+
+// ResolveAlertEvent passe un événement non résolu à "resolved".
+func ResolveAlertEvent(ctx context.Context, db *sqlx.DB, id int64, at time.Time) error {
+	res, err := db.ExecContext(ctx, `
+		UPDATE alert_events SET state = ?, resolved_at = ?, updated_at = ?
+		WHERE id = ? AND state <> ?`,
+		types.AlertStateResolved, at, at, id, types.AlertStateResolved)
+	if err != nil {
+		return fmt.Errorf("resolve alert_event %d: %w", id, err)
+	}
+	return expectOneRow(res, ErrAlertNotFound)
+}
+
+// expectOneRow renvoie notFound si la requête n'a touché aucune ligne.
+func expectOneRow(res sql.Result, notFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package dboperations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/migrations"
+	"go_tsconditioner/internal/types"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrCatalogEntryNotFound est renvoyée quand la datasource n'a pas de fiche au catalogue.
+var ErrCatalogEntryNotFound = errors.New("catalog entry not found")
+
+// OpenCatalogDB ouvre le fichier du cache devices (alldatasources.sqlite), qui
+// porte aussi le catalogue, et applique ses migrations.
+func OpenCatalogDB(sqlitePath string) (*sqlx.DB, error) {
+	db, err := OpenSQLite(sqlitePath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrations.Up(context.Background(), db, migrations.Devices); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate catalog db (%s): %w", sqlitePath, err)
+	}
+	return db, nil
+}
+
+// ListCatalog renvoie les fiches du catalogue, toutes ou celles d'un device.
+func ListCatalog(ctx context.Context, db *sqlx.DB, device string) ([]types.DataSourceMeta, error) {
+	out := []types.DataSourceMeta{}
+	q := `SELECT * FROM datasource_catalog`
+	var args []any
+	if device != "" {
+		q += ` WHERE device = ?`
+		args = append(args, device)
+	}
+	q += ` ORDER BY device, datasource`
+	if err := db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, fmt.Errorf("select datasource_catalog: %w", err)
+	}
+	return out, nil
+}
+
+func GetCatalogEntry(ctx context.Context, db *sqlx.DB, device, datasource string) (types.DataSourceMeta, error) {
+	var m types.DataSourceMeta
+	err := db.GetContext(ctx, &m, `SELECT * FROM datasource_catalog WHERE device = ? AND datasource = ?`, device, datasource)
+	if errors.Is(err, sql.ErrNoRows) {
+		return m, ErrCatalogEntryNotFound
+	}
+	if err != nil {
+		return m, fmt.Errorf("select datasource_catalog (device=%s, ds=%s): %w", device, datasource, err)
+	}
+	return m, nil
+}
+
+// UpsertCatalogEntry crée ou remplace la fiche (device, datasource).
+func UpsertCatalogEntry(ctx context.Context, db *sqlx.DB, m types.DataSourceMeta) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO datasource_catalog (device, datasource, label, unit, phys_min, phys_max, cadence_seconds, kind, timezone, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (device, datasource) DO UPDATE SET
+			label = excluded.label,
+			unit = excluded.unit,
+			phys_min = excluded.phys_min,
+			phys_max = excluded.phys_max,
+			cadence_seconds = excluded.cadence_seconds,
+			kind = excluded.kind,
+			timezone = excluded.timezone,
+			updated_at = excluded.updated_at`,
+		m.Device, m.DataSource, m.Label, m.Unit, m.PhysMin, m.PhysMax, m.CadenceSeconds, m.Kind, m.Timezone, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("upsert datasource_catalog (device=%s, ds=%s): %w", m.Device, m.DataSource, err)
+	}
+	return nil
+}
+
+func DeleteCatalogEntry(ctx context.Context, db *sqlx.DB, device, datasource string) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM datasource_catalog WHERE device = ? AND datasource = ?`, device, datasource)
+	if err != nil {
+		return fmt.Errorf("delete datasource_catalog (device=%s, ds=%s): %w", device, datasource, err)
+	}
+	return expectOneRow(res, ErrCatalogEntryNotFound)
+}
@@ -0,0 +1,66 @@
+package dboperations
+
+import (
+	"context"
+	"errors"
+	"go_tsconditioner/internal/types"
+	"path/filepath"
+	"testing"
+)
+
+func TestCatalogCRUD(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenCatalogDB(filepath.Join(t.TempDir(), "alldatasources.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	lo, hi := 0.0, 250.0
+	cadence := int64(60)
+	m := types.DataSourceMeta{
+		Device: "dev1", DataSource: "power", Label: "Puissance active", Unit: "kW",
+		PhysMin: &lo, PhysMax: &hi, CadenceSeconds: &cadence,
+		Kind: types.DataSourceKindGauge, Timezone: "Europe/Luxembourg",
+	}
+	if err := UpsertCatalogEntry(ctx, db, m); err != nil {
+		t.Fatal(err)
+	}
+	got, err := GetCatalogEntry(ctx, db, "dev1", "power")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Unit != "kW" || got.PhysMax == nil || *got.PhysMax != 250 || got.CadenceSeconds == nil || *got.CadenceSeconds != 60 {
+		t.Fatalf("round-trip: %+v", got)
+	}
+
+	// upsert : remplace la fiche, les bornes absentes redeviennent NULL
+	m.Unit, m.PhysMin, m.PhysMax = "W", nil, nil
+	if err := UpsertCatalogEntry(ctx, db, m); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = GetCatalogEntry(ctx, db, "dev1", "power")
+	if got.Unit != "W" || got.PhysMin != nil || got.PhysMax != nil {
+		t.Fatalf("upsert: %+v", got)
+	}
+
+	if err := UpsertCatalogEntry(ctx, db, types.DataSourceMeta{Device: "dev2", DataSource: "energy", Kind: types.DataSourceKindCounter}); err != nil {
+		t.Fatal(err)
+	}
+	if all, _ := ListCatalog(ctx, db, ""); len(all) != 2 {
+		t.Fatalf("list all: %d entries", len(all))
+	}
+	if one, _ := ListCatalog(ctx, db, "dev2"); len(one) != 1 || one[0].Kind != types.DataSourceKindCounter {
+		t.Fatalf("list dev2: %+v", one)
+	}
+
+	if err := DeleteCatalogEntry(ctx, db, "dev1", "power"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetCatalogEntry(ctx, db, "dev1", "power"); !errors.Is(err, ErrCatalogEntryNotFound) {
+		t.Fatalf("get after delete: %v", err)
+	}
+	if err := DeleteCatalogEntry(ctx, db, "dev1", "power"); !errors.Is(err, ErrCatalogEntryNotFound) {
+		t.Fatalf("second delete: %v", err)
+	}
+}
@@ -37,3 +37,22 @@ func ConnectDB(cfg *config.PGConfig) (*sqlx.DB, error) {
 	log.Println("\033[34m✅ PostgreSQL prêt\033[0m")
 	return db, nil
 }
+
+// OpenSQLite ouvre (ou crée) un fichier SQLite avec un timeout d'attente
+// de verrou et le journal WAL.
+func OpenSQLite(sqlitePath string) (*sqlx.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", sqlitePath)
+
+	db, err := sqlx.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite db (%s): %w", sqlitePath, err)
+	}
+	// SQLite : un seul écrivain à la fois
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping sqlite db (%s): %w", sqlitePath, err)
+	}
+	return db, nil
+}
@@ -0,0 +1,156 @@
+package dboperations
+
+import (
+	"context"
+	"fmt"
+	"go_tsconditioner/internal/timeseries"
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultTargetPoints est le nombre de buckets visé quand l'appelant n'en précise pas.
+const DefaultTargetPoints = 1000
+
+// MaxBuckets borne le nombre de buckets d'une requête agrégée.
+const MaxBuckets = 100000
+
+// niceBuckets sont les tailles de bucket proposées par ChooseBucket.
+var niceBuckets = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour,
+	24 * time.Hour, 7 * 24 * time.Hour,
+}
+
+// ChooseBucket renvoie la plus petite taille "ronde" de bucket telle que
+// [from, to] tienne en au plus target buckets.
+func ChooseBucket(from, to time.Time, target int) time.Duration {
+	if target <= 0 {
+		target = DefaultTargetPoints
+	}
+	span := to.Sub(from)
+	for _, b := range niceBuckets {
+		if span/b < time.Duration(target) {
+			return b
+		}
+	}
+	// au-delà d'une semaine : multiple entier de jours
+	day := 24 * time.Hour
+	days := (span/time.Duration(target) + day - 1) / day
+	return days * day
+}
+
+type telemetryBucketRow struct {
+	Bucket time.Time `db:"bucket"`
+	Min    float64   `db:"vmin"`
+	Max    float64   `db:"vmax"`
+	Avg    float64   `db:"vavg"`
+	Count  int64     `db:"n"`
+}
+
+// LoadTelemetryBuckets agrège côté PostgreSQL les points d'un couple device/datasource
+// sur [from, to] par buckets de taille bucket (alignés sur l'epoch Unix, donc en UTC).
+//
+// Le container renvoyé contient quatre séries régulières "Average", "Minimum",
+// "Maximum" et "Count", horodatées en fin de bucket comme TimeSeries.Regularize.
+// Les buckets vides sont présents avec Meas = NaN / Status = StMissing (Count = 0).
+func LoadTelemetryBuckets(
+	ctx context.Context,
+	db *sqlx.DB,
+	device string,
+	datasource string,
+	from, to time.Time,
+	bucket time.Duration,
+) (*timeseries.TsContainer, error) {
+
+	first, nb, err := bucketGrid(from, to, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	const q = `
+		SELECT to_timestamp(floor(extract(epoch FROM time) / $5) * $5) AS bucket,
+		       min(value)   AS vmin,
+		       max(value)   AS vmax,
+		       avg(value)   AS vavg,
+		       count(value) AS n
+		FROM "Telemetry"
+		WHERE device = $1
+		  AND datasource = $2
+		  AND time >= $3
+		  AND time <= $4
+		  AND value IS NOT NULL
+		GROUP BY bucket
+		ORDER BY bucket;
+	`
+
+	var rows []telemetryBucketRow
+	if err := db.SelectContext(ctx, &rows, q, device, datasource, from.UTC(), to.UTC(), int64(bucket/time.Second)); err != nil {
+		return nil, fmt.Errorf("select telemetry buckets (device=%s, ds=%s, bucket=%s): %w", device, datasource, bucket, err)
+	}
+
+	return bucketsToContainer(rows, device, datasource, first, nb, bucket), nil
+}
+
+// bucketGrid valide la requête et renvoie le début du premier bucket et leur nombre.
+// La grille est alignée sur l'epoch Unix, comme floor(epoch/sec)*sec côté SQL :
+// time.Truncate s'aligne sur l'an 1 et décale les buckets de plusieurs jours.
+func bucketGrid(from, to time.Time, bucket time.Duration) (time.Time, int, error) {
+	if bucket < time.Second {
+		return time.Time{}, 0, fmt.Errorf("bucket must be >= 1s, got %s", bucket)
+	}
+	if !to.After(from) {
+		return time.Time{}, 0, fmt.Errorf("invalid range: from %s is not before to %s", from, to)
+	}
+	sec := int64(bucket / time.Second)
+	first := time.Unix(from.Unix()/sec*sec, 0).UTC()
+	nb := int(to.Sub(first)/bucket) + 1
+	if nb > MaxBuckets {
+		return time.Time{}, 0, fmt.Errorf("range needs %d buckets of %s (max %d)", nb, bucket, MaxBuckets)
+	}
+	return first, nb, nil
+}
+
+// bucketsToContainer remplit la grille régulière [first, first+nb*bucket) avec
+// les lignes agrégées (triées) et des NaN pour les buckets sans données.
+func bucketsToContainer(rows []telemetryBucketRow, device, datasource string, first time.Time, nb int, bucket time.Duration) *timeseries.TsContainer {
+	avg := &timeseries.TimeSeries{Name: datasource + " Average", DataSeries: make([]timeseries.DataUnit, 0, nb)}
+	mn := &timeseries.TimeSeries{Name: datasource + " Minimum", DataSeries: make([]timeseries.DataUnit, 0, nb)}
+	mx := &timeseries.TimeSeries{Name: datasource + " Maximum", DataSeries: make([]timeseries.DataUnit, 0, nb)}
+	cnt := &timeseries.TimeSeries{Name: datasource + " Count", DataSeries: make([]timeseries.DataUnit, 0, nb)}
+
+	j := 0
+	for i := 0; i < nb; i++ {
+		start := first.Add(time.Duration(i) * bucket)
+		end := start.Add(bucket)
+
+		for j < len(rows) && rows[j].Bucket.Before(start) {
+			j++
+		}
+		if j < len(rows) && rows[j].Bucket.Equal(start) {
+			r := rows[j]
+			avg.AddDataUnit(timeseries.NewDataUnit(end, r.Avg))
+			mn.AddDataUnit(timeseries.NewDataUnit(end, r.Min))
+			mx.AddDataUnit(timeseries.NewDataUnit(end, r.Max))
+			cnt.AddDataUnit(timeseries.NewDataUnit(end, float64(r.Count)))
+			continue
+		}
+		avg.AddDataUnit(timeseries.NewDataUnitWithStatus(end, math.NaN(), timeseries.StMissing))
+		mn.AddDataUnit(timeseries.NewDataUnitWithStatus(end, math.NaN(), timeseries.StMissing))
+		mx.AddDataUnit(timeseries.NewDataUnitWithStatus(end, math.NaN(), timeseries.StMissing))
+		cnt.AddDataUnit(timeseries.NewDataUnit(end, 0))
+	}
+
+	return &timeseries.TsContainer{
+		Name:    fmt.Sprintf("%s / %s @ %s", device, datasource, bucket),
+		Comment: fmt.Sprintf("device=%s; datasource=%s; bucket=%s; buckets=%d", device, datasource, bucket, nb),
+		Ts: map[string]*timeseries.TimeSeries{
+			"Average": avg,
+			"Minimum": mn,
+			"Maximum": mx,
+			"Count":   cnt,
+		},
+	}
+}
@@ -0,0 +1,70 @@
+package dboperations
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestChooseBucket(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		span   time.Duration
+		target int
+		want   time.Duration
+	}{
+		{time.Hour, 1000, 5 * time.Second},
+		{24 * time.Hour, 1000, 5 * time.Minute},
+		{7 * 24 * time.Hour, 1000, 15 * time.Minute},
+		{365 * 24 * time.Hour, 1000, 12 * time.Hour},
+		{365 * 24 * time.Hour, 0, 12 * time.Hour},             // défaut DefaultTargetPoints
+		{10 * 365 * 24 * time.Hour, 100, 37 * 24 * time.Hour}, // au-delà d'une semaine : jours entiers
+	}
+	for _, c := range cases {
+		if got := ChooseBucket(from, from.Add(c.span), c.target); got != c.want {
+			t.Fatalf("span %s target %d: got %s, want %s", c.span, c.target, got, c.want)
+		}
+	}
+}
+
+func TestBucketsToContainerFillsGaps(t *testing.T) {
+	first := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := time.Minute
+	rows := []telemetryBucketRow{
+		{Bucket: first, Min: 1, Max: 3, Avg: 2, Count: 3},
+		{Bucket: first.Add(2 * b), Min: 5, Max: 5, Avg: 5, Count: 1},
+	}
+	c := bucketsToContainer(rows, "dev", "power", first, 4, b)
+
+	avg := c.Ts["Average"].DataSeries
+	cnt := c.Ts["Count"].DataSeries
+	if len(avg) != 4 || len(cnt) != 4 {
+		t.Fatalf("len = %d/%d, want 4", len(avg), len(cnt))
+	}
+	if !avg[0].Chron.Equal(first.Add(b)) {
+		t.Fatalf("buckets must be labelled by their end, got %v", avg[0].Chron)
+	}
+	if avg[0].Meas != 2 || avg[2].Meas != 5 || c.Ts["Maximum"].DataSeries[0].Meas != 3 {
+		t.Fatalf("unexpected values: %+v", avg)
+	}
+	if !math.IsNaN(avg[1].Meas) || !math.IsNaN(avg[3].Meas) {
+		t.Fatalf("empty buckets must be NaN: %+v", avg)
+	}
+	if cnt[1].Meas != 0 || cnt[0].Meas != 3 {
+		t.Fatalf("unexpected counts: %+v", cnt)
+	}
+}
+
+func TestBucketGridAlignedOnEpoch(t *testing.T) {
+	from := time.Date(2025, 3, 5, 13, 0, 0, 0, time.UTC)
+	for _, b := range []time.Duration{3 * 24 * time.Hour, 7 * 24 * time.Hour} {
+		first, _, err := bucketGrid(from, from.Add(30*24*time.Hour), b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sec := int64(b / time.Second)
+		if first.Unix()%sec != 0 || first.After(from) || from.Sub(first) >= b {
+			t.Fatalf("bucket %s: first = %v not epoch-aligned below %v", b, first, from)
+		}
+	}
+}
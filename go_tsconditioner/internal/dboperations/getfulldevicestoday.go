@@ -1,6 +1,7 @@
 package dboperations
 
 import (
+	"context"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -26,82 +27,39 @@ type telemetryRow struct {
 }
 
 func LoadTimeSeriesForDeviceDataSourceToday(
+	ctx context.Context,
 	db *sqlx.DB,
 	deviceID uuid.UUID,
 	datasource string,
 	loc *time.Location,
 ) (*timeseries.TimeSeries, error) {
+	return loadTelemetryDay(ctx, db, deviceID, datasource, StartOfToday(loc), StartOfTomorrow(loc))
+}
 
-	from := StartOfToday(loc)
-	to := StartOfTomorrow(loc)
-
-	const q = `
-		SELECT time, value
-		FROM "Telemetry"
-		WHERE device = $1
-		  AND datasource = $2
-		  AND time >= $3
-		  AND time <  $4
-		ORDER BY time;
-	`
-
-	var rows []telemetryRow
-	if err := db.Select(&rows, q, deviceID.String(), datasource, from, to); err != nil {
-		return nil, fmt.Errorf("select telemetry today (device=%s, ds=%s): %w", deviceID, datasource, err)
-	}
-
-	ts := &timeseries.TimeSeries{
-		Name:    datasource,
-		Comment: fmt.Sprintf("Telemetry for %s (today)", datasource),
-	}
-
-	for _, r := range rows {
-		du := timeseries.DataUnit{
-			Chron: r.T,
-			Meas:  r.V,
+// FindDevice retrouve un device dans la liste en cache.
+func FindDevice(cachedDevices []types.Device, deviceID uuid.UUID) (*types.Device, bool) {
+	for i := range cachedDevices {
+		if cachedDevices[i].DeviceID == deviceID {
+			return &cachedDevices[i], true
 		}
-		ts.AddDataUnit(du)
-
 	}
-	return ts, nil
+	return nil, false
 }
+
 func BuildTodayTsContainerForDevice(
-	db *sqlx.DB,
+	ctx context.Context,
+	repo TelemetryRepository,
 	cachedDevices []types.Device,
 	deviceID uuid.UUID,
 	loc *time.Location,
 ) (*timeseries.TsContainer, error) {
 
 	// 1) retrouver le device dans le cache
-	var dev *types.Device
-	for i := range cachedDevices {
-		if cachedDevices[i].DeviceID == deviceID {
-			dev = &cachedDevices[i]
-			break
-		}
-	}
-	if dev == nil {
+	dev, ok := FindDevice(cachedDevices, deviceID)
+	if !ok {
 		return nil, fmt.Errorf("device not found in cache: %s", deviceID)
 	}
 
-	// 2) container
-	c := &timeseries.TsContainer{
-		Name:    dev.DeviceName,
-		Comment: "All datasources - today",
-		Ts:      make(map[string]*timeseries.TimeSeries),
-	}
-
-	// 3) une TimeSeries par datasource
-	for _, ds := range dev.DataSources {
-		if ds.Name == "" {
-			continue
-		}
-		ts, err := LoadTimeSeriesForDeviceDataSourceToday(db, dev.DeviceID, ds.Name, loc)
-		if err != nil {
-			return nil, err
-		}
-		c.Ts[ds.Name] = ts
-	}
-
-	return c, nil
+	// 2) une TimeSeries par datasource
+	return repo.TodayContainer(ctx, *dev, loc)
 }
@@ -1,13 +1,16 @@
 package dboperations
 
 import (
+	"context"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"go_tsconditioner/internal/migrations"
 	"go_tsconditioner/internal/types"
 	"log"
 	_ "modernc.org/sqlite"
 	"sort"
+	"time"
 )
 
 type deviceDatasourceRow struct {
@@ -16,41 +19,31 @@ type deviceDatasourceRow struct {
 	DataSource string    `db:"datasource"`
 }
 
-func LoadDevicesWithDataSourcesRemote(remoteDB *sqlx.DB) ([]types.Device, error) {
+// RefreshDevicesCache relit les devices actifs du dernier jour dans Telemetry
+// et réécrit la table datasources du fichier SQLite.
+func RefreshDevicesCache(ctx context.Context, repo TelemetryRepository, sqlitePath string) ([]types.Device, error) {
 
-	// 1) Charger depuis Telemetry (PostgreSQL)
-	const selectQ = `
-		SELECT DISTINCT device, serial, datasource
-		FROM "Telemetry"
-		WHERE device IS NOT NULL
-		  AND datasource IS NOT NULL
-		  AND time >= now() - interval '1 day'
-		ORDER BY device, datasource;
-	`
-
-	var rows []deviceDatasourceRow
-	if err := remoteDB.Select(&rows, selectQ); err != nil {
-		log.Printf("❌ Erreur SELECT in Telemetry (LoadDevicesWithDataSourcesRemote): %v", err)
+	// 1) Charger depuis Telemetry
+	devices, err := repo.ListDevices(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		log.Printf("❌ Erreur SELECT in Telemetry (RefreshDevicesCache): %v", err)
 		return nil, err
 	}
 
 	// 2) Ouvrir la DB SQLite fichier
-	sqlitePath := "alldatasources.sqlite"
-	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL", sqlitePath)
-
-	sqliteDB, err := sqlx.Open("sqlite", dsn)
+	sqliteDB, err := OpenSQLite(sqlitePath)
 	if err != nil {
-		return nil, fmt.Errorf("open sqlite db (%s): %w", sqlitePath, err)
+		return nil, err
 	}
 	defer sqliteDB.Close()
 
-	// Optionnel mais recommandé : ping
-	if err := sqliteDB.Ping(); err != nil {
-		return nil, fmt.Errorf("ping sqlite db (%s): %w", err)
+	// 3) S'assurer que la table datasources existe (base neuve)
+	if _, err := migrations.Up(ctx, sqliteDB, migrations.Devices); err != nil {
+		return nil, fmt.Errorf("migrate sqlite db (%s): %w", sqlitePath, err)
 	}
 
 	// 4) Transaction SQLite
-	tx, err := sqliteDB.Beginx()
+	tx, err := sqliteDB.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("begin sqlite transaction: %w", err)
 	}
@@ -62,30 +55,33 @@ func LoadDevicesWithDataSourcesRemote(remoteDB *sqlx.DB) ([]types.Device, error)
 	}()
 
 	// 5) Vider datasources
-	if _, err := tx.Exec(`DELETE FROM datasources`); err != nil {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM datasources`); err != nil {
 		return nil, fmt.Errorf("delete datasources (sqlite): %w", err)
 	}
 
-	// 6) Repeupler livedevices
-	// SQLite: placeholders "?" (sqlx les gère très bien)
+	// 6) Repeupler datasources
 	const insertQ = `
 		INSERT INTO datasources (device, serial, datasource)
 		VALUES (?, ?, ?)
 	`
 
-	stmt, err := tx.Preparex(insertQ)
+	stmt, err := tx.PreparexContext(ctx, insertQ)
 	if err != nil {
 		return nil, fmt.Errorf("prepare insert (sqlite): %w", err)
 	}
 	defer stmt.Close()
 
-	for _, r := range rows {
-		// device est un uuid.UUID -> on le stocke en TEXT
-		if _, err := stmt.Exec(r.Device.String(), r.Serial, r.DataSource); err != nil {
-			return nil, fmt.Errorf(
-				"insert livedevices (device=%s, datasource=%s): %w",
-				r.Device, r.DataSource, err,
-			)
+	n := 0
+	for _, d := range devices {
+		for _, ds := range d.DataSources {
+			// device est un uuid.UUID -> on le stocke en TEXT
+			if _, err := stmt.ExecContext(ctx, d.DeviceID.String(), d.DeviceName, ds.Name); err != nil {
+				return nil, fmt.Errorf(
+					"insert datasources (device=%s, datasource=%s): %w",
+					d.DeviceID, ds.Name, err,
+				)
+			}
+			n++
 		}
 	}
 
@@ -94,47 +90,12 @@ func LoadDevicesWithDataSourcesRemote(remoteDB *sqlx.DB) ([]types.Device, error)
 	}
 	committed = true
 
-	log.Printf("✅ datasources refreshed in sqlite (%d rows) -> %s", len(rows), sqlitePath)
-
-	// 7) Construire la sortie []types.Device (inchangé)
-	byDevice := make(map[uuid.UUID]*types.Device, len(rows))
-	seenDS := make(map[uuid.UUID]map[string]struct{}, len(rows))
-
-	for _, r := range rows {
-		dev, ok := byDevice[r.Device]
-		if !ok {
-			dev = &types.Device{
-				DeviceID:    r.Device,
-				DeviceName:  r.Serial,
-				DataSources: []types.DataSource{},
-			}
-			byDevice[r.Device] = dev
-			seenDS[r.Device] = make(map[string]struct{})
-		}
-
-		if _, exists := seenDS[r.Device][r.DataSource]; !exists {
-			dev.DataSources = append(dev.DataSources, types.DataSource{Name: r.DataSource})
-			seenDS[r.Device][r.DataSource] = struct{}{}
-		}
-	}
-
-	out := make([]types.Device, 0, len(byDevice))
-	for _, d := range byDevice {
-		sort.Slice(d.DataSources, func(i, j int) bool {
-			return d.DataSources[i].Name < d.DataSources[j].Name
-		})
-		out = append(out, *d)
-	}
-
-	sort.Slice(out, func(i, j int) bool {
-		return out[i].DeviceID.String() < out[j].DeviceID.String()
-	})
-
-	return out, nil
+	log.Printf("✅ datasources refreshed in sqlite (%d rows) -> %s", n, sqlitePath)
+	return devices, nil
 }
 
 // La fonction OUVRE elle-même alldatasources.sqlite puis appelle la logique
-func LoadDevicesWithDataSourcesLocal(sqlitePath string) ([]types.Device, error) {
+func LoadDevicesWithDataSourcesLocal(ctx context.Context, sqlitePath string) ([]types.Device, error) {
 
 	const driverName = "sqlite"
 
@@ -144,7 +105,7 @@ func LoadDevicesWithDataSourcesLocal(sqlitePath string) ([]types.Device, error)
 	}
 	defer db.Close()
 
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
 		return nil, fmt.Errorf("ping sqlite db %q: %w", sqlitePath, err)
 	}
 
@@ -157,47 +118,14 @@ func LoadDevicesWithDataSourcesLocal(sqlitePath string) ([]types.Device, error)
 	`
 
 	var rows []deviceDatasourceRow
-	if err := db.Select(&rows, q); err != nil {
+	if err := db.SelectContext(ctx, &rows, q); err != nil {
 		log.Printf("❌ Erreur SELECT in datasources (LoadDevicesWithDataSourcesFromAllDataSourcesDB): %v", err)
 		return nil, err
 	}
 
-	byDevice := make(map[uuid.UUID]*types.Device, len(rows))
-	seenDS := make(map[uuid.UUID]map[string]struct{}, len(rows))
-
-	for _, r := range rows {
-		dev, ok := byDevice[r.Device]
-		if !ok {
-			dev = &types.Device{
-				DeviceID:    r.Device,
-				DeviceName:  r.Serial,
-				DataSources: []types.DataSource{},
-			}
-			byDevice[r.Device] = dev
-			seenDS[r.Device] = make(map[string]struct{})
-		}
-
-		if _, exists := seenDS[r.Device][r.DataSource]; !exists {
-			dev.DataSources = append(dev.DataSources, types.DataSource{Name: r.DataSource})
-			seenDS[r.Device][r.DataSource] = struct{}{}
-		}
-	}
-
-	out := make([]types.Device, 0, len(byDevice))
-	for _, d := range byDevice {
-		sort.Slice(d.DataSources, func(i, j int) bool {
-			return d.DataSources[i].Name < d.DataSources[j].Name
-		})
-		out = append(out, *d)
-	}
-
-	sort.Slice(out, func(i, j int) bool {
-		return out[i].DeviceID.String() < out[j].DeviceID.String()
-	})
-
-	return out, nil
+	return groupDevices(rows), nil
 }
-func LoadDevicesWithDataSourcesLocalOld(db *sqlx.DB) ([]types.Device, error) {
+func LoadDevicesWithDataSourcesLocalOld(ctx context.Context, db *sqlx.DB) ([]types.Device, error) {
 	var q string
 	q = `
 			SELECT DISTINCT device, serial, datasource
@@ -205,7 +133,7 @@ func LoadDevicesWithDataSourcesLocalOld(db *sqlx.DB) ([]types.Device, error) {
 			ORDER BY device, datasource;
 		`
 	var rows []deviceDatasourceRow
-	if err := db.Select(&rows, q); err != nil {
+	if err := db.SelectContext(ctx, &rows, q); err != nil {
 		log.Printf("❌ Erreur SELECT in Telemetry (LoadDevicesWithDataSourcesLocal): %v", err)
 		return nil, err
 	}
@@ -249,7 +177,7 @@ func LoadDevicesWithDataSourcesLocalOld(db *sqlx.DB) ([]types.Device, error) {
 	return out, nil
 }
 
-func LoadDevicesWithDataSourcesRemoteOld(remoteDB *sqlx.DB, localDB *sqlx.DB) ([]types.Device, error) {
+func LoadDevicesWithDataSourcesRemoteOld(ctx context.Context, remoteDB *sqlx.DB, localDB *sqlx.DB) ([]types.Device, error) {
 
 	// 1) Charger depuis Telemetry
 	const selectQ = `
@@ -262,13 +190,13 @@ func LoadDevicesWithDataSourcesRemoteOld(remoteDB *sqlx.DB, localDB *sqlx.DB) ([
 	`
 
 	var rows []deviceDatasourceRow
-	if err := remoteDB.Select(&rows, selectQ); err != nil {
+	if err := remoteDB.SelectContext(ctx, &rows, selectQ); err != nil {
 		log.Printf("❌ Erreur SELECT in Telemetry (LoadDevicesWithDataSourcesRemote): %v", err)
 		return nil, err
 	}
 
 	// 2) Transaction pour livedevices
-	tx, err := localDB.Beginx()
+	tx, err := localDB.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("begin transaction: %w", err)
 	}
@@ -279,7 +207,7 @@ func LoadDevicesWithDataSourcesRemoteOld(remoteDB *sqlx.DB, localDB *sqlx.DB) ([
 	}()
 
 	// 3) Vider livedevices
-	if _, err = tx.Exec(`DELETE FROM "datasources"`); err != nil {
+	if _, err = tx.ExecContext(ctx, `DELETE FROM "datasources"`); err != nil {
 		return nil, fmt.Errorf("delete livedevices: %w", err)
 	}
 
@@ -290,7 +218,7 @@ func LoadDevicesWithDataSourcesRemoteOld(remoteDB *sqlx.DB, localDB *sqlx.DB) ([
 	`
 
 	for _, r := range rows {
-		if _, err = tx.Exec(insertQ, r.Device, r.Serial, r.DataSource); err != nil {
+		if _, err = tx.ExecContext(ctx, insertQ, r.Device, r.Serial, r.DataSource); err != nil {
 			return nil, fmt.Errorf(
 				"insert livedevices (device=%s, datasource=%s): %w",
 				r.Device, r.DataSource, err,
@@ -4,41 +4,77 @@ import (
 	"context"
 	"database/sql"
 	"math"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"go_tsconditioner/internal/timeseries"
 )
 
 // DBTimeSeriesMeta contient tout ce qui doit aller dans la table timeseries,
 // en plus de ce qui est déjà dans timeseries.TimeSeries (Name, MemId).
 type DBTimeSeriesMeta struct {
-	Device      string
-	Serial      sql.NullString
-	Datasource  string
-	FreqSeconds sql.NullInt64
-	Agg         sql.NullString
-
-	Method1  sql.NullString
-	Min1     sql.NullFloat64
-	Max1     sql.NullFloat64
-	Percent1 sql.NullFloat64
-	Lvl1     sql.NullFloat64
-	Method2  sql.NullString
-	Min2     sql.NullFloat64
-	Max2     sql.NullFloat64
-	Percent2 sql.NullFloat64
-	Lvl2     sql.NullFloat64
-	Interp   sql.NullString
+	Device      string         `db:"device"`
+	Serial      sql.NullString `db:"serial"`
+	Datasource  string         `db:"datasource"`
+	FreqSeconds sql.NullInt64  `db:"freq_seconds"`
+	Agg         sql.NullString `db:"agg"`
+
+	Reduce   sql.NullBool    `db:"reduce"`
+	Method1  sql.NullString  `db:"method1"`
+	Min1     sql.NullFloat64 `db:"min1"`
+	Max1     sql.NullFloat64 `db:"max1"`
+	Percent1 sql.NullFloat64 `db:"percent1"`
+	Lvl1     sql.NullFloat64 `db:"lvl1"`
+	Method2  sql.NullString  `db:"method2"`
+	Min2     sql.NullFloat64 `db:"min2"`
+	Max2     sql.NullFloat64 `db:"max2"`
+	Percent2 sql.NullFloat64 `db:"percent2"`
+	Lvl2     sql.NullFloat64 `db:"lvl2"`
+	Interp   sql.NullString  `db:"interp"`
 }
 
+// dataUnitsBatchSize est le nombre de lignes par INSERT multi-lignes, de
+// dataUnitColumns paramètres chacune : 3000 paramètres par requête, loin des
+// limites SQLite (32766) et PostgreSQL (65535).
+const (
+	dataUnitsBatchSize = 500
+	dataUnitColumns    = 6 // timeseries_id, chron, meas, dchron_ns, dmeas, status
+)
+
+// dataUnitsWriter écrit toutes les observations d'une série dans dataunits.
+type dataUnitsWriter func(ctx context.Context, tx *sqlx.Tx, tsID int64, dus []timeseries.DataUnit) error
+
 // SaveTimeSeries insère une TimeSeries dans la table timeseries,
-// puis toutes ses observations dans dataunits, dans une transaction.
+// puis toutes ses observations dans dataunits via COPY, dans une transaction.
 // Elle renvoie l'ID de la ligne timeseries créée.
 func SaveTimeSeries(
 	ctx context.Context,
 	db *sqlx.DB,
 	ts *timeseries.TimeSeries,
 	meta DBTimeSeriesMeta,
+) (int64, error) {
+	return saveTimeSeries(ctx, db, ts, meta, copyDataUnits)
+}
+
+// SaveTimeSeriesBatched fait la même chose que SaveTimeSeries avec des INSERT
+// multi-lignes au lieu de COPY. Elle fonctionne aussi sur SQLite (mêmes tables,
+// cf. migrations.Series), ce qui permet de tester le chemin d'écriture sans PostgreSQL.
+func SaveTimeSeriesBatched(
+	ctx context.Context,
+	db *sqlx.DB,
+	ts *timeseries.TimeSeries,
+	meta DBTimeSeriesMeta,
+) (int64, error) {
+	return saveTimeSeries(ctx, db, ts, meta, batchInsertDataUnits)
+}
+
+func saveTimeSeries(
+	ctx context.Context,
+	db *sqlx.DB,
+	ts *timeseries.TimeSeries,
+	meta DBTimeSeriesMeta,
+	write dataUnitsWriter,
 ) (int64, error) {
 	// 1) Démarrer une transaction
 	tx, err := db.BeginTxx(ctx, &sql.TxOptions{})
@@ -57,56 +93,27 @@ func SaveTimeSeries(
 	var tsID int64
 
 	// IMPORTANT : adapter la liste des colonnes si tu ajoutes/enlèves des champs
-	insertTsSQL := `
+	insertTsSQL := tx.Rebind(`
         INSERT INTO timeseries (
-            name,
-            device,
-            serial,
-            datasource,
-            freq_seconds,
-            agg,
-            method1,
-            min1,
-            max1,
-            percent1,
-            lvl1,
-            method2,
-            min2,
-            max2,
-            percent2,
-            lvl2,
+            name, unit, device, serial, datasource, freq_seconds, agg, reduce,
+            method1, min1, max1, percent1, lvl1,
+            method2, min2, max2, percent2, lvl2,
             interp
         )
-        VALUES (
-            $1,  -- name
-            $2,  -- device
-            $3,  -- serial
-            $4,  -- datasource
-            $5,  -- freq_seconds
-            $6,  -- agg
-            $7,  -- method1
-            $8,  -- min1
-            $9, -- max1
-            $10, -- percent1
-            $11, -- lvl1
-            $12, -- method2
-            $13, -- min2
-            $14, -- max2
-            $15, -- percent2
-            $16, -- lvl2
-            $17  -- interp
-        )
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
         RETURNING id;
-    `
+    `)
 	err = tx.QueryRowContext(
 		ctx,
 		insertTsSQL,
 		ts.Name,
+		ts.Unit,
 		meta.Device,
 		meta.Serial,
 		meta.Datasource,
 		meta.FreqSeconds,
 		meta.Agg,
+		meta.Reduce,
 		meta.Method1,
 		meta.Min1,
 		meta.Max1,
@@ -123,44 +130,80 @@ func SaveTimeSeries(
 		return 0, err
 	}
 
-	// 3) Préparer l'insert pour dataunits
-	insertDuSQL := `
-        INSERT INTO dataunits (
-            timeseries_id,
-            chron,
-            meas,
-            status
-        ) VALUES ($1, $2, $3, $4);
-    `
-
-	stmt, err := tx.PrepareContext(ctx, insertDuSQL)
-	if err != nil {
+	// 3) Observations
+	if err = write(ctx, tx, tsID, ts.DataSeries); err != nil {
+		return 0, err
+	}
+
+	// 4) Commit si tout s’est bien passé
+	if err = tx.Commit(); err != nil {
 		return 0, err
 	}
+
+	return tsID, nil
+}
+
+// dataUnitArgs convertit un DataUnit en valeurs de colonnes :
+// NaN -> NULL pour meas/dmeas, NaDuration -> NULL pour dchron (en ns),
+// et le StatusCode complet en SMALLINT.
+func dataUnitArgs(du timeseries.DataUnit) (meas, dchron, dmeas any, status int16) {
+	if !math.IsNaN(du.Meas) {
+		meas = du.Meas
+	}
+	if du.Dchron != timeseries.NaDuration {
+		dchron = int64(du.Dchron)
+	}
+	if !math.IsNaN(du.Dmeas) {
+		dmeas = du.Dmeas
+	}
+	return meas, dchron, dmeas, int16(du.Status)
+}
+
+// copyDataUnits utilise le protocole COPY de PostgreSQL (pq.CopyIn) :
+// une seule instruction, les lignes sont envoyées en flux.
+func copyDataUnits(ctx context.Context, tx *sqlx.Tx, tsID int64, dus []timeseries.DataUnit) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("dataunits",
+		"timeseries_id", "chron", "meas", "dchron_ns", "dmeas", "status"))
+	if err != nil {
+		return err
+	}
 	defer stmt.Close()
 
-	// 4) Boucler sur les DataUnit
-	for _, du := range ts.DataSeries {
-		// Meas peut être NaN, et tu veux probablement que ce soit NULL en DB
-		var meas *float64
-		if !math.IsNaN(du.Meas) {
-			meas = &du.Meas
-		} else {
-			meas = nil // => NULL en DB
+	for _, du := range dus {
+		meas, dchron, dmeas, status := dataUnitArgs(du)
+		if _, err := stmt.ExecContext(ctx, tsID, du.Chron, meas, dchron, dmeas, status); err != nil {
+			return err
 		}
+	}
+	// Exec sans argument : flush du COPY
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return err
+	}
+	return nil
+}
 
-		// StatusCode est un uint8 ; on le cast vers SMALLINT
-		status := int16(du.Status)
+// batchInsertDataUnits écrit les observations par INSERT multi-lignes
+// de dataUnitsBatchSize lignes.
+func batchInsertDataUnits(ctx context.Context, tx *sqlx.Tx, tsID int64, dus []timeseries.DataUnit) error {
+	for start := 0; start < len(dus); start += dataUnitsBatchSize {
+		end := min(start+dataUnitsBatchSize, len(dus))
+		batch := dus[start:end]
 
-		if _, err = stmt.ExecContext(ctx, tsID, du.Chron, meas, status); err != nil {
-			return 0, err
+		var sb strings.Builder
+		sb.WriteString("INSERT INTO dataunits (timeseries_id, chron, meas, dchron_ns, dmeas, status) VALUES ")
+		args := make([]any, 0, len(batch)*dataUnitColumns)
+		for i, du := range batch {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString("(?, ?, ?, ?, ?, ?)")
+			meas, dchron, dmeas, status := dataUnitArgs(du)
+			args = append(args, tsID, du.Chron, meas, dchron, dmeas, status)
 		}
-	}
 
-	// 5) Commit si tout s’est bien passé
-	if err = tx.Commit(); err != nil {
-		return 0, err
+		if _, err := tx.ExecContext(ctx, tx.Rebind(sb.String()), args...); err != nil {
+			return err
+		}
 	}
-
-	return tsID, nil
+	return nil
 }
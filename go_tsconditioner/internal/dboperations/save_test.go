@@ -0,0 +1,81 @@
+package dboperations
+
+import (
+	"context"
+	"go_tsconditioner/internal/migrations"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/types"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveTimeSeriesBatchedSQLiteRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "series.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := migrations.Up(ctx, db, migrations.Series); err != nil {
+		t.Fatal(err)
+	}
+
+	// plus d'un lot, avec des NaN et tous les StatusCode
+	const n = 2*dataUnitsBatchSize + 17
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts := timeseries.TimeSeries{Name: "roundtrip", Unit: "kWh"}
+	for i := 0; i < n; i++ {
+		meas := float64(i)
+		if i%100 == 7 {
+			meas = math.NaN()
+		}
+		ts.AddDataUnit(timeseries.NewDataUnitWithStatus(start.Add(time.Duration(i)*time.Minute), meas, timeseries.StatusCode(i%6)))
+	}
+	ts.DeltasFiller()
+
+	polish := &types.PolishingRequest{FreqSeconds: 60, Agg: "average", Reduce: true, Method1: "zScore", Lvl1: 3}
+	id, err := SaveTimeSeriesBatched(ctx, db, &ts, MetaFromPolishing("dev-1", "SN1", "power", polish))
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	got, info, err := LoadSavedTimeSeries(ctx, db, id)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if info.Points != n || len(got.DataSeries) != n {
+		t.Fatalf("points: info=%d series=%d, want %d", info.Points, len(got.DataSeries), n)
+	}
+	if info.Polishing == nil || *info.Polishing != *polish {
+		t.Fatalf("polishing = %+v, want %+v", info.Polishing, polish)
+	}
+	if got.Unit != "kWh" || info.Unit != "kWh" {
+		t.Fatalf("unit: series=%q info=%q, want kWh", got.Unit, info.Unit)
+	}
+	if info.Serial == nil || *info.Serial != "SN1" {
+		t.Fatalf("serial = %v", info.Serial)
+	}
+
+	for i, want := range ts.DataSeries {
+		g := got.DataSeries[i]
+		if !g.Chron.Equal(want.Chron) || g.Status != want.Status || g.Dchron != want.Dchron {
+			t.Fatalf("unit %d: got %+v, want %+v", i, g, want)
+		}
+		if !sameFloat(g.Meas, want.Meas) || !sameFloat(g.Dmeas, want.Dmeas) {
+			t.Fatalf("unit %d values: got %+v, want %+v", i, g, want)
+		}
+	}
+
+	if err := DeleteSavedTimeSeries(ctx, db, id); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, _, err := LoadSavedTimeSeries(ctx, db, id); err != ErrSavedSeriesNotFound {
+		t.Fatalf("load after delete: got %v, want ErrSavedSeriesNotFound", err)
+	}
+}
+
+func sameFloat(a, b float64) bool {
+	return a == b || (math.IsNaN(a) && math.IsNaN(b))
+}
@@ -0,0 +1,208 @@
+package dboperations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/types"
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrSavedSeriesNotFound est renvoyée quand l'ID demandé n'existe pas dans timeseries.
+var ErrSavedSeriesNotFound = errors.New("saved time series not found")
+
+// MetaFromPolishing construit la méta DB à partir du device/datasource et,
+// si fournis, des paramètres exacts de polishing.
+func MetaFromPolishing(device, serial, datasource string, p *types.PolishingRequest) DBTimeSeriesMeta {
+	meta := DBTimeSeriesMeta{
+		Device:     device,
+		Serial:     sql.NullString{String: serial, Valid: serial != ""},
+		Datasource: datasource,
+	}
+	if p == nil {
+		return meta
+	}
+	meta.FreqSeconds = sql.NullInt64{Int64: p.FreqSeconds, Valid: true}
+	meta.Agg = sql.NullString{String: p.Agg, Valid: true}
+	meta.Reduce = sql.NullBool{Bool: p.Reduce, Valid: true}
+	meta.Method1 = sql.NullString{String: p.Method1, Valid: true}
+	meta.Min1 = sql.NullFloat64{Float64: p.Min1, Valid: true}
+	meta.Max1 = sql.NullFloat64{Float64: p.Max1, Valid: true}
+	meta.Percent1 = sql.NullFloat64{Float64: p.Percent1, Valid: true}
+	meta.Lvl1 = sql.NullFloat64{Float64: p.Lvl1, Valid: true}
+	meta.Method2 = sql.NullString{String: p.Method2, Valid: true}
+	meta.Min2 = sql.NullFloat64{Float64: p.Min2, Valid: true}
+	meta.Max2 = sql.NullFloat64{Float64: p.Max2, Valid: true}
+	meta.Percent2 = sql.NullFloat64{Float64: p.Percent2, Valid: true}
+	meta.Lvl2 = sql.NullFloat64{Float64: p.Lvl2, Valid: true}
+	meta.Interp = sql.NullString{String: p.Interp, Valid: true}
+	return meta
+}
+
+// Polishing reconstruit la PolishingRequest enregistrée (nil si la série
+// a été sauvegardée sans paramètres). MemId n'a pas de sens en base et vaut 0.
+func (m DBTimeSeriesMeta) Polishing() *types.PolishingRequest {
+	if !m.FreqSeconds.Valid && !m.Agg.Valid && !m.Reduce.Valid &&
+		!m.Method1.Valid && !m.Method2.Valid && !m.Interp.Valid {
+		return nil
+	}
+	return &types.PolishingRequest{
+		Reduce:      m.Reduce.Bool,
+		FreqSeconds: m.FreqSeconds.Int64,
+		Agg:         m.Agg.String,
+		Method1:     m.Method1.String,
+		Min1:        m.Min1.Float64,
+		Max1:        m.Max1.Float64,
+		Percent1:    m.Percent1.Float64,
+		Lvl1:        m.Lvl1.Float64,
+		Method2:     m.Method2.String,
+		Min2:        m.Min2.Float64,
+		Max2:        m.Max2.Float64,
+		Percent2:    m.Percent2.Float64,
+		Lvl2:        m.Lvl2.Float64,
+		Interp:      m.Interp.String,
+	}
+}
+
+type savedTimeSeriesRow struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+	Unit string `db:"unit"`
+	DBTimeSeriesMeta
+	Points int `db:"points"`
+}
+
+func (r savedTimeSeriesRow) info() types.SavedTimeSeriesInfo {
+	info := types.SavedTimeSeriesInfo{
+		ID:         r.ID,
+		Name:       r.Name,
+		Unit:       r.Unit,
+		Device:     r.Device,
+		DataSource: r.Datasource,
+		Points:     r.Points,
+		Polishing:  r.DBTimeSeriesMeta.Polishing(),
+	}
+	if r.Serial.Valid {
+		serial := r.Serial.String
+		info.Serial = &serial
+	}
+	return info
+}
+
+const selectSavedTimeSeries = `
+	SELECT t.id, t.name, t.unit, t.device, t.serial, t.datasource, t.freq_seconds, t.agg, t.reduce,
+	       t.method1, t.min1, t.max1, t.percent1, t.lvl1,
+	       t.method2, t.min2, t.max2, t.percent2, t.lvl2, t.interp,
+	       (SELECT count(*) FROM dataunits d WHERE d.timeseries_id = t.id) AS points
+	FROM timeseries t
+`
+
+// ListSavedTimeSeries renvoie toutes les séries persistées, les plus récentes d'abord.
+func ListSavedTimeSeries(ctx context.Context, db *sqlx.DB) ([]types.SavedTimeSeriesInfo, error) {
+	var rows []savedTimeSeriesRow
+	if err := db.SelectContext(ctx, &rows, selectSavedTimeSeries+` ORDER BY t.id DESC`); err != nil {
+		return nil, fmt.Errorf("select timeseries: %w", err)
+	}
+	out := make([]types.SavedTimeSeriesInfo, len(rows))
+	for i, r := range rows {
+		out[i] = r.info()
+	}
+	return out, nil
+}
+
+type dataUnitRow struct {
+	Chron    time.Time       `db:"chron"`
+	Meas     sql.NullFloat64 `db:"meas"`
+	DchronNS sql.NullInt64   `db:"dchron_ns"`
+	Dmeas    sql.NullFloat64 `db:"dmeas"`
+	Status   int16           `db:"status"`
+}
+
+// LoadSavedTimeSeries relit une série persistée et ses observations.
+// La TimeSeries renvoyée n'a pas de MemId : c'est à l'appelant de la placer dans le store.
+func LoadSavedTimeSeries(ctx context.Context, db *sqlx.DB, id int64) (*timeseries.TimeSeries, types.SavedTimeSeriesInfo, error) {
+	var row savedTimeSeriesRow
+	err := db.GetContext(ctx, &row, db.Rebind(selectSavedTimeSeries+` WHERE t.id = ?`), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, types.SavedTimeSeriesInfo{}, ErrSavedSeriesNotFound
+	}
+	if err != nil {
+		return nil, types.SavedTimeSeriesInfo{}, fmt.Errorf("select timeseries %d: %w", id, err)
+	}
+
+	var dus []dataUnitRow
+	q := db.Rebind(`
+		SELECT chron, meas, dchron_ns, dmeas, status
+		FROM dataunits
+		WHERE timeseries_id = ?
+		ORDER BY chron;
+	`)
+	if err := db.SelectContext(ctx, &dus, q, id); err != nil {
+		return nil, types.SavedTimeSeriesInfo{}, fmt.Errorf("select dataunits (timeseries %d): %w", id, err)
+	}
+
+	ts := &timeseries.TimeSeries{
+		Name:       row.Name,
+		Unit:       row.Unit,
+		Comment:    fmt.Sprintf("saved series #%d (device=%s; datasource=%s)", row.ID, row.Device, row.Datasource),
+		DataSeries: make([]timeseries.DataUnit, len(dus)),
+	}
+	for i, r := range dus {
+		du := timeseries.DataUnit{
+			Chron:  r.Chron,
+			Meas:   math.NaN(),
+			Dchron: timeseries.NaDuration,
+			Dmeas:  math.NaN(),
+			Status: timeseries.StatusCode(r.Status),
+		}
+		if r.Meas.Valid {
+			du.Meas = r.Meas.Float64
+		}
+		if r.DchronNS.Valid {
+			du.Dchron = time.Duration(r.DchronNS.Int64)
+		}
+		if r.Dmeas.Valid {
+			du.Dmeas = r.Dmeas.Float64
+		}
+		ts.DataSeries[i] = du
+	}
+	return ts, row.info(), nil
+}
+
+// DeleteSavedTimeSeries supprime une série et ses observations dans une transaction.
+func DeleteSavedTimeSeries(ctx context.Context, db *sqlx.DB, id int64) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM dataunits WHERE timeseries_id = ?`), id); err != nil {
+		return fmt.Errorf("delete dataunits (timeseries %d): %w", id, err)
+	}
+	res, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM timeseries WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("delete timeseries %d: %w", id, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrSavedSeriesNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
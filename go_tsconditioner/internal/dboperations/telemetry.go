@@ -41,6 +41,17 @@ func mapStateToStatusCode(state string) timeseries.StatusCode {
 	}
 }
 
+// DataUnit convertit une ligne seule (Dchron / Dmeas non renseignés).
+func (t Telemetry) DataUnit() timeseries.DataUnit {
+	return timeseries.DataUnit{
+		Chron:  t.Time,
+		Meas:   t.Value,
+		Dchron: timeseries.NaDuration,
+		Dmeas:  math.NaN(),
+		Status: mapStateToStatusCode(t.State),
+	}
+}
+
 // Transforme les lignes Telemetry en un TimeSeries
 func TelemetryRowsToTimeSeries(rows []Telemetry) (*timeseries.TimeSeries, error) {
 	if len(rows) == 0 {
@@ -0,0 +1,187 @@
+package dboperations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/timeseries"
+	"math"
+	"time"
+	"unsafe"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MaxTelemetryPoints est le plafond par défaut de points lus par un TelemetryCursor
+// (≈ 110 Mo de DataUnit). Au-delà, la lecture s'arrête avec ErrTelemetryTooLarge.
+var MaxTelemetryPoints = 2_000_000
+
+// ErrTelemetryTooLarge est renvoyée (enveloppée) quand une lecture dépasse le plafond de points.
+var ErrTelemetryTooLarge = errors.New("telemetry range exceeds the point ceiling")
+
+// TelemetryQuery décrit une lecture de Telemetry pour un couple device/datasource
+// sur [From, To]. Limit > 0 tronque aux Limit premiers points ; MaxPoints <= 0
+// applique MaxTelemetryPoints.
+type TelemetryQuery struct {
+	Device     string
+	DataSource string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	MaxPoints  int
+}
+
+// telemetryMeta garde les colonnes descriptives de la première ligne lue.
+type telemetryMeta struct {
+	device, manufacturer, model, serial, state string
+}
+
+// TelemetryCursor lit Telemetry ligne à ligne (ORDER BY time côté SQL) et
+// construit les DataUnit au fil de l'eau, Dchron/Dmeas compris, sans jamais
+// matérialiser le résultat complet de la requête.
+//
+//	cur, err := OpenTelemetryCursor(ctx, db, q)
+//	defer cur.Close()
+//	for cur.Next() { du := cur.DataUnit() ... }
+//	if err := cur.Err(); err != nil { ... }
+type TelemetryCursor struct {
+	ctx   context.Context
+	rows  *sqlx.Rows
+	q     TelemetryQuery
+	max   int
+	n     int
+	cur   timeseries.DataUnit
+	first *telemetryMeta
+	err   error
+}
+
+// OpenTelemetryCursor lance la requête ; l'annulation de ctx interrompt la lecture.
+func OpenTelemetryCursor(ctx context.Context, db *sqlx.DB, q TelemetryQuery) (*TelemetryCursor, error) {
+	sqlQuery := `
+		SELECT time, value, state, device, manufacturer, model, serial
+		FROM "Telemetry"
+		WHERE device = ?
+		  AND datasource = ?
+		  AND time >= ?
+		  AND time <= ?
+		ORDER BY time
+	`
+	args := []any{q.Device, q.DataSource, q.From.UTC(), q.To.UTC()}
+	if q.Limit > 0 {
+		sqlQuery += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	rows, err := db.QueryxContext(ctx, db.Rebind(sqlQuery), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query telemetry (device=%s, ds=%s): %w", q.Device, q.DataSource, err)
+	}
+
+	max := q.MaxPoints
+	if max <= 0 {
+		max = MaxTelemetryPoints
+	}
+	return &TelemetryCursor{ctx: ctx, rows: rows, q: q, max: max}, nil
+}
+
+// Next avance d'une ligne. Il renvoie false en fin de résultat ou sur erreur (voir Err).
+func (c *TelemetryCursor) Next() bool {
+	if c.err != nil {
+		return false
+	}
+	if !c.rows.Next() {
+		c.err = c.rows.Err()
+		if c.err == nil {
+			c.err = c.ctx.Err()
+		}
+		return false
+	}
+	if c.n >= c.max {
+		c.err = fmt.Errorf("device=%s, ds=%s: more than %d points (≈ %d Mo): %w",
+			c.q.Device, c.q.DataSource, c.max, c.max*int(unsafe.Sizeof(timeseries.DataUnit{}))>>20, ErrTelemetryTooLarge)
+		return false
+	}
+
+	var (
+		t                                   time.Time
+		v                                   sql.NullFloat64
+		state, dev, manufacturer, model, sn sql.RawBytes
+	)
+	if err := c.rows.Scan(&t, &v, &state, &dev, &manufacturer, &model, &sn); err != nil {
+		c.err = fmt.Errorf("scan telemetry (device=%s, ds=%s): %w", c.q.Device, c.q.DataSource, err)
+		return false
+	}
+	if c.first == nil {
+		// RawBytes n'est valide que jusqu'au prochain Next : copie
+		c.first = &telemetryMeta{
+			device:       string(dev),
+			manufacturer: string(manufacturer),
+			model:        string(model),
+			serial:       string(sn),
+			state:        string(state),
+		}
+	}
+
+	du := timeseries.DataUnit{
+		Chron:  t,
+		Meas:   v.Float64,
+		Dchron: 0,
+		Dmeas:  math.NaN(),
+		Status: mapStateToStatusCode(string(state)),
+	}
+	if !v.Valid {
+		du.Meas = math.NaN()
+		du.Status = timeseries.StMissing
+	}
+	if c.n > 0 {
+		du.Dchron = du.Chron.Sub(c.cur.Chron)
+		du.Dmeas = du.Meas - c.cur.Meas
+	}
+	c.cur = du
+	c.n++
+	return true
+}
+
+// DataUnit renvoie le point courant.
+func (c *TelemetryCursor) DataUnit() timeseries.DataUnit { return c.cur }
+
+// Count renvoie le nombre de points lus jusqu'ici.
+func (c *TelemetryCursor) Count() int { return c.n }
+
+// Err renvoie la première erreur rencontrée (annulation du contexte comprise).
+func (c *TelemetryCursor) Err() error { return c.err }
+
+// Close libère la connexion ; à appeler même après une erreur.
+func (c *TelemetryCursor) Close() error { return c.rows.Close() }
+
+// ReadTelemetryTimeSeries lit toute la plage via un TelemetryCursor.
+// Name et Comment suivent TelemetryRowsToTimeSeries (device + colonnes descriptives
+// de la première ligne). Renvoie une série vide si la plage ne contient aucun point.
+func ReadTelemetryTimeSeries(ctx context.Context, db *sqlx.DB, q TelemetryQuery) (*timeseries.TimeSeries, error) {
+	cur, err := OpenTelemetryCursor(ctx, db, q)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close()
+
+	ts := &timeseries.TimeSeries{Name: q.Device}
+	if q.Limit > 0 && q.Limit <= cur.max {
+		ts.DataSeries = make([]timeseries.DataUnit, 0, q.Limit)
+	}
+	for cur.Next() {
+		ts.DataSeries = append(ts.DataSeries, cur.DataUnit())
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	if m := cur.first; m != nil {
+		ts.Name = m.device
+		ts.Comment = fmt.Sprintf(
+			"device=%s; datasource=%s; manufacturer=%s; model=%s; serial=%s; state(colonne)=%s",
+			m.device, q.DataSource, m.manufacturer, m.model, m.serial, m.state,
+		)
+	}
+	return ts, nil
+}
@@ -0,0 +1,84 @@
+package dboperations
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// openTelemetrySQLite crée une table "Telemetry" minimale dans un fichier SQLite
+// et y insère n points (dans le désordre) pour dev/power.
+func openTelemetrySQLite(t *testing.T, n int) (*sqlx.DB, time.Time) {
+	t.Helper()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "telemetry.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(`
+		CREATE TABLE "Telemetry" (
+			id TEXT, time TIMESTAMP NOT NULL, device TEXT NOT NULL, value REAL,
+			datasource TEXT NOT NULL, state TEXT, manufacturer TEXT, model TEXT, serial TEXT
+		)`); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := n - 1; i >= 0; i-- {
+		var v any = float64(i)
+		if i == 3 {
+			v = nil
+		}
+		if _, err := db.Exec(`INSERT INTO "Telemetry" (time, device, value, datasource, state, manufacturer, model, serial)
+			VALUES (?, 'dev', ?, 'power', 'OK', 'acme', 'm1', 'SN1')`, start.Add(time.Duration(i)*time.Minute), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db, start
+}
+
+func TestReadTelemetryTimeSeriesStreamsInOrder(t *testing.T) {
+	db, start := openTelemetrySQLite(t, 10)
+	q := TelemetryQuery{Device: "dev", DataSource: "power", From: start, To: start.Add(time.Hour)}
+
+	ts, err := ReadTelemetryTimeSeries(context.Background(), db, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts.DataSeries) != 10 || ts.Name != "dev" {
+		t.Fatalf("len=%d name=%q", len(ts.DataSeries), ts.Name)
+	}
+	for i, du := range ts.DataSeries {
+		if !du.Chron.Equal(start.Add(time.Duration(i) * time.Minute)) {
+			t.Fatalf("point %d out of order: %v", i, du.Chron)
+		}
+	}
+	if du := ts.DataSeries[3]; !math.IsNaN(du.Meas) || du.Status.String() != "StMissing" {
+		t.Fatalf("NULL value must be NaN/StMissing, got %+v", du)
+	}
+	if du := ts.DataSeries[1]; du.Dchron != time.Minute || du.Dmeas != 1 {
+		t.Fatalf("deltas not filled: %+v", du)
+	}
+
+	q.MaxPoints = 5
+	if _, err := ReadTelemetryTimeSeries(context.Background(), db, q); !errors.Is(err, ErrTelemetryTooLarge) {
+		t.Fatalf("ceiling: got %v, want ErrTelemetryTooLarge", err)
+	}
+
+	q.Limit = 5
+	if ts, err := ReadTelemetryTimeSeries(context.Background(), db, q); err != nil || len(ts.DataSeries) != 5 {
+		t.Fatalf("limit within ceiling: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ReadTelemetryTimeSeries(ctx, db, TelemetryQuery{Device: "dev", DataSource: "power", From: start, To: start.Add(time.Hour)}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled context: got %v", err)
+	}
+}
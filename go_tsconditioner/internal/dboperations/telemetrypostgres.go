@@ -0,0 +1,45 @@
+package dboperations
+
+import (
+	"context"
+	"fmt"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/types"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresTelemetryRepository lit la table "Telemetry" de la base PostgreSQL distante.
+type PostgresTelemetryRepository struct {
+	sqlTelemetry
+}
+
+func NewPostgresTelemetryRepository(db *sqlx.DB) *PostgresTelemetryRepository {
+	return &PostgresTelemetryRepository{sqlTelemetry{db: db}}
+}
+
+func (r *PostgresTelemetryRepository) Buckets(ctx context.Context, device, datasource string, from, to time.Time, bucket time.Duration) (*timeseries.TsContainer, error) {
+	return LoadTelemetryBuckets(ctx, r.db, device, datasource, from, to, bucket)
+}
+
+// LastSeenPoints s'appuie sur DISTINCT ON, propre à PostgreSQL.
+func (r *PostgresTelemetryRepository) LastSeenPoints(ctx context.Context, since time.Time) ([]types.LastTelemetryPoint, error) {
+	const q = `
+		SELECT DISTINCT ON (device, datasource)
+			device,
+			serial,
+			datasource,
+			time  AS last_time,
+			value AS last_value
+		FROM "Telemetry"
+		WHERE time >= $1
+		ORDER BY device, datasource, time DESC;
+	`
+
+	var rows []types.LastTelemetryPoint
+	if err := r.db.SelectContext(ctx, &rows, q, since.UTC()); err != nil {
+		return nil, fmt.Errorf("select last seen points: %w", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,270 @@
+package dboperations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/types"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// TelemetryRepository regroupe toutes les lectures de la table Telemetry.
+// Les handlers et le moteur d'alertes n'en connaissent que cette interface :
+// PostgresTelemetryRepository en production, SQLiteTelemetryRepository pour
+// tourner et tester hors ligne sur des données de fixture.
+type TelemetryRepository interface {
+	// ListDevices renvoie les devices (et leurs datasources) ayant émis depuis since.
+	ListDevices(ctx context.Context, since time.Time) ([]types.Device, error)
+	// ListDataSources renvoie les datasources connues d'un device, triées.
+	ListDataSources(ctx context.Context, device string) ([]string, error)
+	// ReadRange lit une plage en flux (voir TelemetryCursor).
+	ReadRange(ctx context.Context, q TelemetryQuery) (*timeseries.TimeSeries, error)
+	// Buckets agrège [from, to] par buckets (voir LoadTelemetryBuckets).
+	Buckets(ctx context.Context, device, datasource string, from, to time.Time, bucket time.Duration) (*timeseries.TsContainer, error)
+	// LastSeenPoints renvoie le dernier point de chaque couple device/datasource depuis since.
+	LastSeenPoints(ctx context.Context, since time.Time) ([]types.LastTelemetryPoint, error)
+	// LastTime renvoie l'horodatage du dernier point depuis since (found=false si aucun).
+	LastTime(ctx context.Context, device, datasource string, since time.Time) (last time.Time, found bool, err error)
+	// TodayContainer charge la journée en cours (dans loc) de toutes les datasources du device.
+	TodayContainer(ctx context.Context, dev types.Device, loc *time.Location) (*timeseries.TsContainer, error)
+}
+
+// sqlTelemetry porte les requêtes communes aux deux dialectes :
+// placeholders "?" passés par Rebind, temps toujours envoyés en UTC.
+type sqlTelemetry struct {
+	db *sqlx.DB
+}
+
+func (r sqlTelemetry) ListDevices(ctx context.Context, since time.Time) ([]types.Device, error) {
+	q := r.db.Rebind(`
+		SELECT DISTINCT device, serial, datasource
+		FROM "Telemetry"
+		WHERE device IS NOT NULL
+		  AND datasource IS NOT NULL
+		  AND time >= ?
+		ORDER BY device, datasource;
+	`)
+	var rows []deviceDatasourceRow
+	if err := r.db.SelectContext(ctx, &rows, q, since.UTC()); err != nil {
+		return nil, fmt.Errorf("select devices in telemetry: %w", err)
+	}
+	return groupDevices(rows), nil
+}
+
+func (r sqlTelemetry) ListDataSources(ctx context.Context, device string) ([]string, error) {
+	q := r.db.Rebind(`
+		SELECT DISTINCT datasource
+		FROM "Telemetry"
+		WHERE device = ?
+		ORDER BY datasource;
+	`)
+	var out []string
+	if err := r.db.SelectContext(ctx, &out, q, device); err != nil {
+		return nil, fmt.Errorf("select datasources (device=%s): %w", device, err)
+	}
+	return out, nil
+}
+
+func (r sqlTelemetry) ReadRange(ctx context.Context, q TelemetryQuery) (*timeseries.TimeSeries, error) {
+	return ReadTelemetryTimeSeries(ctx, r.db, q)
+}
+
+func (r sqlTelemetry) LastTime(ctx context.Context, device, datasource string, since time.Time) (time.Time, bool, error) {
+	// ORDER BY ... LIMIT 1 plutôt que max(time) : la colonne garde son type
+	// (SQLite renvoie un texte pour un agrégat)
+	q := r.db.Rebind(`
+		SELECT time
+		FROM "Telemetry"
+		WHERE device = ?
+		  AND datasource = ?
+		  AND time >= ?
+		ORDER BY time DESC
+		LIMIT 1;
+	`)
+	var t time.Time
+	err := r.db.GetContext(ctx, &t, q, device, datasource, since.UTC())
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("select last telemetry time (device=%s, ds=%s): %w", device, datasource, err)
+	}
+	return t, true, nil
+}
+
+// TodayContainer charge les datasources en parallèle (voir loadDatasourcesParallel).
+func (r sqlTelemetry) TodayContainer(ctx context.Context, dev types.Device, loc *time.Location) (*timeseries.TsContainer, error) {
+	from := StartOfToday(loc)
+	to := StartOfTomorrow(loc)
+
+	c := &timeseries.TsContainer{
+		Name:    dev.DeviceName,
+		Comment: "All datasources - today",
+		Ts:      make(map[string]*timeseries.TimeSeries),
+	}
+	err := loadDatasourcesParallel(ctx, c, dev.DataSources, poolWorkers(r.db), func(ctx context.Context, ds string) (*timeseries.TimeSeries, error) {
+		return loadTelemetryDay(ctx, r.db, dev.DeviceID, ds, from, to)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// defaultParallelLoads borne le parallélisme quand le pool n'a pas de limite.
+const defaultParallelLoads = 4
+
+// poolWorkers dimensionne le parallélisme sur le pool de connexions (MaxOpenConns).
+func poolWorkers(db *sqlx.DB) int {
+	if n := db.Stats().MaxOpenConnections; n > 0 {
+		return n
+	}
+	return defaultParallelLoads
+}
+
+// loadDatasourcesParallel remplit c (via Add) avec au plus workers chargements simultanés.
+// Une datasource en échec est notée dans c.Errors sans interrompre les autres.
+// Une erreur n'est renvoyée que si le contexte est annulé ou si toutes les datasources échouent.
+func loadDatasourcesParallel(
+	ctx context.Context,
+	c *timeseries.TsContainer,
+	datasources []types.DataSource,
+	workers int,
+	load func(ctx context.Context, ds string) (*timeseries.TimeSeries, error),
+) error {
+	jobs := make(chan string)
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		total    int
+	)
+
+	if workers < 1 {
+		workers = 1
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ds := range jobs {
+				ts, err := load(ctx, ds)
+				mu.Lock()
+				if err == nil {
+					err = c.Add(ds, ts)
+				}
+				if err != nil {
+					if c.Errors == nil {
+						c.Errors = make(map[string]string)
+					}
+					c.Errors[ds] = err.Error()
+					if firstErr == nil {
+						firstErr = err
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, ds := range datasources {
+		if ds.Name == "" {
+			continue
+		}
+		select {
+		case jobs <- ds.Name:
+			total++
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if total > 0 && len(c.Errors) == total {
+		return fmt.Errorf("all %d datasources failed: %w", total, firstErr)
+	}
+	if len(c.Errors) > 0 {
+		log.Printf("⚠️ %s : %d/%d datasources en échec", c.Name, len(c.Errors), total)
+	}
+	return nil
+}
+
+// loadTelemetryDay charge [from, to[ pour un couple device/datasource, toutes
+// les mesures marquées StOK (comportement historique du conteneur "today").
+func loadTelemetryDay(ctx context.Context, db *sqlx.DB, deviceID uuid.UUID, datasource string, from, to time.Time) (*timeseries.TimeSeries, error) {
+	q := db.Rebind(`
+		SELECT time, value
+		FROM "Telemetry"
+		WHERE device = ?
+		  AND datasource = ?
+		  AND time >= ?
+		  AND time <  ?
+		ORDER BY time;
+	`)
+
+	var rows []telemetryRow
+	if err := db.SelectContext(ctx, &rows, q, deviceID.String(), datasource, from.UTC(), to.UTC()); err != nil {
+		return nil, fmt.Errorf("select telemetry today (device=%s, ds=%s): %w", deviceID, datasource, err)
+	}
+
+	ts := &timeseries.TimeSeries{
+		Name:       datasource,
+		Comment:    fmt.Sprintf("Telemetry for %s (today)", datasource),
+		DataSeries: make([]timeseries.DataUnit, 0, len(rows)),
+	}
+	for _, r := range rows {
+		ts.AddDataUnit(timeseries.DataUnit{Chron: r.T, Meas: r.V})
+	}
+	return ts, nil
+}
+
+// groupDevices regroupe les lignes (device, serial, datasource) par device,
+// datasources dédoublonnées et triées, devices triés par UUID.
+func groupDevices(rows []deviceDatasourceRow) []types.Device {
+	byDevice := make(map[uuid.UUID]*types.Device, len(rows))
+	seenDS := make(map[uuid.UUID]map[string]struct{}, len(rows))
+
+	for _, r := range rows {
+		dev, ok := byDevice[r.Device]
+		if !ok {
+			dev = &types.Device{
+				DeviceID:    r.Device,
+				DeviceName:  r.Serial,
+				DataSources: []types.DataSource{},
+			}
+			byDevice[r.Device] = dev
+			seenDS[r.Device] = make(map[string]struct{})
+		}
+
+		if _, exists := seenDS[r.Device][r.DataSource]; !exists {
+			dev.DataSources = append(dev.DataSources, types.DataSource{Name: r.DataSource})
+			seenDS[r.Device][r.DataSource] = struct{}{}
+		}
+	}
+
+	out := make([]types.Device, 0, len(byDevice))
+	for _, d := range byDevice {
+		sort.Slice(d.DataSources, func(i, j int) bool {
+			return d.DataSources[i].Name < d.DataSources[j].Name
+		})
+		out = append(out, *d)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].DeviceID.String() < out[j].DeviceID.String()
+	})
+	return out
+}
@@ -0,0 +1,62 @@
+package dboperations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/types"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadDatasourcesParallelBoundedAndPartial(t *testing.T) {
+	var dss []types.DataSource
+	for i := 0; i < 30; i++ {
+		dss = append(dss, types.DataSource{Name: fmt.Sprintf("ds%02d", i)})
+	}
+
+	var running, peak int32
+	load := func(ctx context.Context, ds string) (*timeseries.TimeSeries, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		if ds == "ds07" || ds == "ds21" {
+			return nil, errors.New("boom")
+		}
+		return &timeseries.TimeSeries{Name: ds}, nil
+	}
+
+	c := &timeseries.TsContainer{Name: "dev", Ts: map[string]*timeseries.TimeSeries{}}
+	if err := loadDatasourcesParallel(context.Background(), c, dss, 4, load); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Ts) != 28 || len(c.Errors) != 2 || c.Errors["ds07"] == "" {
+		t.Fatalf("ts=%d errors=%v", len(c.Ts), c.Errors)
+	}
+	if peak > 4 || peak < 2 {
+		t.Fatalf("peak concurrency = %d, want 2..4", peak)
+	}
+
+	// tout en échec -> erreur
+	fail := func(ctx context.Context, ds string) (*timeseries.TimeSeries, error) { return nil, errors.New("down") }
+	c = &timeseries.TsContainer{Name: "dev", Ts: map[string]*timeseries.TimeSeries{}}
+	if err := loadDatasourcesParallel(context.Background(), c, dss[:3], 4, fail); err == nil {
+		t.Fatalf("all failed: expected an error")
+	}
+
+	// contexte annulé -> erreur du contexte
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c = &timeseries.TsContainer{Name: "dev", Ts: map[string]*timeseries.TimeSeries{}}
+	if err := loadDatasourcesParallel(ctx, c, dss, 4, load); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled: got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package dboperations
+
+import (
+	"context"
+	"fmt"
+	"go_tsconditioner/internal/migrations"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/types"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SQLiteTelemetryRepository lit une table "Telemetry" locale (fichier SQLite ou
+// ":memory:"), pour faire tourner le serveur et les tests sans la base distante.
+type SQLiteTelemetryRepository struct {
+	sqlTelemetry
+}
+
+// OpenSQLiteTelemetry ouvre (ou crée) le fichier et applique le jeu de migrations Telemetry.
+// Les temps sont écrits au format SQLite ("2006-01-02 15:04:05.999999999-07:00") pour
+// que les comparaisons de chaînes et strftime fonctionnent.
+func OpenSQLiteTelemetry(sqlitePath string) (*SQLiteTelemetryRepository, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite", sqlitePath)
+
+	db, err := sqlx.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite telemetry (%s): %w", sqlitePath, err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := migrations.Up(context.Background(), db, migrations.Telemetry); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate sqlite telemetry (%s): %w", sqlitePath, err)
+	}
+	return &SQLiteTelemetryRepository{sqlTelemetry{db: db}}, nil
+}
+
+// DB expose la connexion (fixtures, fermeture).
+func (r *SQLiteTelemetryRepository) DB() *sqlx.DB { return r.db }
+
+func (r *SQLiteTelemetryRepository) Close() error { return r.db.Close() }
+
+// Insert ajoute des lignes Telemetry (fixtures, import hors ligne) dans une transaction.
+func (r *SQLiteTelemetryRepository) Insert(ctx context.Context, rows []Telemetry) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.PreparexContext(ctx, `
+		INSERT INTO "Telemetry" (id, time, device, value, datasource, state, manufacturer, model, serial)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare insert telemetry (sqlite): %w", err)
+	}
+	defer stmt.Close()
+
+	for _, t := range rows {
+		state := t.State
+		if state == "" {
+			state = "OK"
+		}
+		if _, err := stmt.ExecContext(ctx, t.Id.String(), t.Time.UTC(), t.Device.String(), t.Value,
+			t.Datasource, state, t.Manufacturer, t.Model, t.Serial); err != nil {
+			return fmt.Errorf("insert telemetry (device=%s, ds=%s): %w", t.Device, t.Datasource, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// LastSeenPoints : pas de DISTINCT ON en SQLite, jointure sur le max(time) par couple.
+func (r *SQLiteTelemetryRepository) LastSeenPoints(ctx context.Context, since time.Time) ([]types.LastTelemetryPoint, error) {
+	const q = `
+		SELECT t.device, t.serial, t.datasource, t.time AS last_time, t.value AS last_value
+		FROM "Telemetry" t
+		JOIN (
+			SELECT device, datasource, max(time) AS mt
+			FROM "Telemetry"
+			WHERE time >= ?
+			GROUP BY device, datasource
+		) m ON m.device = t.device AND m.datasource = t.datasource AND m.mt = t.time
+		ORDER BY t.device, t.datasource;
+	`
+
+	var rows []types.LastTelemetryPoint
+	if err := r.db.SelectContext(ctx, &rows, q, since.UTC()); err != nil {
+		return nil, fmt.Errorf("select last seen points (sqlite): %w", err)
+	}
+	return rows, nil
+}
+
+type sqliteBucketRow struct {
+	Epoch int64   `db:"bucket_epoch"`
+	Min   float64 `db:"vmin"`
+	Max   float64 `db:"vmax"`
+	Avg   float64 `db:"vavg"`
+	Count int64   `db:"n"`
+}
+
+// Buckets reproduit LoadTelemetryBuckets avec strftime('%s') à la place de extract(epoch).
+func (r *SQLiteTelemetryRepository) Buckets(ctx context.Context, device, datasource string, from, to time.Time, bucket time.Duration) (*timeseries.TsContainer, error) {
+	first, nb, err := bucketGrid(from, to, bucket)
+	if err != nil {
+		return nil, err
+	}
+	sec := int64(bucket / time.Second)
+
+	const q = `
+		SELECT (CAST(strftime('%s', time) AS INTEGER) / ?) * ? AS bucket_epoch,
+		       min(value)   AS vmin,
+		       max(value)   AS vmax,
+		       avg(value)   AS vavg,
+		       count(value) AS n
+		FROM "Telemetry"
+		WHERE device = ?
+		  AND datasource = ?
+		  AND time >= ?
+		  AND time <= ?
+		  AND value IS NOT NULL
+		GROUP BY bucket_epoch
+		ORDER BY bucket_epoch;
+	`
+
+	var rows []sqliteBucketRow
+	if err := r.db.SelectContext(ctx, &rows, q, sec, sec, device, datasource, from.UTC(), to.UTC()); err != nil {
+		return nil, fmt.Errorf("select telemetry buckets (device=%s, ds=%s, bucket=%s): %w", device, datasource, bucket, err)
+	}
+
+	out := make([]telemetryBucketRow, len(rows))
+	for i, r := range rows {
+		out[i] = telemetryBucketRow{Bucket: time.Unix(r.Epoch, 0).UTC(), Min: r.Min, Max: r.Max, Avg: r.Avg, Count: r.Count}
+	}
+	return bucketsToContainer(out, device, datasource, first, nb, bucket), nil
+}
@@ -0,0 +1,142 @@
+package dboperations
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// telemetryFixture : deux devices, le premier avec deux datasources,
+// un point par minute sur la dernière demi-heure.
+func telemetryFixture(t *testing.T) (*SQLiteTelemetryRepository, uuid.UUID, time.Time) {
+	t.Helper()
+	repo, err := OpenSQLiteTelemetry(filepath.Join(t.TempDir(), "telemetry.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	dev1, dev2 := uuid.New(), uuid.New()
+	now := time.Now().UTC().Truncate(time.Minute)
+	var rows []Telemetry
+	for i := 30; i >= 1; i-- {
+		at := now.Add(-time.Duration(i) * time.Minute)
+		rows = append(rows,
+			Telemetry{Id: uuid.New(), Time: at, Device: dev1, Value: float64(i), Datasource: "power", Serial: "SN1"},
+			Telemetry{Id: uuid.New(), Time: at, Device: dev1, Value: 20, Datasource: "temp", Serial: "SN1"},
+			Telemetry{Id: uuid.New(), Time: at, Device: dev2, Value: 1, Datasource: "power", Serial: "SN2"},
+		)
+	}
+	if err := repo.Insert(context.Background(), rows); err != nil {
+		t.Fatal(err)
+	}
+	return repo, dev1, now
+}
+
+func TestSQLiteTelemetryRepository(t *testing.T) {
+	ctx := context.Background()
+	repo, dev1, now := telemetryFixture(t)
+	var _ TelemetryRepository = repo
+
+	devices, err := repo.ListDevices(ctx, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("devices = %d, want 2", len(devices))
+	}
+	dev, ok := FindDevice(devices, dev1)
+	if !ok || len(dev.DataSources) != 2 || dev.DeviceName != "SN1" {
+		t.Fatalf("device 1 = %+v", dev)
+	}
+
+	names, err := repo.ListDataSources(ctx, dev1.String())
+	if err != nil || len(names) != 2 || names[0] != "power" || names[1] != "temp" {
+		t.Fatalf("datasources = %v (%v)", names, err)
+	}
+
+	last, err := repo.LastSeenPoints(ctx, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(last) != 3 {
+		t.Fatalf("last seen = %d, want 3", len(last))
+	}
+	for _, p := range last {
+		if !p.LastTime.Equal(now.Add(-time.Minute)) {
+			t.Fatalf("last time %v, want %v", p.LastTime, now.Add(-time.Minute))
+		}
+	}
+
+	lt, found, err := repo.LastTime(ctx, dev1.String(), "power", now.Add(-time.Hour))
+	if err != nil || !found || !lt.Equal(now.Add(-time.Minute)) {
+		t.Fatalf("last time = %v %v %v", lt, found, err)
+	}
+	if _, found, _ := repo.LastTime(ctx, dev1.String(), "power", now); found {
+		t.Fatalf("no point expected after now")
+	}
+
+	ts, err := repo.ReadRange(ctx, TelemetryQuery{Device: dev1.String(), DataSource: "power", From: now.Add(-10 * time.Minute), To: now})
+	if err != nil || len(ts.DataSeries) != 10 {
+		t.Fatalf("range: %v points, err %v", len(ts.DataSeries), err)
+	}
+
+	tsc, err := repo.Buckets(ctx, dev1.String(), "power", now.Add(-30*time.Minute), now, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total float64
+	for _, du := range tsc.Ts["Count"].DataSeries {
+		total += du.Meas
+	}
+	if total != 30 {
+		t.Fatalf("bucket counts sum to %g, want 30", total)
+	}
+
+	today, err := repo.TodayContainer(ctx, *dev, time.UTC)
+	if err != nil || len(today.Ts) != 2 {
+		t.Fatalf("today container: %v (%v)", today, err)
+	}
+}
+
+// Les buckets de plusieurs jours doivent tomber sur ceux calculés par SQLite
+// (floor(epoch/sec)*sec), sinon toute la grille revient en NaN.
+func TestSQLiteBucketsMultiDay(t *testing.T) {
+	ctx := context.Background()
+	repo, err := OpenSQLiteTelemetry(filepath.Join(t.TempDir(), "telemetry.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	dev := uuid.New()
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(60 * 24 * time.Hour)
+	var rows []Telemetry
+	for at := from; at.Before(to); at = at.Add(time.Hour) {
+		rows = append(rows, Telemetry{Id: uuid.New(), Time: at, Device: dev, Value: 1, Datasource: "power", Serial: "SN1"})
+	}
+	if err := repo.Insert(ctx, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, b := range []time.Duration{3 * 24 * time.Hour, 7 * 24 * time.Hour} {
+		tsc, err := repo.Buckets(ctx, dev.String(), "power", from.Add(24*time.Hour), to.Add(-24*time.Hour), b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		avg := tsc.Ts["Average"].DataSeries
+		if len(avg) == 0 {
+			t.Fatalf("bucket %s: no bucket", b)
+		}
+		for i, du := range avg {
+			if math.IsNaN(du.Meas) || du.Meas != 1 {
+				t.Fatalf("bucket %s: average[%d] = %g at %v, want 1", b, i, du.Meas, du.Chron)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package dboperations
+
+import (
+	"context"
+	"fmt"
+	"go_tsconditioner/internal/timeseries"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// TelemetryWriter ajoute des lignes à une table "Telemetry" : la base
+// PostgreSQL locale en production (PostgresTelemetryWriter), le fichier SQLite
+// hors ligne (SQLiteTelemetryRepository.Insert).
+type TelemetryWriter interface {
+	Insert(ctx context.Context, rows []Telemetry) error
+}
+
+// PostgresTelemetryWriter écrit dans la table "Telemetry" de la base locale
+// (migration postgres 0004), par COPY dans une transaction.
+type PostgresTelemetryWriter struct {
+	db *sqlx.DB
+}
+
+func NewPostgresTelemetryWriter(db *sqlx.DB) *PostgresTelemetryWriter {
+	return &PostgresTelemetryWriter{db: db}
+}
+
+func (w *PostgresTelemetryWriter) Insert(ctx context.Context, rows []Telemetry) (err error) {
+	tx, err := w.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("Telemetry",
+		"id", "time", "device", "value", "datasource", "state", "manufacturer", "model", "serial"))
+	if err != nil {
+		return fmt.Errorf("prepare copy telemetry: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, t := range rows {
+		state := t.State
+		if state == "" {
+			state = "OK"
+		}
+		if _, err = stmt.ExecContext(ctx, t.Id.String(), t.Time.UTC(), t.Device.String(), t.Value,
+			t.Datasource, state, t.Manufacturer, t.Model, t.Serial); err != nil {
+			return fmt.Errorf("copy telemetry (device=%s, ds=%s): %w", t.Device, t.Datasource, err)
+		}
+	}
+	// Exec sans argument : flush du COPY
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("copy telemetry: %w", err)
+	}
+	return tx.Commit()
+}
+
+// TimeSeriesToTelemetryRows est l'inverse de TelemetryRowsToTimeSeries : une
+// ligne par DataUnit, avec un nouvel id. tmpl fournit device, datasource,
+// manufacturer, model et serial.
+func TimeSeriesToTelemetryRows(ts *timeseries.TimeSeries, tmpl Telemetry) []Telemetry {
+	rows := make([]Telemetry, len(ts.DataSeries))
+	for i, du := range ts.DataSeries {
+		r := tmpl
+		r.Id = uuid.New()
+		r.Time = du.Chron.UTC()
+		r.Value = du.Meas
+		r.State = mapStatusCodeToState(du.Status)
+		rows[i] = r
+	}
+	return rows
+}
+
+// mapStatusCodeToState est l'inverse de mapStateToStatusCode.
+func mapStatusCodeToState(st timeseries.StatusCode) string {
+	switch st {
+	case timeseries.StOK:
+		return "OK"
+	case timeseries.StMissing:
+		return "MISSING"
+	case timeseries.StOutlier:
+		return "OUTLIER"
+	case timeseries.StRejected:
+		return "REJECTED"
+	case timeseries.StSimulated:
+		return "SIMULATED"
+	default:
+		return "INVALID"
+	}
+}
@@ -0,0 +1,361 @@
+// Package gorilla compresse des colonnes d'entiers, de flottants et
+// d'horodatages à la manière de Gorilla (Pelkonen et al., VLDB 2015) :
+// delta-of-delta pour les entiers et les temps, XOR avec la valeur
+// précédente pour les flottants. Chaque colonne est un flux de bits (poids
+// fort en premier) complété par des zéros jusqu'à l'octet ; le nombre de
+// valeurs n'y figure pas, il est fourni au décodage. Les colonnes d'octets
+// (statuts) sont codées en plages (EncodeRuns).
+//
+// Le détail bit à bit est documenté dans internal/tswire/README.md.
+package gorilla
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+	"time"
+)
+
+var ErrShort = errors.New("gorilla: truncated stream")
+
+// Seaux du delta-of-delta : préfixe puis largeur de la valeur signée.
+// Un delta-of-delta nul s'écrit sur le seul bit 0.
+var dodBuckets = []struct {
+	prefix, prefixLen uint64
+	width             int
+}{
+	{0b10, 2, 7},
+	{0b110, 3, 9},
+	{0b1110, 4, 12},
+	{0b11110, 5, 32},
+	{0b11111, 5, 64},
+}
+
+// EncodeInts encode vs en delta-of-delta : la première valeur sur 64 bits,
+// puis pour chaque suivante (v[i]-v[i-1]) - (v[i-1]-v[i-2]), le delta
+// précédent du premier point valant 0. L'arithmétique est modulo 2^64, ce
+// qui rend le codage exact même pour des sentinelles comme math.MinInt64.
+func EncodeInts(vs []int64) []byte {
+	if len(vs) == 0 {
+		return nil
+	}
+	w := bitWriter{buf: make([]byte, 0, 8+len(vs)/4)}
+	w.writeBits(uint64(vs[0]), 64)
+	var prevDelta int64
+	for i := 1; i < len(vs); i++ {
+		delta := vs[i] - vs[i-1]
+		dod := delta - prevDelta
+		prevDelta = delta
+		if dod == 0 {
+			w.writeBits(0, 1)
+			continue
+		}
+		for _, b := range dodBuckets {
+			if b.width == 64 || fitsSigned(dod, b.width) {
+				w.writeBits(b.prefix, int(b.prefixLen))
+				w.writeBits(uint64(dod), b.width)
+				break
+			}
+		}
+	}
+	return w.buf
+}
+
+// DecodeInts relit n valeurs écrites par EncodeInts.
+func DecodeInts(b []byte, n int) ([]int64, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	if n-1 > len(b)*8-64 { // au moins un bit par valeur suivante
+		return nil, ErrShort
+	}
+	r := bitReader{buf: b}
+	first, err := r.readBits(64)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]int64, n)
+	out[0] = int64(first)
+	var prevDelta int64
+	for i := 1; i < n; i++ {
+		var dod int64
+		// préfixe : des 1 terminés par un 0, sauf 11111 (cinq 1 au plus)
+		ones := 0
+		for ones < 5 {
+			bit, err := r.readBits(1)
+			if err != nil {
+				return nil, err
+			}
+			if bit == 0 {
+				break
+			}
+			ones++
+		}
+		if ones > 0 {
+			width := dodBuckets[ones-1].width
+			u, err := r.readBits(width)
+			if err != nil {
+				return nil, err
+			}
+			dod = signExtend(u, width)
+		}
+		prevDelta += dod
+		out[i] = out[i-1] + prevDelta
+	}
+	return out, nil
+}
+
+// EncodeFloats encode vs par XOR : la première valeur sur 64 bits, puis pour
+// chaque suivante x = bits(v[i]) ^ bits(v[i-1]) :
+//   - 0 si x est nul ;
+//   - 10 puis les bits significatifs de x, si ses zéros de tête et de queue
+//     couvrent au moins ceux de la fenêtre précédente (que l'on réutilise) ;
+//   - 11, les zéros de tête sur 5 bits (plafonnés à 31), la longueur
+//     significative moins 1 sur 6 bits, puis les bits significatifs.
+//
+// Les bits sont conservés tels quels : NaN, infinis et -0 reviennent à
+// l'identique.
+func EncodeFloats(vs []float64) []byte {
+	if len(vs) == 0 {
+		return nil
+	}
+	w := bitWriter{buf: make([]byte, 0, 8+len(vs)*2)}
+	prev := math.Float64bits(vs[0])
+	w.writeBits(prev, 64)
+	lead, trail := -1, 0 // pas encore de fenêtre
+	for _, v := range vs[1:] {
+		cur := math.Float64bits(v)
+		x := cur ^ prev
+		prev = cur
+		if x == 0 {
+			w.writeBits(0, 1)
+			continue
+		}
+		l, t := bits.LeadingZeros64(x), bits.TrailingZeros64(x)
+		if l > 31 {
+			l = 31
+		}
+		if lead >= 0 && l >= lead && t >= trail {
+			w.writeBits(0b10, 2)
+			w.writeBits(x>>trail, 64-lead-trail)
+			continue
+		}
+		lead, trail = l, t
+		sig := 64 - l - t
+		w.writeBits(0b11, 2)
+		w.writeBits(uint64(l), 5)
+		w.writeBits(uint64(sig-1), 6)
+		w.writeBits(x>>t, sig)
+	}
+	return w.buf
+}
+
+// DecodeFloats relit n valeurs écrites par EncodeFloats.
+func DecodeFloats(b []byte, n int) ([]float64, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	if n-1 > len(b)*8-64 {
+		return nil, ErrShort
+	}
+	r := bitReader{buf: b}
+	prev, err := r.readBits(64)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]float64, n)
+	out[0] = math.Float64frombits(prev)
+	lead, trail := -1, 0
+	for i := 1; i < n; i++ {
+		ctl, err := r.readBits(1)
+		if err != nil {
+			return nil, err
+		}
+		if ctl == 1 {
+			fresh, err := r.readBits(1)
+			if err != nil {
+				return nil, err
+			}
+			if fresh == 1 {
+				l, err := r.readBits(5)
+				if err != nil {
+					return nil, err
+				}
+				sig, err := r.readBits(6)
+				if err != nil {
+					return nil, err
+				}
+				lead, trail = int(l), 64-int(l)-int(sig+1)
+			} else if lead < 0 {
+				return nil, errors.New("gorilla: window reused before being set")
+			}
+			x, err := r.readBits(64 - lead - trail)
+			if err != nil {
+				return nil, err
+			}
+			prev ^= x << trail
+		}
+		out[i] = math.Float64frombits(prev)
+	}
+	return out, nil
+}
+
+// Unités de temps reconnues par EncodeTimes, de la plus grossière à la plus
+// fine ; l'octet d'en-tête est l'exposant de 10 en nanosecondes.
+var timeUnits = []struct {
+	exp byte
+	ns  int64
+}{{9, 1e9}, {6, 1e6}, {3, 1e3}, {0, 1}}
+
+// naTime code l'heure zéro (timeseries.NaDate) dans la colonne des temps.
+const naTime = math.MinInt64
+
+// EncodeTimes écrit un octet d'unité (exposant de 10 en nanosecondes : 9, 6,
+// 3 ou 0), la plus grossière qui divise exactement tous les horodatages, puis
+// les temps Unix dans cette unité via EncodeInts. Des données à la seconde
+// ou à la milliseconde tiennent ainsi dans les petits seaux. L'heure zéro
+// s'écrit math.MinInt64 ; les autres doivent tenir dans UnixNano (1678-2262).
+func EncodeTimes(ts []time.Time) []byte {
+	if len(ts) == 0 {
+		return nil
+	}
+	ns := make([]int64, len(ts))
+	unit := 0
+	for i, t := range ts {
+		if t.IsZero() {
+			ns[i] = naTime
+			continue
+		}
+		ns[i] = t.UnixNano()
+		for unit < len(timeUnits)-1 && ns[i]%timeUnits[unit].ns != 0 {
+			unit++
+		}
+	}
+	u := timeUnits[unit]
+	for i, v := range ns {
+		if v != naTime {
+			ns[i] = v / u.ns
+		}
+	}
+	return append([]byte{u.exp}, EncodeInts(ns)...)
+}
+
+// DecodeTimes relit n horodatages écrits par EncodeTimes, en UTC.
+func DecodeTimes(b []byte, n int) ([]time.Time, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	if len(b) == 0 {
+		return nil, ErrShort
+	}
+	var scale int64
+	for _, u := range timeUnits {
+		if u.exp == b[0] {
+			scale = u.ns
+		}
+	}
+	if scale == 0 {
+		return nil, errors.New("gorilla: unknown time unit")
+	}
+	vs, err := DecodeInts(b[1:], n)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]time.Time, n)
+	for i, v := range vs {
+		if v != naTime {
+			out[i] = time.Unix(0, v*scale).UTC()
+		}
+	}
+	return out, nil
+}
+
+// EncodeRuns code une colonne d'octets (des statuts, typiquement) en plages :
+// longueur en uvarint puis valeur, répétés.
+func EncodeRuns(vs []byte) []byte {
+	var out []byte
+	for i := 0; i < len(vs); {
+		j := i + 1
+		for j < len(vs) && vs[j] == vs[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i))
+		out = append(out, vs[i])
+		i = j
+	}
+	return out
+}
+
+// DecodeRuns relit n octets écrits par EncodeRuns ; les plages doivent
+// couvrir exactement n valeurs.
+func DecodeRuns(b []byte, n int) ([]byte, error) {
+	out := make([]byte, 0, min(n, 8*len(b)))
+	for len(b) > 0 {
+		run, k := binary.Uvarint(b)
+		if k <= 0 || k >= len(b) || run > uint64(n-len(out)) {
+			return nil, errors.New("gorilla: bad run")
+		}
+		for range run {
+			out = append(out, b[k])
+		}
+		b = b[k+1:]
+	}
+	if len(out) != n {
+		return nil, ErrShort
+	}
+	return out, nil
+}
+
+func fitsSigned(v int64, width int) bool {
+	lim := int64(1) << (width - 1)
+	return v >= -lim && v < lim
+}
+
+func signExtend(u uint64, width int) int64 {
+	if width == 64 {
+		return int64(u)
+	}
+	shift := 64 - width
+	return int64(u<<shift) >> shift
+}
+
+type bitWriter struct {
+	buf  []byte
+	free int // bits libres dans le dernier octet
+}
+
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for n > 0 {
+		if w.free == 0 {
+			w.buf = append(w.buf, 0)
+			w.free = 8
+		}
+		k := min(n, w.free)
+		chunk := byte(v>>(n-k)) & byte(1<<k-1)
+		w.buf[len(w.buf)-1] |= chunk << (w.free - k)
+		w.free -= k
+		n -= k
+	}
+}
+
+type bitReader struct {
+	buf []byte
+	pos int // en bits
+}
+
+func (r *bitReader) readBits(n int) (uint64, error) {
+	if r.pos+n > len(r.buf)*8 {
+		return 0, ErrShort
+	}
+	var v uint64
+	for n > 0 {
+		off := r.pos % 8
+		k := min(n, 8-off)
+		chunk := uint64(r.buf[r.pos/8]>>(8-off-k)) & (1<<k - 1)
+		v = v<<k | chunk
+		r.pos += k
+		n -= k
+	}
+	return v, nil
+}
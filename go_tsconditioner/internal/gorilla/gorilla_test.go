@@ -0,0 +1,112 @@
+package gorilla
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestIntsRoundTrip(t *testing.T) {
+	cases := [][]int64{
+		{42},
+		{0, 600, 1200, 1800, 2400}, // pas régulier : dod nuls
+		{0, 60, 121, 179, 300, 5000, 5001, 1 << 40, -7},     // tous les seaux
+		{math.MinInt64, 1, math.MaxInt64, math.MinInt64, 0}, // débordements modulo 2^64
+	}
+	rng := rand.New(rand.NewSource(1))
+	random := make([]int64, 1000)
+	for i := range random {
+		random[i] = rng.Int63() - rng.Int63()
+	}
+	cases = append(cases, random)
+
+	for _, vs := range cases {
+		got, err := DecodeInts(EncodeInts(vs), len(vs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range vs {
+			if got[i] != vs[i] {
+				t.Fatalf("%v: [%d] = %d", vs, i, got[i])
+			}
+		}
+	}
+
+	regular := make([]int64, 1000)
+	for i := range regular {
+		regular[i] = int64(i) * 600
+	}
+	// 8 octets pour la première valeur, 2 bits pour le premier delta, puis 1 bit par point
+	if n := len(EncodeInts(regular)); n > 8+1+1000/8+1 {
+		t.Fatalf("regular series: %d bytes", n)
+	}
+}
+
+func TestFloatsRoundTrip(t *testing.T) {
+	vs := []float64{20.5, 20.5, 20.75, 21, math.NaN(), 21, math.Inf(1), math.Copysign(0, -1), 0, 1e-300, -3.25, 123456.789}
+	rng := rand.New(rand.NewSource(2))
+	for range 1000 {
+		vs = append(vs, math.Round(rng.NormFloat64()*100)/10)
+	}
+	got, err := DecodeFloats(EncodeFloats(vs), len(vs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range vs {
+		if math.Float64bits(got[i]) != math.Float64bits(vs[i]) {
+			t.Fatalf("[%d] = %v, want %v", i, got[i], vs[i])
+		}
+	}
+}
+
+func TestTimesRoundTrip(t *testing.T) {
+	base := time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC)
+	loc, _ := time.LoadLocation("Europe/Luxembourg")
+	for _, tc := range []struct {
+		ts   []time.Time
+		unit byte
+	}{
+		{[]time.Time{base, base.Add(10 * time.Minute), {}, base.In(loc).Add(time.Hour)}, 9},
+		{[]time.Time{base, base.Add(1500 * time.Millisecond)}, 6},
+		{[]time.Time{base.Add(time.Nanosecond)}, 0},
+	} {
+		b := EncodeTimes(tc.ts)
+		if b[0] != tc.unit {
+			t.Errorf("unit %d, want %d", b[0], tc.unit)
+		}
+		got, err := DecodeTimes(b, len(tc.ts))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range tc.ts {
+			if !got[i].Equal(tc.ts[i]) || got[i].IsZero() != tc.ts[i].IsZero() || got[i].Location() != time.UTC {
+				t.Fatalf("[%d] = %v, want %v", i, got[i], tc.ts[i])
+			}
+		}
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	b := EncodeFloats([]float64{1, 2, 3, 4, 5})
+	if _, err := DecodeFloats(b[:len(b)-2], 5); err == nil {
+		t.Fatal("truncated floats accepted")
+	}
+	if _, err := DecodeInts(EncodeInts([]int64{1, 2}), 1<<30); err == nil {
+		t.Fatal("oversized count accepted")
+	}
+}
+
+func TestRunsRoundTrip(t *testing.T) {
+	vs := []byte{0, 0, 0, 1, 0, 4, 4, 4, 4, 5}
+	got, err := DecodeRuns(EncodeRuns(vs), len(vs))
+	if err != nil || string(got) != string(vs) {
+		t.Fatalf("%v %v", got, err)
+	}
+	if _, err := DecodeRuns(EncodeRuns(vs), len(vs)+1); err == nil {
+		t.Fatal("short runs accepted")
+	}
+	if _, err := DecodeRuns(EncodeRuns(vs), len(vs)-1); err == nil {
+		t.Fatal("long runs accepted")
+	}
+}
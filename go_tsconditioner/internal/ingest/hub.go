@@ -0,0 +1,67 @@
+package ingest
+
+import (
+	"go_tsconditioner/internal/dboperations"
+	"log"
+	"sync"
+)
+
+// hubBuffer : lots en attente par abonné avant abandon (client trop lent).
+const hubBuffer = 64
+
+// Hub diffuse les lignes ingérées localement aux abonnés (flux SSE) des
+// séries concernées, sans attendre la prochaine lecture de Telemetry.
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[*hubSub]struct{}
+}
+
+type hubSub struct {
+	pairs map[Pair]bool
+	ch    chan []dboperations.Telemetry
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*hubSub]struct{})}
+}
+
+// Subscribe renvoie le canal des lignes des séries pairs et la fonction de
+// désabonnement, à appeler une fois le flux terminé.
+func (h *Hub) Subscribe(pairs []Pair) (<-chan []dboperations.Telemetry, func()) {
+	s := &hubSub{pairs: make(map[Pair]bool, len(pairs)), ch: make(chan []dboperations.Telemetry, hubBuffer)}
+	for _, p := range pairs {
+		s.pairs[p] = true
+	}
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+	return s.ch, func() {
+		h.mu.Lock()
+		delete(h.subs, s)
+		h.mu.Unlock()
+	}
+}
+
+// Observe envoie à chaque abonné les lignes de ses séries ; un abonné dont le
+// tampon est plein perd le lot : le flux le rattrape en relisant la table où
+// les lignes sont écrites (cf. routeshandlers.StreamTelemetry).
+func (h *Hub) Observe(rows []dboperations.Telemetry) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.subs {
+		var mine []dboperations.Telemetry
+		for _, r := range rows {
+			if s.pairs[pairOf(r)] {
+				mine = append(mine, r)
+			}
+		}
+		if len(mine) == 0 {
+			continue
+		}
+		select {
+		case s.ch <- mine:
+		default:
+			log.Printf("⚠️ Flux live : abonné saturé, %d lignes non diffusées", len(mine))
+		}
+	}
+}
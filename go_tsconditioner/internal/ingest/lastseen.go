@@ -0,0 +1,101 @@
+// Package ingest regroupe ce qui pousse des points dans la Telemetry locale
+// (abonné MQTT) et l'état "dernier point vu" tenu à jour à chaque écriture.
+package ingest
+
+import (
+	"context"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/types"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Pair désigne une série de Telemetry.
+type Pair struct {
+	Device     string `json:"device"`
+	DataSource string `json:"datasource"`
+}
+
+func pairOf(r dboperations.Telemetry) Pair { return Pair{r.Device.String(), r.Datasource} }
+
+// Observer est notifié des lignes écrites avec succès (cf. Tracking).
+type Observer interface {
+	Observe(rows []dboperations.Telemetry)
+}
+
+// LastSeen garde en mémoire le dernier point écrit de chaque couple
+// device / datasource, pour que le rapport /report/latest voie les points
+// ingérés sans attendre la base distante.
+type LastSeen struct {
+	mu     sync.RWMutex
+	points map[Pair]types.LastTelemetryPoint
+}
+
+func NewLastSeen() *LastSeen {
+	return &LastSeen{points: make(map[Pair]types.LastTelemetryPoint)}
+}
+
+// Observe retient, par couple, la ligne la plus récente de rows.
+func (l *LastSeen) Observe(rows []dboperations.Telemetry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, r := range rows {
+		k := pairOf(r)
+		if p, ok := l.points[k]; ok && !r.Time.After(p.LastTime) {
+			continue
+		}
+		p := types.LastTelemetryPoint{Device: k.Device, DataSource: k.DataSource, LastTime: r.Time.UTC()}
+		if r.Serial != "" {
+			serial := r.Serial
+			p.Serial = &serial
+		}
+		if !math.IsNaN(r.Value) {
+			v := r.Value
+			p.LastValue = &v
+		}
+		l.points[k] = p
+	}
+}
+
+// Since renvoie les derniers points postérieurs à since, triés par device
+// puis datasource.
+func (l *LastSeen) Since(since time.Time) []types.LastTelemetryPoint {
+	l.mu.RLock()
+	out := make([]types.LastTelemetryPoint, 0, len(l.points))
+	for _, p := range l.points {
+		if !p.LastTime.Before(since) {
+			out = append(out, p)
+		}
+	}
+	l.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Device != out[j].Device {
+			return out[i].Device < out[j].Device
+		}
+		return out[i].DataSource < out[j].DataSource
+	})
+	return out
+}
+
+type trackingWriter struct {
+	w         dboperations.TelemetryWriter
+	observers []Observer
+}
+
+// Tracking enveloppe w : chaque écriture réussie est passée aux observers
+// (LastSeen, Hub).
+func Tracking(w dboperations.TelemetryWriter, observers ...Observer) dboperations.TelemetryWriter {
+	return trackingWriter{w: w, observers: observers}
+}
+
+func (t trackingWriter) Insert(ctx context.Context, rows []dboperations.Telemetry) error {
+	if err := t.w.Insert(ctx, rows); err != nil {
+		return err
+	}
+	for _, o := range t.observers {
+		o.Observe(rows)
+	}
+	return nil
+}
@@ -0,0 +1,439 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/config"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/timeseries"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+)
+
+// maxPendingBatches : au-delà de maxPendingBatches lots en attente (base
+// indisponible), les lignes les plus anciennes sont abandonnées.
+const maxPendingBatches = 20
+
+// queuedMessages : messages décodés en attente d'écriture ; file pleine, le
+// handler MQTT abandonne le message plutôt que de bloquer le client paho
+// (keepalive et autres topics).
+const queuedMessages = 1024
+
+// insertTimeout borne chaque écriture d'un lot ; après un échec, la suivante
+// attend de retryMin à retryMax (doublé à chaque échec). Variables pour les tests.
+var (
+	insertTimeout = 30 * time.Second
+	retryMin      = time.Second
+	retryMax      = time.Minute
+)
+
+// MQTTSubscriber s'abonne aux topics de config.MQTT, décode les messages JSON
+// en DataUnit et les écrit par lots dans "Telemetry" via w.
+type MQTTSubscriber struct {
+	cfg       config.MQTT
+	rules     []topicRule
+	w         dboperations.TelemetryWriter
+	batchSize int
+	flush     time.Duration
+	rows      chan []dboperations.Telemetry
+}
+
+func NewMQTTSubscriber(cfg config.MQTT, w dboperations.TelemetryWriter) (*MQTTSubscriber, error) {
+	s := &MQTTSubscriber{cfg: cfg, w: w, rows: make(chan []dboperations.Telemetry, queuedMessages)}
+	s.batchSize, s.flush = cfg.Batch()
+	for i, t := range cfg.Topics {
+		r, err := compileTopicRule(t)
+		if err != nil {
+			return nil, fmt.Errorf("TOPICS[%d]: %w", i, err)
+		}
+		s.rules = append(s.rules, r)
+	}
+	return s, nil
+}
+
+// Run se connecte au broker (et s'y reconnecte), puis écrit les lots jusqu'à
+// l'annulation de ctx ; le dernier lot est écrit avant de rendre la main.
+func (s *MQTTSubscriber) Run(ctx context.Context) {
+	clientID := s.cfg.ClientID
+	if clientID == "" {
+		clientID = "tsconditioner-" + uuid.NewString()[:8]
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(s.cfg.Broker).
+		SetClientID(clientID).
+		SetUsername(s.cfg.Username).
+		SetPassword(s.cfg.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10 * time.Second).
+		// session propre : on se réabonne à chaque (re)connexion
+		SetOnConnectHandler(s.subscribe).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Printf("⚠️ MQTT : connexion perdue (%s): %v", s.cfg.Broker, err)
+		})
+	client := mqtt.NewClient(opts)
+	client.Connect()
+	log.Printf("📡 MQTT : %s, %d topics", s.cfg.Broker, len(s.rules))
+
+	s.batchLoop(ctx)
+	client.Disconnect(250)
+}
+
+func (s *MQTTSubscriber) subscribe(client mqtt.Client) {
+	for _, r := range s.rules {
+		tok := client.Subscribe(r.cfg.Filter, s.cfg.QoS, func(_ mqtt.Client, m mqtt.Message) {
+			rows, err := r.rows(m.Topic(), m.Payload(), time.Now().UTC())
+			if err != nil {
+				log.Printf("⚠️ MQTT %s : message ignoré: %v", m.Topic(), err)
+				return
+			}
+			s.enqueue(m.Topic(), rows)
+		})
+		if tok.Wait() && tok.Error() != nil {
+			log.Printf("❌ MQTT : abonnement %s: %v", r.cfg.Filter, tok.Error())
+		}
+	}
+}
+
+// enqueue confie rows à batchLoop sans jamais bloquer l'appelant.
+func (s *MQTTSubscriber) enqueue(topic string, rows []dboperations.Telemetry) {
+	if len(rows) == 0 {
+		return
+	}
+	select {
+	case s.rows <- rows:
+	default:
+		log.Printf("⚠️ MQTT %s : file d'écriture pleine, %d lignes abandonnées", topic, len(rows))
+	}
+}
+
+func (s *MQTTSubscriber) batchLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.flush)
+	defer ticker.Stop()
+	var (
+		pending []dboperations.Telemetry
+		backoff time.Duration // > 0 : la dernière écriture a échoué
+		retryAt time.Time
+	)
+	flush := func() {
+		if len(pending) == 0 || time.Now().Before(retryAt) {
+			return
+		}
+		if err := s.write(ctx, pending); err != nil {
+			backoff = min(max(2*backoff, retryMin), retryMax)
+			retryAt = time.Now().Add(backoff)
+			log.Printf("❌ MQTT : écriture de %d lignes Telemetry (nouvel essai dans %s): %v", len(pending), backoff, err)
+			return
+		}
+		pending, backoff, retryAt = pending[:0], 0, time.Time{}
+	}
+	for {
+		select {
+		case rows := <-s.rows:
+			pending = s.bound(append(pending, rows...))
+			if len(pending) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+		drain:
+			for {
+				select {
+				case rows := <-s.rows:
+					pending = append(pending, rows...)
+				default:
+					break drain
+				}
+			}
+			if len(pending) > 0 {
+				flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				if err := s.write(flushCtx, pending); err != nil {
+					log.Printf("❌ MQTT : dernier lot de %d lignes Telemetry perdu: %v", len(pending), err)
+				}
+				cancel()
+			}
+			return
+		}
+	}
+}
+
+// write écrit pending en un lot, borné par insertTimeout.
+func (s *MQTTSubscriber) write(ctx context.Context, pending []dboperations.Telemetry) error {
+	ctx, cancel := context.WithTimeout(ctx, insertTimeout)
+	defer cancel()
+	return s.w.Insert(ctx, pending)
+}
+
+// bound garde au plus maxPendingBatches lots, en abandonnant les plus anciennes lignes.
+func (s *MQTTSubscriber) bound(pending []dboperations.Telemetry) []dboperations.Telemetry {
+	if limit := maxPendingBatches * s.batchSize; len(pending) > limit {
+		log.Printf("⚠️ MQTT : %d lignes abandonnées", len(pending)-limit)
+		pending = pending[len(pending)-limit:]
+	}
+	return pending
+}
+
+// topicRule : une entrée TOPICS compilée.
+type topicRule struct {
+	cfg    config.MQTTTopic
+	levels []string // niveaux du filtre
+	device *uuid.UUID
+}
+
+var placeholder = regexp.MustCompile(`\{(\d+|#)\}`)
+
+func compileTopicRule(t config.MQTTTopic) (topicRule, error) {
+	r := topicRule{cfg: t, levels: strings.Split(t.Filter, "/")}
+	plus := 0
+	for i, l := range r.levels {
+		switch {
+		case l == "+":
+			plus++
+		case l == "#":
+			if i != len(r.levels)-1 {
+				return r, fmt.Errorf("filter %q: '#' must be the last level", t.Filter)
+			}
+		case strings.ContainsAny(l, "+#"):
+			return r, fmt.Errorf("filter %q: wildcards must occupy a whole level", t.Filter)
+		}
+	}
+	for _, tmpl := range []string{t.Device, t.DataSource, t.Serial} {
+		for _, m := range placeholder.FindAllStringSubmatch(tmpl, -1) {
+			if m[1] == "#" {
+				if r.levels[len(r.levels)-1] != "#" {
+					return r, fmt.Errorf("%q uses {#} but filter %q has no '#'", tmpl, t.Filter)
+				}
+				continue
+			}
+			if n, _ := strconv.Atoi(m[1]); n < 1 || n > plus {
+				return r, fmt.Errorf("%q: filter %q has %d '+' levels", tmpl, t.Filter, plus)
+			}
+		}
+	}
+	if !placeholder.MatchString(t.Device) {
+		id, err := uuid.Parse(t.Device)
+		if err != nil {
+			return r, fmt.Errorf("DEVICE %q is not a UUID", t.Device)
+		}
+		r.device = &id
+	}
+	return r, nil
+}
+
+// match renvoie les niveaux captés par les '+' et la suite captée par '#'.
+func (r topicRule) match(topic string) (captures []string, rest string, ok bool) {
+	levels := strings.Split(topic, "/")
+	for i, f := range r.levels {
+		if f == "#" {
+			return captures, strings.Join(levels[i:], "/"), true
+		}
+		if i >= len(levels) {
+			return nil, "", false
+		}
+		switch f {
+		case "+":
+			captures = append(captures, levels[i])
+		default:
+			if f != levels[i] {
+				return nil, "", false
+			}
+		}
+	}
+	return captures, "", len(levels) == len(r.levels)
+}
+
+func expand(tmpl string, captures []string, rest string) string {
+	return placeholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		key := m[1 : len(m)-1]
+		if key == "#" {
+			return rest
+		}
+		n, _ := strconv.Atoi(key)
+		return captures[n-1]
+	})
+}
+
+// rows décode un message reçu sur topic en lignes Telemetry.
+func (r topicRule) rows(topic string, payload []byte, now time.Time) ([]dboperations.Telemetry, error) {
+	captures, rest, ok := r.match(topic)
+	if !ok {
+		return nil, fmt.Errorf("topic does not match %q", r.cfg.Filter)
+	}
+	var device uuid.UUID
+	if r.device != nil {
+		device = *r.device
+	} else {
+		var err error
+		if device, err = uuid.Parse(expand(r.cfg.Device, captures, rest)); err != nil {
+			return nil, fmt.Errorf("device %q is not a UUID", expand(r.cfg.Device, captures, rest))
+		}
+	}
+	series, err := r.decode(payload, expand(r.cfg.DataSource, captures, rest), now)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(series))
+	for ds := range series {
+		names = append(names, ds)
+	}
+	sort.Strings(names)
+	var rows []dboperations.Telemetry
+	for _, ds := range names {
+		tmpl := dboperations.Telemetry{Device: device, Datasource: ds, Serial: expand(r.cfg.Serial, captures, rest)}
+		rows = append(rows, dboperations.TimeSeriesToTelemetryRows(series[ds], tmpl)...)
+	}
+	return rows, nil
+}
+
+// decode accepte un nombre, un objet ou un tableau d'objets / de nombres.
+// Avec datasource, la valeur est le champ VALUE_FIELD ; sans, chaque champ
+// numérique (ou booléen) hors TIME_FIELD est une datasource.
+func (r topicRule) decode(payload []byte, datasource string, now time.Time) (map[string]*timeseries.TimeSeries, error) {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	items, isArray := v.([]any)
+	if !isArray {
+		items = []any{v}
+	}
+
+	series := make(map[string]*timeseries.TimeSeries)
+	add := func(ds string, at time.Time, meas float64) {
+		ts, ok := series[ds]
+		if !ok {
+			ts = &timeseries.TimeSeries{Name: ds}
+			series[ds] = ts
+		}
+		ts.AddData(at, meas)
+	}
+	for _, item := range items {
+		switch x := item.(type) {
+		case json.Number:
+			if datasource == "" {
+				return nil, errors.New("bare number needs DATASOURCE")
+			}
+			f, err := x.Float64()
+			if err != nil {
+				return nil, err
+			}
+			add(datasource, now, f)
+		case map[string]any:
+			at, err := r.timestamp(x, now)
+			if err != nil {
+				return nil, err
+			}
+			if datasource != "" {
+				field := r.valueField()
+				f, ok := numeric(x[field])
+				if !ok {
+					return nil, fmt.Errorf("missing numeric field %q", field)
+				}
+				add(datasource, at, f)
+				continue
+			}
+			for k, fv := range x {
+				if k == r.timeField() {
+					continue
+				}
+				if f, ok := numeric(fv); ok {
+					add(k, at, f)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unexpected JSON value %T", item)
+		}
+	}
+	return series, nil
+}
+
+func (r topicRule) valueField() string {
+	if r.cfg.ValueField == "" {
+		return "value"
+	}
+	return r.cfg.ValueField
+}
+
+func (r topicRule) timeField() string {
+	if r.cfg.TimeField == "" {
+		return "time"
+	}
+	return r.cfg.TimeField
+}
+
+func (r topicRule) timestamp(obj map[string]any, now time.Time) (time.Time, error) {
+	switch t := obj[r.timeField()].(type) {
+	case nil:
+		return now, nil
+	case string:
+		at, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return now, fmt.Errorf("field %q: %w", r.timeField(), err)
+		}
+		return at.UTC(), nil
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			switch r.cfg.TimeUnit {
+			case "ms":
+				return time.UnixMilli(n).UTC(), nil
+			case "us":
+				return time.UnixMicro(n).UTC(), nil
+			case "ns":
+				return time.Unix(0, n).UTC(), nil
+			default:
+				return time.Unix(n, 0).UTC(), nil
+			}
+		}
+		// fraction d'unité (ex: 1735689600.25 s)
+		f, err := t.Float64()
+		if err != nil {
+			return now, fmt.Errorf("field %q: %w", r.timeField(), err)
+		}
+		return time.Unix(0, int64(math.Round(f*1e9/unitsPerSecond(r.cfg.TimeUnit)))).UTC(), nil
+	default:
+		return now, fmt.Errorf("field %q: unexpected %T", r.timeField(), t)
+	}
+}
+
+func unitsPerSecond(unit string) float64 {
+	switch unit {
+	case "ms":
+		return 1e3
+	case "us":
+		return 1e6
+	case "ns":
+		return 1e9
+	default:
+		return 1
+	}
+}
+
+func numeric(v any) (float64, bool) {
+	switch x := v.(type) {
+	case json.Number:
+		f, err := x.Float64()
+		return f, err == nil
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
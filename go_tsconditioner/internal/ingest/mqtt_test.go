@@ -0,0 +1,196 @@
+package ingest
+
+import (
+	"context"
+	"go_tsconditioner/internal/config"
+	"go_tsconditioner/internal/dboperations"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+)
+
+const testDevice = "6f1c2a0e-5b7d-4c3e-9a41-0d2b8e7f1a23"
+
+func TestTopicRuleDecoding(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	r, err := compileTopicRule(config.MQTTTopic{Filter: "sites/+/meters/+/#", Device: "{2}", DataSource: "{#}", Serial: "{1}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := r.rows("sites/lux/meters/"+testDevice+"/power/active", []byte(`[{"value": 1.5, "time": "2025-01-01T10:00:00Z"}, 2.5]`), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Device.String() != testDevice || rows[0].Datasource != "power/active" || rows[0].Serial != "lux" {
+		t.Fatalf("rows: %+v", rows)
+	}
+	if !rows[0].Time.Equal(time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)) || !rows[1].Time.Equal(now) || rows[1].Value != 2.5 || rows[1].State != "OK" {
+		t.Fatalf("rows: %+v", rows)
+	}
+
+	// sans DATASOURCE : un champ numérique = une datasource
+	r, err = compileTopicRule(config.MQTTTopic{Filter: "zigbee/+", Device: testDevice, TimeField: "ts", TimeUnit: "ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err = r.rows("zigbee/salon", []byte(`{"temperature": 21.5, "occupancy": true, "battery": "low", "ts": 1735729200000}`), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Datasource != "occupancy" || rows[0].Value != 1 || rows[1].Datasource != "temperature" || rows[1].Time.Unix() != 1735729200 {
+		t.Fatalf("rows: %+v", rows)
+	}
+
+	for _, bad := range []struct {
+		topic   string
+		payload string
+	}{
+		{"zigbee/salon/extra", `{"temperature": 1}`},
+		{"zigbee/salon", `not json`},
+		{"zigbee/salon", `21.5`}, // nombre nu sans DATASOURCE
+		{"zigbee/salon", `{"temperature": 1, "ts": "hier"}`},
+	} {
+		if _, err := r.rows(bad.topic, []byte(bad.payload), now); err == nil {
+			t.Errorf("%s %s: expected error", bad.topic, bad.payload)
+		}
+	}
+
+	for _, cfg := range []config.MQTTTopic{
+		{Filter: "a/#/b", Device: testDevice},
+		{Filter: "a/b+", Device: testDevice},
+		{Filter: "a/+", Device: "{2}"},
+		{Filter: "a/+", Device: "{#}"},
+		{Filter: "a/+", Device: "not-a-uuid"},
+	} {
+		if _, err := compileTopicRule(cfg); err == nil {
+			t.Errorf("%+v: expected error", cfg)
+		}
+	}
+}
+
+// Broker MQTT embarqué (mochi) : messages retenus publiés avant l'abonnement,
+// écrits par lot dans une Telemetry SQLite, et visibles dans LastSeen.
+func TestMQTTSubscriberWithEmbeddedBroker(t *testing.T) {
+	broker := mochi.New(&mochi.Options{InlineClient: true, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err := broker.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatal(err)
+	}
+	tcp := listeners.NewTCP(listeners.Config{ID: "test", Address: "127.0.0.1:0"})
+	if err := broker.AddListener(tcp); err != nil {
+		t.Fatal(err)
+	}
+	go broker.Serve()
+	defer broker.Close()
+
+	if err := broker.Publish("meters/"+testDevice+"/power", []byte(`{"value": 3.5, "time": "2025-01-01T00:00:00Z"}`), true, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Publish("meters/"+testDevice+"/temp", []byte(`[{"value": 20, "time": 1735689600}, {"value": 21, "time": 1735689660}]`), true, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := dboperations.OpenSQLiteTelemetry(filepath.Join(t.TempDir(), "telemetry.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	seen := NewLastSeen()
+
+	sub, err := NewMQTTSubscriber(config.MQTT{
+		Broker:       "tcp://" + tcp.Address(),
+		QoS:          1,
+		BatchSize:    100,
+		FlushSeconds: 1,
+		Topics:       []config.MQTTTopic{{Filter: "meters/+/+", Device: "{1}", DataSource: "{2}"}},
+	}, Tracking(repo, seen))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { sub.Run(ctx); close(done) }()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for len(seen.Since(time.Time{})) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("last seen: %+v", seen.Since(time.Time{}))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	points := seen.Since(time.Time{})
+	if points[1].DataSource != "temp" || *points[1].LastValue != 21 || !points[1].LastTime.Equal(time.Unix(1735689660, 0)) {
+		t.Fatalf("last seen temp: %+v", points[1])
+	}
+	ts, err := repo.ReadRange(context.Background(), dboperations.TelemetryQuery{
+		Device: testDevice, DataSource: "temp", From: time.Unix(1735689000, 0), To: time.Unix(1735690000, 0),
+	})
+	if err != nil || len(ts.DataSeries) != 2 {
+		t.Fatalf("telemetry temp: %+v %v", ts, err)
+	}
+}
+
+// blockingWriter simule une base qui ne répond plus : Insert attend la fin du contexte.
+type blockingWriter struct {
+	calls chan time.Time // échéance du contexte de chaque appel
+}
+
+func (w *blockingWriter) Insert(ctx context.Context, rows []dboperations.Telemetry) error {
+	dl, _ := ctx.Deadline()
+	w.calls <- dl
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// Base bloquée : chaque écriture a son échéance, les essais s'espacent et le
+// handler MQTT n'est jamais bloqué par la file pleine.
+func TestMQTTWriteTimeoutAndBackoff(t *testing.T) {
+	defer func(a, b, c time.Duration) { insertTimeout, retryMin, retryMax = a, b, c }(insertTimeout, retryMin, retryMax)
+	insertTimeout, retryMin, retryMax = 20*time.Millisecond, 200*time.Millisecond, time.Second
+
+	w := &blockingWriter{calls: make(chan time.Time, 100)}
+	sub, err := NewMQTTSubscriber(config.MQTT{
+		Broker: "tcp://127.0.0.1:0", BatchSize: 1, FlushSeconds: 1,
+		Topics: []config.MQTTTopic{{Filter: "meters/+", Device: testDevice, DataSource: "power"}},
+	}, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { sub.batchLoop(ctx); close(done) }()
+
+	row := []dboperations.Telemetry{{Datasource: "power", Value: 1}}
+	start := time.Now()
+	for i := 0; i < 3*queuedMessages; i++ {
+		sub.enqueue("meters/a", row)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("enqueue blocked for %s", d)
+	}
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+	<-done
+	close(w.calls)
+
+	var n int
+	for dl := range w.calls {
+		if dl.IsZero() {
+			t.Fatalf("Insert called without deadline")
+		}
+		n++
+	}
+	// un essai, un second après retryMin, puis le dernier lot à l'arrêt
+	if n < 2 || n > 3 {
+		t.Fatalf("Insert called %d times in 300ms, want backoff between attempts", n)
+	}
+}
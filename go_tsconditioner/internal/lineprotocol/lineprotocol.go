@@ -0,0 +1,290 @@
+// Package lineprotocol lit le line protocol InfluxDB (Telegraf, Node-RED...) :
+//
+//	measurement[,tag=valeur...] champ=valeur[,champ=valeur...] [timestamp]
+//
+// Échappements : "\," et "\ " dans la measurement ; "\,", "\=" et "\ " dans
+// les clés et valeurs de tags et les clés de champs ; "\"" et "\\" dans les
+// chaînes. Les lignes vides et celles commençant par '#' sont ignorées.
+package lineprotocol
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLineSize borne une ligne (beaucoup de champs ou longues chaînes).
+const maxLineSize = 1 << 20
+
+type FieldKind int
+
+const (
+	Float FieldKind = iota
+	Integer
+	Unsigned
+	Boolean
+	String
+)
+
+func (k FieldKind) String() string {
+	switch k {
+	case Float:
+		return "float"
+	case Integer:
+		return "integer"
+	case Unsigned:
+		return "unsigned"
+	case Boolean:
+		return "boolean"
+	case String:
+		return "string"
+	default:
+		return fmt.Sprintf("FieldKind(%d)", int(k))
+	}
+}
+
+// Field : Value porte les champs numériques (booléens : 1 / 0), Str les chaînes.
+type Field struct {
+	Key   string
+	Kind  FieldKind
+	Value float64
+	Str   string
+}
+
+// Numeric indique si le champ peut devenir une mesure.
+func (f Field) Numeric() bool { return f.Kind != String }
+
+type Point struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      []Field
+	Time        time.Time
+	Line        int // numéro de ligne dans le lot (Parse)
+}
+
+// ParseError situe l'erreur (ligne à partir de 1).
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string { return fmt.Sprintf("line %d: %s", e.Line, e.Msg) }
+
+// ParsePrecision accepte les précisions d'InfluxDB v1 et v2 : "" ou ns / n,
+// us / u, ms, s, m, h.
+func ParsePrecision(s string) (time.Duration, error) {
+	switch s {
+	case "", "ns", "n":
+		return time.Nanosecond, nil
+	case "us", "u":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	default:
+		return 0, fmt.Errorf("unknown precision %q (ns, us, ms, s, m, h)", s)
+	}
+}
+
+// Parse lit toutes les lignes de r. Les timestamps sont exprimés en precision ;
+// un point sans timestamp prend now. La première ligne invalide arrête la
+// lecture (*ParseError) : rien n'est renvoyé d'un lot partiellement valide.
+func Parse(r io.Reader, precision time.Duration, now time.Time) ([]Point, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var points []Point
+	n := 0
+	for sc.Scan() {
+		n++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		p, err := ParseLine(line, precision, now)
+		if err != nil {
+			// une erreur de lecture (corps borné par MaxBytesReader...) livre
+			// d'abord la ligne tronquée : c'est elle qu'on renvoie alors
+			if !sc.Scan() && sc.Err() != nil && !errors.Is(sc.Err(), bufio.ErrTooLong) {
+				return nil, sc.Err()
+			}
+			return nil, &ParseError{Line: n, Msg: err.Error()}
+		}
+		p.Line = n
+		points = append(points, p)
+	}
+	if err := sc.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, &ParseError{Line: n + 1, Msg: fmt.Sprintf("line longer than %d bytes", maxLineSize)}
+		}
+		return nil, err
+	}
+	return points, nil
+}
+
+// ParseLine lit une ligne (sans retour chariot).
+func ParseLine(line string, precision time.Duration, now time.Time) (Point, error) {
+	p := Point{Tags: map[string]string{}}
+
+	var i int
+	p.Measurement, i = scanToken(line, 0, ", ", ", ")
+	if p.Measurement == "" {
+		return p, errors.New("missing measurement")
+	}
+
+	// tags
+	for i < len(line) && line[i] == ',' {
+		var key, val string
+		key, i = scanToken(line, i+1, "=, ", ",= ")
+		if i >= len(line) || line[i] != '=' || key == "" {
+			return p, fmt.Errorf("invalid tag at column %d", i+1)
+		}
+		val, i = scanToken(line, i+1, ", ", ",= ")
+		if val == "" {
+			return p, fmt.Errorf("empty value for tag %q", key)
+		}
+		p.Tags[key] = val
+	}
+	if i >= len(line) || line[i] != ' ' {
+		return p, errors.New("missing fields")
+	}
+	i = skipSpaces(line, i)
+
+	// champs
+	for {
+		var key string
+		key, i = scanToken(line, i, "=, ", ",= ")
+		if i >= len(line) || line[i] != '=' || key == "" {
+			return p, fmt.Errorf("invalid field at column %d", i+1)
+		}
+		i++
+		f := Field{Key: key}
+		if i < len(line) && line[i] == '"' {
+			s, next, ok := scanString(line, i+1)
+			if !ok {
+				return p, fmt.Errorf("unterminated string for field %q", key)
+			}
+			f.Kind, f.Str, i = String, s, next
+		} else {
+			start := i
+			for i < len(line) && line[i] != ',' && line[i] != ' ' {
+				i++
+			}
+			var err error
+			if f.Kind, f.Value, err = parseFieldValue(line[start:i]); err != nil {
+				return p, fmt.Errorf("field %q: %w", key, err)
+			}
+		}
+		p.Fields = append(p.Fields, f)
+		if i < len(line) && line[i] == ',' {
+			i++
+			continue
+		}
+		break
+	}
+
+	// timestamp
+	rest := strings.TrimSpace(line[i:])
+	if i < len(line) && line[i] != ' ' {
+		return p, fmt.Errorf("unexpected character at column %d", i+1)
+	}
+	if rest == "" {
+		p.Time = now
+		return p, nil
+	}
+	ts, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil {
+		return p, fmt.Errorf("invalid timestamp %q", rest)
+	}
+	if precision > 1 && (ts > math.MaxInt64/int64(precision) || ts < math.MinInt64/int64(precision)) {
+		return p, fmt.Errorf("timestamp %d out of range", ts)
+	}
+	p.Time = time.Unix(0, ts*int64(precision)).UTC()
+	return p, nil
+}
+
+// scanToken lit depuis i jusqu'au premier caractère de stops non échappé ;
+// seuls les caractères de escapable se déséchappent.
+func scanToken(line string, i int, stops, escapable string) (string, int) {
+	var sb strings.Builder
+	for i < len(line) {
+		c := line[i]
+		if c == '\\' && i+1 < len(line) && strings.IndexByte(escapable, line[i+1]) >= 0 {
+			sb.WriteByte(line[i+1])
+			i += 2
+			continue
+		}
+		if strings.IndexByte(stops, c) >= 0 {
+			break
+		}
+		sb.WriteByte(c)
+		i++
+	}
+	return sb.String(), i
+}
+
+// scanString lit une chaîne à partir du caractère suivant le guillemet ouvrant.
+func scanString(line string, i int) (string, int, bool) {
+	var sb strings.Builder
+	for i < len(line) {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line) && (line[i+1] == '"' || line[i+1] == '\\'):
+			sb.WriteByte(line[i+1])
+			i += 2
+		case c == '"':
+			return sb.String(), i + 1, true
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return "", i, false
+}
+
+func skipSpaces(line string, i int) int {
+	for i < len(line) && line[i] == ' ' {
+		i++
+	}
+	return i
+}
+
+func parseFieldValue(s string) (FieldKind, float64, error) {
+	switch s {
+	case "":
+		return Float, 0, errors.New("empty value")
+	case "t", "T", "true", "True", "TRUE":
+		return Boolean, 1, nil
+	case "f", "F", "false", "False", "FALSE":
+		return Boolean, 0, nil
+	}
+	switch s[len(s)-1] {
+	case 'i':
+		v, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+		if err != nil {
+			return Integer, 0, fmt.Errorf("invalid integer %q", s)
+		}
+		return Integer, float64(v), nil
+	case 'u':
+		v, err := strconv.ParseUint(s[:len(s)-1], 10, 64)
+		if err != nil {
+			return Unsigned, 0, fmt.Errorf("invalid unsigned %q", s)
+		}
+		return Unsigned, float64(v), nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return Float, 0, fmt.Errorf("invalid float %q", s)
+	}
+	return Float, v, nil
+}
@@ -0,0 +1,98 @@
+package lineprotocol
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestParseLineEscapesAndTypes(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	p, err := ParseLine(`my\ meter,site=Paris\,\ 15e,device=abc power=1.5,count=3i,big=7u,on=t,label="a \"b\" c" 1735689600000`, time.Millisecond, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Measurement != "my meter" || p.Tags["site"] != "Paris, 15e" || p.Tags["device"] != "abc" {
+		t.Fatalf("measurement/tags: %+v", p)
+	}
+	want := []Field{
+		{Key: "power", Kind: Float, Value: 1.5},
+		{Key: "count", Kind: Integer, Value: 3},
+		{Key: "big", Kind: Unsigned, Value: 7},
+		{Key: "on", Kind: Boolean, Value: 1},
+		{Key: "label", Kind: String, Str: `a "b" c`},
+	}
+	if len(p.Fields) != len(want) {
+		t.Fatalf("fields: %+v", p.Fields)
+	}
+	for i, f := range want {
+		if p.Fields[i] != f {
+			t.Fatalf("field %d: %+v, want %+v", i, p.Fields[i], f)
+		}
+	}
+	if !p.Time.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("time: %v", p.Time)
+	}
+
+	p, err = ParseLine("cpu value=2", time.Nanosecond, now)
+	if err != nil || !p.Time.Equal(now) {
+		t.Fatalf("no timestamp: %v %v", p.Time, err)
+	}
+}
+
+func TestParseReportsLine(t *testing.T) {
+	in := "# commentaire\ncpu value=1 10\n\ncpu value=oops 20\n"
+	_, err := Parse(strings.NewReader(in), time.Second, time.Now())
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Line != 4 {
+		t.Fatalf("want error on line 4, got %v", err)
+	}
+
+	points, err := Parse(strings.NewReader("cpu value=1 10\r\ncpu value=2 20\n"), time.Second, time.Now())
+	if err != nil || len(points) != 2 || points[1].Line != 2 || points[1].Time.Unix() != 20 {
+		t.Fatalf("points: %+v %v", points, err)
+	}
+}
+
+// Une lecture interrompue au milieu d'une ligne renvoie l'erreur de lecture,
+// pas une erreur de syntaxe sur la ligne tronquée.
+func TestParseReadErrorWins(t *testing.T) {
+	boom := errors.New("body too large")
+	r := io.MultiReader(strings.NewReader("cpu value=1 10\ncpu val"), iotest.ErrReader(boom))
+	if _, err := Parse(r, time.Second, time.Now()); !errors.Is(err, boom) {
+		t.Fatalf("want read error, got %v", err)
+	}
+}
+
+func TestParseLineErrors(t *testing.T) {
+	for _, line := range []string{
+		"cpu",
+		",tag=a value=1",
+		"cpu,tag value=1",
+		"cpu,tag= value=1",
+		"cpu value=",
+		`cpu value="open`,
+		"cpu value=1 notatime",
+		"cpu value=NaN",
+		"cpu value=1i2",
+		"cpu value=1 99999999999999999",
+	} {
+		if _, err := ParseLine(line, time.Hour, time.Now()); err == nil {
+			t.Errorf("%q: expected error", line)
+		}
+	}
+}
+
+func TestParsePrecision(t *testing.T) {
+	for in, want := range map[string]time.Duration{"": time.Nanosecond, "u": time.Microsecond, "ms": time.Millisecond, "s": time.Second} {
+		if got, err := ParsePrecision(in); err != nil || got != want {
+			t.Errorf("%q: %v %v", in, got, err)
+		}
+	}
+	if _, err := ParsePrecision("d"); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,188 @@
+// Package migrations applique les schémas SQL versionnés embarqués dans le binaire
+// (sql/<set>/NNNN_description.sql) et trace les versions appliquées, par jeu,
+// dans la table schema_migrations de chaque base.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed sql
+var files embed.FS
+
+// Jeux de migrations disponibles, un par base.
+const (
+	Postgres  = "postgres"  // base PostgreSQL locale : timeseries, dataunits
+	Devices   = "devices"   // alldatasources.sqlite : datasources
+	Alerts    = "alerts"    // alerts.sqlite : alert_rules, alert_events
+	Series    = "series"    // SQLite : mêmes tables que Postgres (timeseries, dataunits)
+	Telemetry = "telemetry" // SQLite : copie locale de "Telemetry" pour tourner hors ligne
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	SQL     string
+}
+
+type Status struct {
+	Version   int64      `db:"version"`
+	Name      string     `db:"name"`
+	AppliedAt *time.Time `db:"applied_at"`
+}
+
+// Chaque jeu numérote ses migrations à partir de 0001 : la clé inclut le jeu
+// pour que deux jeux appliqués à la même base ne se masquent pas.
+const createVersionsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		set_name   TEXT      NOT NULL,
+		version    BIGINT    NOT NULL,
+		name       TEXT      NOT NULL,
+		applied_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (set_name, version)
+	);
+`
+
+// Load renvoie les migrations d'un jeu, triées par version.
+func Load(set string) ([]Migration, error) {
+	dir := path.Join("sql", set)
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("unknown migration set %q: %w", set, err)
+	}
+
+	var out []Migration
+	seen := make(map[int64]string, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		base := strings.TrimSuffix(e.Name(), ".sql")
+		prefix, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s/%s: expected NNNN_description.sql", set, e.Name())
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s/%s: invalid version %q", set, e.Name(), prefix)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migration %s: version %d used by %s and %s", set, version, other, e.Name())
+		}
+		seen[version] = e.Name()
+
+		b, err := fs.ReadFile(files, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Migration{Version: version, Name: name, SQL: string(b)})
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Up applique, dans l'ordre et chacune dans sa transaction, les migrations du jeu
+// qui ne figurent pas encore dans schema_migrations. Elle renvoie celles appliquées.
+func Up(ctx context.Context, db *sqlx.DB, set string) ([]Migration, error) {
+	all, err := Load(set)
+	if err != nil {
+		return nil, err
+	}
+	done, err := appliedVersions(ctx, db, set)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, m := range all {
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+		if err := apply(ctx, db, set, m); err != nil {
+			return applied, fmt.Errorf("migration %s/%04d_%s: %w", set, m.Version, m.Name, err)
+		}
+		log.Printf("✅ migration %s/%04d_%s appliquée", set, m.Version, m.Name)
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// StatusOf liste toutes les migrations du jeu avec leur date d'application (nil si en attente).
+func StatusOf(ctx context.Context, db *sqlx.DB, set string) ([]Status, error) {
+	all, err := Load(set)
+	if err != nil {
+		return nil, err
+	}
+	done, err := appliedVersions(ctx, db, set)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Status, len(all))
+	for i, m := range all {
+		out[i] = Status{Version: m.Version, Name: m.Name}
+		if at, ok := done[m.Version]; ok {
+			at := at
+			out[i].AppliedAt = &at
+		}
+	}
+	return out, nil
+}
+
+func appliedVersions(ctx context.Context, db *sqlx.DB, set string) (map[int64]time.Time, error) {
+	if _, err := db.ExecContext(ctx, createVersionsTable); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+	var rows []Status
+	q := db.Rebind(`SELECT version, name, applied_at FROM schema_migrations WHERE set_name = ?`)
+	if err := db.SelectContext(ctx, &rows, q, set); err != nil {
+		return nil, fmt.Errorf("select schema_migrations: %w", err)
+	}
+	out := make(map[int64]time.Time, len(rows))
+	for _, r := range rows {
+		if r.AppliedAt != nil {
+			out[r.Version] = *r.AppliedAt
+		}
+	}
+	return out, nil
+}
+
+func apply(ctx context.Context, db *sqlx.DB, set string, m Migration) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return err
+	}
+	// la clé primaire (set_name, version) empêche une double application concurrente
+	q := tx.Rebind(`INSERT INTO schema_migrations (set_name, version, name, applied_at) VALUES (?, ?, ?, ?)`)
+	if _, err := tx.ExecContext(ctx, q, set, m.Version, m.Name, time.Now().UTC()); err != nil {
+		return fmt.Errorf("record version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
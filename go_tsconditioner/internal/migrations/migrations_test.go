@@ -0,0 +1,99 @@
+package migrations
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+func TestLoadSetsAreOrdered(t *testing.T) {
+	for _, set := range []string{Postgres, Devices, Alerts, Series, Telemetry} {
+		ms, err := Load(set)
+		if err != nil {
+			t.Fatalf("%s: %v", set, err)
+		}
+		if len(ms) == 0 {
+			t.Fatalf("%s: no migrations embedded", set)
+		}
+		for i := 1; i < len(ms); i++ {
+			if ms[i-1].Version >= ms[i].Version {
+				t.Fatalf("%s: versions not strictly increasing: %d then %d", set, ms[i-1].Version, ms[i].Version)
+			}
+		}
+	}
+	if _, err := Load("nope"); err == nil {
+		t.Fatalf("unknown set must fail")
+	}
+}
+
+func TestUpSQLiteIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	for _, set := range []string{Devices, Alerts, Series, Telemetry} {
+		db, err := sqlx.Open("sqlite", filepath.Join(t.TempDir(), set+".sqlite"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		all, _ := Load(set)
+		applied, err := Up(ctx, db, set)
+		if err != nil {
+			t.Fatalf("%s first Up: %v", set, err)
+		}
+		if len(applied) != len(all) {
+			t.Fatalf("%s: applied %d, want %d", set, len(applied), len(all))
+		}
+
+		again, err := Up(ctx, db, set)
+		if err != nil {
+			t.Fatalf("%s second Up: %v", set, err)
+		}
+		if len(again) != 0 {
+			t.Fatalf("%s: second Up applied %d migrations, want 0", set, len(again))
+		}
+
+		st, err := StatusOf(ctx, db, set)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range st {
+			if s.AppliedAt == nil {
+				t.Fatalf("%s: version %d reported as pending", set, s.Version)
+			}
+		}
+	}
+}
+
+// Deux jeux numérotés à partir de 0001 dans la même base : aucun ne doit
+// masquer l'autre.
+func TestUpTwoSetsSameDatabase(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlx.Open("sqlite", filepath.Join(t.TempDir(), "shared.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, set := range []string{Alerts, Devices} {
+		all, _ := Load(set)
+		applied, err := Up(ctx, db, set)
+		if err != nil {
+			t.Fatalf("%s: %v", set, err)
+		}
+		if len(applied) != len(all) {
+			t.Fatalf("%s: applied %d, want %d", set, len(applied), len(all))
+		}
+	}
+	st, err := StatusOf(ctx, db, Devices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range st {
+		if s.AppliedAt == nil {
+			t.Fatalf("devices version %d reported as pending", s.Version)
+		}
+	}
+}
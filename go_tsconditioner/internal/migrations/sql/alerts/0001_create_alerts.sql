@@ -0,0 +1,30 @@
+-- Règles d'alerte et événements (alerts.sqlite).
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    name             TEXT     NOT NULL DEFAULT '',
+    device           TEXT     NOT NULL,
+    datasource       TEXT     NOT NULL,
+    kind             TEXT     NOT NULL,
+    operator         TEXT     NOT NULL DEFAULT '',
+    threshold        REAL     NOT NULL DEFAULT 0,
+    duration_seconds INTEGER  NOT NULL,
+    enabled          INTEGER  NOT NULL DEFAULT 1,
+    created_at       DATETIME NOT NULL,
+    updated_at       DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS alert_events (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    rule_id         INTEGER  NOT NULL,
+    device          TEXT     NOT NULL,
+    datasource      TEXT     NOT NULL,
+    state           TEXT     NOT NULL,
+    message         TEXT     NOT NULL DEFAULT '',
+    last_value      REAL,
+    opened_at       DATETIME NOT NULL,
+    acknowledged_at DATETIME,
+    resolved_at     DATETIME,
+    updated_at      DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_events_rule_state ON alert_events (rule_id, state);
@@ -0,0 +1,8 @@
+-- Cache des couples device/datasource (alldatasources.sqlite).
+CREATE TABLE IF NOT EXISTS datasources (
+    device     TEXT NOT NULL,
+    serial     TEXT NOT NULL DEFAULT '',
+    datasource TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_datasources_device_datasource ON datasources (device, datasource);
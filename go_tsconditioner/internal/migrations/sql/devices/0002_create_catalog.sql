@@ -0,0 +1,15 @@
+-- Catalogue des métadonnées par datasource (alldatasources.sqlite).
+-- Indépendant de datasources, qui est vidée à chaque rafraîchissement.
+CREATE TABLE IF NOT EXISTS datasource_catalog (
+    device          TEXT     NOT NULL,
+    datasource      TEXT     NOT NULL,
+    label           TEXT     NOT NULL DEFAULT '',
+    unit            TEXT     NOT NULL DEFAULT '',
+    phys_min        REAL,
+    phys_max        REAL,
+    cadence_seconds INTEGER,
+    kind            TEXT     NOT NULL DEFAULT 'gauge',
+    timezone        TEXT     NOT NULL DEFAULT '',
+    updated_at      DATETIME NOT NULL,
+    PRIMARY KEY (device, datasource)
+);
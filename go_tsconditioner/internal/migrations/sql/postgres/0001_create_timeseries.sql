@@ -0,0 +1,32 @@
+-- Séries persistées (SaveTimeSeries) et leurs observations.
+-- IF NOT EXISTS : les déploiements où ces tables ont été créées à la main
+-- sont simplement adoptés.
+CREATE TABLE IF NOT EXISTS timeseries (
+    id           BIGSERIAL PRIMARY KEY,
+    name         TEXT NOT NULL,
+    device       TEXT NOT NULL,
+    serial       TEXT,
+    datasource   TEXT NOT NULL,
+    freq_seconds BIGINT,
+    agg          TEXT,
+    method1      TEXT,
+    min1         DOUBLE PRECISION,
+    max1         DOUBLE PRECISION,
+    percent1     DOUBLE PRECISION,
+    lvl1         DOUBLE PRECISION,
+    method2      TEXT,
+    min2         DOUBLE PRECISION,
+    max2         DOUBLE PRECISION,
+    percent2     DOUBLE PRECISION,
+    lvl2         DOUBLE PRECISION,
+    interp       TEXT
+);
+
+CREATE TABLE IF NOT EXISTS dataunits (
+    timeseries_id BIGINT      NOT NULL REFERENCES timeseries (id) ON DELETE CASCADE,
+    chron         TIMESTAMPTZ NOT NULL,
+    meas          DOUBLE PRECISION,
+    status        SMALLINT    NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_dataunits_timeseries_chron ON dataunits (timeseries_id, chron);
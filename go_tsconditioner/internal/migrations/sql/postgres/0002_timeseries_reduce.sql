@@ -0,0 +1,2 @@
+-- Paramètre "reduce" de la PolishingRequest.
+ALTER TABLE timeseries ADD COLUMN IF NOT EXISTS reduce BOOLEAN;
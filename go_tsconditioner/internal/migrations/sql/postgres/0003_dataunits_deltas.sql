@@ -0,0 +1,3 @@
+-- Dchron (en nanosecondes) et Dmeas de chaque DataUnit ; NULL pour NaDuration / NaN.
+ALTER TABLE dataunits ADD COLUMN IF NOT EXISTS dchron_ns BIGINT;
+ALTER TABLE dataunits ADD COLUMN IF NOT EXISTS dmeas DOUBLE PRECISION;
@@ -0,0 +1,15 @@
+-- Table "Telemetry" locale, au format de la base distante : reçoit les points
+-- poussés par les passerelles (line protocol, cf. PostgresTelemetryWriter).
+CREATE TABLE IF NOT EXISTS "Telemetry" (
+    id           UUID             PRIMARY KEY,
+    time         TIMESTAMPTZ      NOT NULL,
+    device       UUID             NOT NULL,
+    value        DOUBLE PRECISION,
+    datasource   TEXT             NOT NULL,
+    state        TEXT             NOT NULL DEFAULT 'OK',
+    manufacturer TEXT             NOT NULL DEFAULT '',
+    model        TEXT             NOT NULL DEFAULT '',
+    serial       TEXT             NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_telemetry_device_datasource_time ON "Telemetry" (device, datasource, time);
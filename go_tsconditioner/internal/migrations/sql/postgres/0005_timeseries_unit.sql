@@ -0,0 +1,2 @@
+-- Unité de la série persistée (TimeSeries.Unit), '' si inconnue.
+ALTER TABLE timeseries ADD COLUMN IF NOT EXISTS unit TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,34 @@
+-- Équivalent SQLite des tables timeseries / dataunits de la base PostgreSQL
+-- locale (état après postgres/0003), pour le chemin d'écriture sans PostgreSQL.
+CREATE TABLE IF NOT EXISTS timeseries (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    name         TEXT NOT NULL,
+    device       TEXT NOT NULL,
+    serial       TEXT,
+    datasource   TEXT NOT NULL,
+    freq_seconds INTEGER,
+    agg          TEXT,
+    reduce       BOOLEAN,
+    method1      TEXT,
+    min1         REAL,
+    max1         REAL,
+    percent1     REAL,
+    lvl1         REAL,
+    method2      TEXT,
+    min2         REAL,
+    max2         REAL,
+    percent2     REAL,
+    lvl2         REAL,
+    interp       TEXT
+);
+
+CREATE TABLE IF NOT EXISTS dataunits (
+    timeseries_id INTEGER  NOT NULL REFERENCES timeseries (id) ON DELETE CASCADE,
+    chron         DATETIME NOT NULL,
+    meas          REAL,
+    status        INTEGER  NOT NULL DEFAULT 0,
+    dchron_ns     INTEGER,
+    dmeas         REAL
+);
+
+CREATE INDEX IF NOT EXISTS idx_dataunits_timeseries_chron ON dataunits (timeseries_id, chron);
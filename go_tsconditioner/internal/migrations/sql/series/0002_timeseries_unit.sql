@@ -0,0 +1,2 @@
+-- Unité de la série persistée, comme postgres/0005.
+ALTER TABLE timeseries ADD COLUMN unit TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+-- Table "Telemetry" au format de la base distante, pour tourner hors ligne
+-- (SQLiteTelemetryRepository) sur des données de fixture.
+CREATE TABLE IF NOT EXISTS "Telemetry" (
+    id           TEXT,
+    time         TIMESTAMP NOT NULL,
+    device       TEXT      NOT NULL,
+    value        REAL,
+    datasource   TEXT      NOT NULL,
+    state        TEXT      NOT NULL DEFAULT 'OK',
+    manufacturer TEXT      NOT NULL DEFAULT '',
+    model        TEXT      NOT NULL DEFAULT '',
+    serial       TEXT      NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_telemetry_device_datasource_time ON "Telemetry" (device, datasource, time);
+CREATE INDEX IF NOT EXISTS idx_telemetry_time ON "Telemetry" (time);
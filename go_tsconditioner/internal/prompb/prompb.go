@@ -0,0 +1,388 @@
+// Package prompb encode et décode les messages du remote read / remote write
+// Prometheus (prompb/types.proto et prompb/remote.proto) utilisés ici :
+// WriteRequest, ReadRequest et ReadResponse (réponse SAMPLES). Les champs non
+// gérés (exemplars, histogrammes, metadata, hints) sont ignorés au décodage.
+// Sur le fil, les corps sont compressés en snappy bloc (voir Decode / Encode).
+package prompb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample : Timestamp en millisecondes Unix.
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+type WriteRequest struct {
+	Timeseries []TimeSeries
+}
+
+type MatchType int32
+
+const (
+	MatchEQ MatchType = iota
+	MatchNEQ
+	MatchRE
+	MatchNRE
+)
+
+type LabelMatcher struct {
+	Type  MatchType
+	Name  string
+	Value string
+}
+
+type Query struct {
+	StartTimestampMs int64
+	EndTimestampMs   int64
+	Matchers         []LabelMatcher
+}
+
+type ResponseType int32
+
+const (
+	ResponseSamples ResponseType = iota
+	ResponseStreamedXORChunks
+)
+
+type ReadRequest struct {
+	Queries               []Query
+	AcceptedResponseTypes []ResponseType
+}
+
+type QueryResult struct {
+	Timeseries []TimeSeries
+}
+
+type ReadResponse struct {
+	Results []QueryResult
+}
+
+var errTruncated = errors.New("prompb: truncated message")
+
+// MaxDecodedBytes borne la taille décompressée d'un message : l'en-tête snappy
+// annonce jusqu'à 4 Gio, que snappy.Decode allouerait d'un coup.
+const MaxDecodedBytes = 32 << 20
+
+// ErrTooLarge : l'en-tête snappy annonce plus de MaxDecodedBytes.
+var ErrTooLarge = errors.New("prompb: decoded message too large")
+
+// Decode décompresse un corps snappy puis décode le message.
+func Decode(body []byte, m interface{ Unmarshal([]byte) error }) error {
+	n, err := snappy.DecodedLen(body)
+	if err != nil {
+		return fmt.Errorf("prompb: snappy: %w", err)
+	}
+	if n > MaxDecodedBytes {
+		return fmt.Errorf("%w: %d bytes", ErrTooLarge, n)
+	}
+	raw, err := snappy.Decode(nil, body)
+	if err != nil {
+		return fmt.Errorf("prompb: snappy: %w", err)
+	}
+	return m.Unmarshal(raw)
+}
+
+// Encode encode le message puis le compresse en snappy.
+func Encode(m interface{ Marshal() []byte }) []byte {
+	return snappy.Encode(nil, m.Marshal())
+}
+
+// ---------- encodage ----------
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendInt64(b []byte, num protowire.Number, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func (l Label) Marshal() []byte {
+	b := appendString(nil, 1, l.Name)
+	return appendString(b, 2, l.Value)
+}
+
+func (s Sample) Marshal() []byte {
+	var b []byte
+	if s.Value != 0 || math.Signbit(s.Value) {
+		b = protowire.AppendTag(b, 1, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(s.Value))
+	}
+	return appendInt64(b, 2, s.Timestamp)
+}
+
+func (ts TimeSeries) Marshal() []byte {
+	var b []byte
+	for _, l := range ts.Labels {
+		b = appendMessage(b, 1, l.Marshal())
+	}
+	for _, s := range ts.Samples {
+		b = appendMessage(b, 2, s.Marshal())
+	}
+	return b
+}
+
+func (r *WriteRequest) Marshal() []byte {
+	var b []byte
+	for _, ts := range r.Timeseries {
+		b = appendMessage(b, 1, ts.Marshal())
+	}
+	return b
+}
+
+func (m LabelMatcher) Marshal() []byte {
+	b := appendInt64(nil, 1, int64(m.Type))
+	b = appendString(b, 2, m.Name)
+	return appendString(b, 3, m.Value)
+}
+
+func (q Query) Marshal() []byte {
+	b := appendInt64(nil, 1, q.StartTimestampMs)
+	b = appendInt64(b, 2, q.EndTimestampMs)
+	for _, m := range q.Matchers {
+		b = appendMessage(b, 3, m.Marshal())
+	}
+	return b
+}
+
+func (r *ReadRequest) Marshal() []byte {
+	var b []byte
+	for _, q := range r.Queries {
+		b = appendMessage(b, 1, q.Marshal())
+	}
+	if len(r.AcceptedResponseTypes) > 0 {
+		var packed []byte
+		for _, t := range r.AcceptedResponseTypes {
+			packed = protowire.AppendVarint(packed, uint64(t))
+		}
+		b = appendMessage(b, 2, packed)
+	}
+	return b
+}
+
+func (r QueryResult) Marshal() []byte {
+	var b []byte
+	for _, ts := range r.Timeseries {
+		b = appendMessage(b, 1, ts.Marshal())
+	}
+	return b
+}
+
+func (r *ReadResponse) Marshal() []byte {
+	var b []byte
+	for _, res := range r.Results {
+		b = appendMessage(b, 1, res.Marshal())
+	}
+	return b
+}
+
+// ---------- décodage ----------
+
+// fields parcourt les champs de b ; fn reçoit le numéro, le type et la valeur
+// brute (varint / fixed64 déjà décodés dans v, octets dans data).
+func fields(b []byte, fn func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errTruncated
+		}
+		b = b[n:]
+		var v uint64
+		var data []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			data, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return errTruncated
+		}
+		b = b[n:]
+		if err := fn(num, typ, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Label) Unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, _ uint64, data []byte) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			l.Name = string(data)
+		case num == 2 && typ == protowire.BytesType:
+			l.Value = string(data)
+		}
+		return nil
+	})
+}
+
+func (s *Sample) Unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, v uint64, _ []byte) error {
+		switch {
+		case num == 1 && typ == protowire.Fixed64Type:
+			s.Value = math.Float64frombits(v)
+		case num == 2 && typ == protowire.VarintType:
+			s.Timestamp = int64(v)
+		}
+		return nil
+	})
+}
+
+func (ts *TimeSeries) Unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, _ uint64, data []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			var l Label
+			if err := l.Unmarshal(data); err != nil {
+				return err
+			}
+			ts.Labels = append(ts.Labels, l)
+		case 2:
+			var s Sample
+			if err := s.Unmarshal(data); err != nil {
+				return err
+			}
+			ts.Samples = append(ts.Samples, s)
+		}
+		return nil
+	})
+}
+
+func (r *WriteRequest) Unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, _ uint64, data []byte) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		var ts TimeSeries
+		if err := ts.Unmarshal(data); err != nil {
+			return err
+		}
+		r.Timeseries = append(r.Timeseries, ts)
+		return nil
+	})
+}
+
+func (m *LabelMatcher) Unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			m.Type = MatchType(v)
+		case num == 2 && typ == protowire.BytesType:
+			m.Name = string(data)
+		case num == 3 && typ == protowire.BytesType:
+			m.Value = string(data)
+		}
+		return nil
+	})
+}
+
+func (q *Query) Unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			q.StartTimestampMs = int64(v)
+		case num == 2 && typ == protowire.VarintType:
+			q.EndTimestampMs = int64(v)
+		case num == 3 && typ == protowire.BytesType:
+			var m LabelMatcher
+			if err := m.Unmarshal(data); err != nil {
+				return err
+			}
+			q.Matchers = append(q.Matchers, m)
+		}
+		return nil
+	})
+}
+
+func (r *ReadRequest) Unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var q Query
+			if err := q.Unmarshal(data); err != nil {
+				return err
+			}
+			r.Queries = append(r.Queries, q)
+		case num == 2 && typ == protowire.VarintType:
+			r.AcceptedResponseTypes = append(r.AcceptedResponseTypes, ResponseType(v))
+		case num == 2 && typ == protowire.BytesType: // packed
+			for len(data) > 0 {
+				t, n := protowire.ConsumeVarint(data)
+				if n < 0 {
+					return errTruncated
+				}
+				r.AcceptedResponseTypes = append(r.AcceptedResponseTypes, ResponseType(t))
+				data = data[n:]
+			}
+		}
+		return nil
+	})
+}
+
+func (r *QueryResult) Unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, _ uint64, data []byte) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		var ts TimeSeries
+		if err := ts.Unmarshal(data); err != nil {
+			return err
+		}
+		r.Timeseries = append(r.Timeseries, ts)
+		return nil
+	})
+}
+
+func (r *ReadResponse) Unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, _ uint64, data []byte) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		var res QueryResult
+		if err := res.Unmarshal(data); err != nil {
+			return err
+		}
+		r.Results = append(r.Results, res)
+		return nil
+	})
+}
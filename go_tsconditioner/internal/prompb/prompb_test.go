@@ -0,0 +1,106 @@
+package prompb
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// Les fichiers de testdata ont été produits par le prompb de Prometheus
+// (github.com/prometheus/prometheus/prompb, v0.54.1) puis compressés en snappy.
+
+func TestDecodeRecordedWriteRequest(t *testing.T) {
+	body, err := os.ReadFile("testdata/write_request.snappy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req WriteRequest
+	if err := Decode(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Timeseries) != 2 {
+		t.Fatalf("timeseries: %+v", req.Timeseries)
+	}
+	ts := req.Timeseries[0]
+	wantLabels := []Label{{"__name__", "power"}, {"device", "6f1c2a0e-5b7d-4c3e-9a41-0d2b8e7f1a23"}, {"serial", "SN1"}}
+	if !reflect.DeepEqual(ts.Labels, wantLabels) {
+		t.Fatalf("labels: %+v", ts.Labels)
+	}
+	if !reflect.DeepEqual(ts.Samples, []Sample{{1.5, 1735689600000}, {2.5, 1735689660000}}) {
+		t.Fatalf("samples: %+v", ts.Samples)
+	}
+
+	// réencodage : même message au décodage
+	var again WriteRequest
+	if err := again.Unmarshal(req.Marshal()); err != nil || !reflect.DeepEqual(again, req) {
+		t.Fatalf("round trip: %+v %v", again, err)
+	}
+}
+
+func TestDecodeRecordedReadRequest(t *testing.T) {
+	body, err := os.ReadFile("testdata/read_request.snappy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req ReadRequest
+	if err := Decode(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Queries) != 1 || !reflect.DeepEqual(req.AcceptedResponseTypes, []ResponseType{ResponseStreamedXORChunks, ResponseSamples}) {
+		t.Fatalf("request: %+v", req)
+	}
+	q := req.Queries[0]
+	if q.StartTimestampMs != 1735689600000 || q.EndTimestampMs != 1735693200000 {
+		t.Fatalf("range: %+v", q)
+	}
+	want := []LabelMatcher{
+		{MatchEQ, "__name__", "tsc_polished"},
+		{MatchEQ, "device", "6f1c2a0e-5b7d-4c3e-9a41-0d2b8e7f1a23"},
+		{MatchRE, "datasource", "pow.*"},
+	}
+	if !reflect.DeepEqual(q.Matchers, want) {
+		t.Fatalf("matchers: %+v", q.Matchers)
+	}
+}
+
+func TestReadResponseRoundTrip(t *testing.T) {
+	resp := ReadResponse{Results: []QueryResult{
+		{Timeseries: []TimeSeries{{
+			Labels:  []Label{{"__name__", "tsc_raw"}},
+			Samples: []Sample{{0, 0}, {math.Copysign(0, -1), 1}, {-3.25, -1000}},
+		}}},
+		{},
+	}}
+	var back ReadResponse
+	if err := Decode(Encode(&resp), &back); err != nil {
+		t.Fatal(err)
+	}
+	// un QueryResult vide reste présent ; -0 garde son signe
+	if len(back.Results) != 2 || len(back.Results[0].Timeseries) != 1 {
+		t.Fatalf("results: %+v", back)
+	}
+	got := back.Results[0].Timeseries[0].Samples
+	if len(got) != 3 || !math.Signbit(got[1].Value) || got[2] != (Sample{-3.25, -1000}) {
+		t.Fatalf("samples: %+v", got)
+	}
+
+	if err := Decode([]byte("not snappy"), &back); err == nil {
+		t.Fatal("expected snappy error")
+	}
+	if err := back.Unmarshal([]byte{0x0a, 0x05, 0x01}); err == nil {
+		t.Fatal("expected truncation error")
+	}
+}
+
+// Quelques octets dont l'en-tête annonce 4 Gio ne doivent rien allouer.
+func TestDecodeRejectsOversizedHeader(t *testing.T) {
+	body := binary.AppendUvarint(nil, 1<<32-1)
+	body = append(body, 0x00, 'x')
+	var req WriteRequest
+	if err := Decode(body, &req); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("want ErrTooLarge, got %v", err)
+	}
+}
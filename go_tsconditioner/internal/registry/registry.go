@@ -0,0 +1,179 @@
+// Package registry tient en mémoire la liste des devices et de leurs datasources,
+// persistée dans alldatasources.sqlite et rafraîchie périodiquement depuis Telemetry.
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/types"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrEmptyCache signale un cache SQLite sans aucun device : sur un déploiement
+// neuf, les migrations créent la table datasources vide avant le premier
+// rafraîchissement.
+var ErrEmptyCache = errors.New("devices cache is empty")
+
+// Diff décrit les devices apparus ou disparus lors d'un rafraîchissement.
+type Diff struct {
+	At      time.Time      `json:"at"`
+	Added   []types.Device `json:"added"`
+	Removed []types.Device `json:"removed"`
+	Count   int            `json:"count"` // nombre de devices après rafraîchissement
+}
+
+// Empty indique qu'aucun device n'a été ajouté ni retiré.
+func (d Diff) Empty() bool { return len(d.Added) == 0 && len(d.Removed) == 0 }
+
+// Registry partage la liste des devices entre les handlers. La liste n'est jamais
+// modifiée en place : un rafraîchissement construit une nouvelle tranche et
+// l'échange sous verrou, les lecteurs gardent donc un instantané cohérent.
+type Registry struct {
+	repo       dboperations.TelemetryRepository
+	sqlitePath string
+	interval   time.Duration
+
+	mu        sync.RWMutex
+	devices   []types.Device
+	lastDiff  *Diff
+	listeners []func(Diff)
+
+	refreshMu sync.Mutex // un seul rafraîchissement à la fois
+}
+
+func New(repo dboperations.TelemetryRepository, sqlitePath string, interval time.Duration) *Registry {
+	return &Registry{repo: repo, sqlitePath: sqlitePath, interval: interval}
+}
+
+// SQLitePath renvoie le fichier cache utilisé.
+func (r *Registry) SQLitePath() string { return r.sqlitePath }
+
+// Devices renvoie l'instantané courant (à ne pas modifier).
+func (r *Registry) Devices() []types.Device {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.devices
+}
+
+// Find retrouve un device dans l'instantané courant.
+func (r *Registry) Find(id uuid.UUID) (types.Device, bool) {
+	dev, ok := dboperations.FindDevice(r.Devices(), id)
+	if !ok {
+		return types.Device{}, false
+	}
+	return *dev, true
+}
+
+// LastDiff renvoie le dernier diff non vide (nil si aucun).
+func (r *Registry) LastDiff() *Diff {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lastDiff
+}
+
+// OnChange enregistre fn, appelée après chaque rafraîchissement qui ajoute ou retire des devices.
+func (r *Registry) OnChange(fn func(Diff)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, fn)
+}
+
+// LoadLocal charge le cache SQLite sans interroger Telemetry (démarrage).
+// Un cache vide renvoie ErrEmptyCache, comme un cache illisible : l'appelant
+// doit alors passer par Refresh.
+func (r *Registry) LoadLocal(ctx context.Context) error {
+	devices, err := dboperations.LoadDevicesWithDataSourcesLocal(ctx, r.sqlitePath)
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return fmt.Errorf("%s: %w", r.sqlitePath, ErrEmptyCache)
+	}
+	r.mu.Lock()
+	r.devices = devices
+	r.mu.Unlock()
+	return nil
+}
+
+// Refresh relit Telemetry, réécrit le cache SQLite, échange la liste en mémoire
+// et notifie les abonnés si des devices sont apparus ou ont disparu.
+func (r *Registry) Refresh(ctx context.Context) (Diff, error) {
+	r.refreshMu.Lock()
+	defer r.refreshMu.Unlock()
+
+	devices, err := dboperations.RefreshDevicesCache(ctx, r.repo, r.sqlitePath)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	r.mu.Lock()
+	diff := Compare(r.devices, devices)
+	diff.At = time.Now().UTC()
+	r.devices = devices
+	if !diff.Empty() {
+		d := diff
+		r.lastDiff = &d
+	}
+	listeners := append([]func(Diff){}, r.listeners...)
+	r.mu.Unlock()
+
+	if !diff.Empty() {
+		log.Printf("🔄 Devices : +%d / -%d (total %d)", len(diff.Added), len(diff.Removed), diff.Count)
+		for _, fn := range listeners {
+			fn(diff)
+		}
+	}
+	return diff, nil
+}
+
+// Run rafraîchit toutes les `interval` jusqu'à l'annulation de ctx.
+// interval <= 0 : pas de rafraîchissement automatique.
+func (r *Registry) Run(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	log.Printf("🔄 Rafraîchissement des devices toutes les %s -> %s", r.interval, r.sqlitePath)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Refresh(ctx); err != nil {
+				log.Printf("❌ Rafraîchissement des devices: %v", err)
+			}
+		}
+	}
+}
+
+// Compare renvoie les devices présents dans next et absents de prev (Added),
+// et inversement (Removed), par DeviceID.
+func Compare(prev, next []types.Device) Diff {
+	seen := make(map[uuid.UUID]struct{}, len(prev))
+	for _, d := range prev {
+		seen[d.DeviceID] = struct{}{}
+	}
+	kept := make(map[uuid.UUID]struct{}, len(next))
+
+	diff := Diff{Added: []types.Device{}, Removed: []types.Device{}, Count: len(next)}
+	for _, d := range next {
+		kept[d.DeviceID] = struct{}{}
+		if _, ok := seen[d.DeviceID]; !ok {
+			diff.Added = append(diff.Added, d)
+		}
+	}
+	for _, d := range prev {
+		if _, ok := kept[d.DeviceID]; !ok {
+			diff.Removed = append(diff.Removed, d)
+		}
+	}
+	return diff
+}
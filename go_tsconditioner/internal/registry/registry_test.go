@@ -0,0 +1,133 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/migrations"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestRefreshSwapsDevicesAndReportsDiff(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo, err := dboperations.OpenSQLiteTelemetry(filepath.Join(dir, "telemetry.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	insert := func(dev uuid.UUID, ds string) {
+		t.Helper()
+		err := repo.Insert(ctx, []dboperations.Telemetry{{
+			Id: uuid.New(), Time: time.Now().UTC().Add(-time.Minute), Device: dev, Value: 1, Datasource: ds, Serial: "SN",
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	dev1, dev2 := uuid.New(), uuid.New()
+	insert(dev1, "power")
+
+	reg := New(repo, filepath.Join(dir, "alldatasources.sqlite"), time.Hour)
+	var events []Diff
+	reg.OnChange(func(d Diff) { events = append(events, d) })
+
+	diff, err := reg.Refresh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 1 || len(diff.Removed) != 0 || len(reg.Devices()) != 1 {
+		t.Fatalf("first refresh: %+v", diff)
+	}
+
+	// lecteurs concurrents pendant un rafraîchissement
+	insert(dev2, "temp")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if n := len(reg.Devices()); n < 1 || n > 2 {
+					t.Errorf("unexpected snapshot of %d devices", n)
+					return
+				}
+			}
+		}()
+	}
+	diff, err = reg.Refresh(ctx)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].DeviceID != dev2 || diff.Count != 2 {
+		t.Fatalf("second refresh: %+v", diff)
+	}
+	if _, ok := reg.Find(dev2); !ok {
+		t.Fatalf("dev2 not found after refresh")
+	}
+
+	// sans changement : pas d'événement
+	if diff, _ := reg.Refresh(ctx); !diff.Empty() {
+		t.Fatalf("third refresh should be empty: %+v", diff)
+	}
+	if len(events) != 2 || reg.LastDiff() == nil {
+		t.Fatalf("events = %d", len(events))
+	}
+
+	// le cache SQLite est relisible au démarrage suivant
+	again := New(repo, reg.SQLitePath(), 0)
+	if err := again.LoadLocal(ctx); err != nil || len(again.Devices()) != 2 {
+		t.Fatalf("LoadLocal: %d devices (%v)", len(again.Devices()), err)
+	}
+
+	if d := Compare(again.Devices(), nil); len(d.Removed) != 2 {
+		t.Fatalf("removed = %d", len(d.Removed))
+	}
+}
+
+// Déploiement neuf : les migrations ont créé une table datasources vide,
+// LoadLocal doit le signaler pour que le démarrage passe par Refresh.
+func TestLoadLocalEmptyCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "alldatasources.sqlite")
+	db, err := sqlx.Open("sqlite", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.Up(ctx, db, migrations.Devices); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	repo, err := dboperations.OpenSQLiteTelemetry(filepath.Join(dir, "telemetry.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	err = repo.Insert(ctx, []dboperations.Telemetry{{
+		Id: uuid.New(), Time: time.Now().UTC().Add(-time.Minute), Device: uuid.New(), Value: 1, Datasource: "power", Serial: "SN",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reg := New(repo, cache, time.Hour)
+	if err := reg.LoadLocal(ctx); !errors.Is(err, ErrEmptyCache) {
+		t.Fatalf("LoadLocal on empty cache = %v, want ErrEmptyCache", err)
+	}
+	if _, err := reg.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.LoadLocal(ctx); err != nil || len(reg.Devices()) != 1 {
+		t.Fatalf("LoadLocal after refresh: %v, %d devices", err, len(reg.Devices()))
+	}
+}
@@ -0,0 +1,208 @@
+package routeshandlers
+
+import (
+	"errors"
+	"go_tsconditioner/internal/alerting"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/types"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+func ListAlertRules(alertsDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rules, err := dboperations.ListAlertRules(c.Request.Context(), alertsDB)
+		if err != nil {
+			log.Printf("❌ Erreur lecture alert_rules: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, rules)
+	}
+}
+
+func GetAlertRule(alertsDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		rule, err := dboperations.GetAlertRule(c.Request.Context(), alertsDB, id)
+		if err != nil {
+			alertErrorJSON(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rule)
+	}
+}
+
+func CreateAlertRule(alertsDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.AlertRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		rule := alertRuleFromRequest(req)
+		if err := alerting.ValidateRule(rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		id, err := dboperations.InsertAlertRule(c.Request.Context(), alertsDB, rule)
+		if err != nil {
+			log.Printf("❌ Erreur création alert_rule: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		created, err := dboperations.GetAlertRule(c.Request.Context(), alertsDB, id)
+		if err != nil {
+			alertErrorJSON(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, created)
+	}
+}
+
+func UpdateAlertRule(alertsDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		var req types.AlertRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		rule := alertRuleFromRequest(req)
+		rule.ID = id
+		if err := alerting.ValidateRule(rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := dboperations.UpdateAlertRule(c.Request.Context(), alertsDB, rule); err != nil {
+			alertErrorJSON(c, err)
+			return
+		}
+		updated, err := dboperations.GetAlertRule(c.Request.Context(), alertsDB, id)
+		if err != nil {
+			alertErrorJSON(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+func DeleteAlertRule(alertsDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		if err := dboperations.DeleteAlertRule(c.Request.Context(), alertsDB, id); err != nil {
+			alertErrorJSON(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+}
+
+// ListAlertEvents accepte ?state=open|acknowledged|resolved et ?limit=N.
+func ListAlertEvents(alertsDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := c.Query("state")
+		switch state {
+		case "", types.AlertStateOpen, types.AlertStateAcknowledged, types.AlertStateResolved:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown state: " + state})
+			return
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "500"))
+		if err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+
+		events, err := dboperations.ListAlertEvents(c.Request.Context(), alertsDB, state, limit)
+		if err != nil {
+			log.Printf("❌ Erreur lecture alert_events: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, events)
+	}
+}
+
+func AcknowledgeAlertEvent(alertsDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		if err := dboperations.AcknowledgeAlertEvent(c.Request.Context(), alertsDB, id, time.Now().UTC()); err != nil {
+			alertErrorJSON(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+}
+
+func ResolveAlertEvent(alertsDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		if err := dboperations.ResolveAlertEvent(c.Request.Context(), alertsDB, id, time.Now().UTC()); err != nil {
+			alertErrorJSON(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+}
+
+func alertRuleFromRequest(req types.AlertRuleRequest) types.AlertRule {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return types.AlertRule{
+		Name:            req.Name,
+		Device:          req.Device,
+		DataSource:      req.DataSource,
+		Kind:            req.Kind,
+		Operator:        req.Operator,
+		Threshold:       req.Threshold,
+		DurationSeconds: req.DurationSeconds,
+		Enabled:         enabled,
+	}
+}
+
+// idParam lit le paramètre :id ; en cas d'erreur la réponse 400 est déjà envoyée.
+func idParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func alertErrorJSON(c *gin.Context, err error) {
+	if abortedQueryJSON(c, err) {
+		return
+	}
+	if errors.Is(err, dboperations.ErrAlertNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("❌ Erreur alertes: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -56,5 +56,5 @@ func BulkSimulator(c *gin.Context) {
 	ts.MemId = store.NewMemId()
 	ts.Sort_Deltas_Stats()
 	store.GlobalTsStore.Save(&ts)
-	c.JSON(http.StatusOK, ts.ToJSON())
+	seriesResponse(c, ts.ToJSON())
 }
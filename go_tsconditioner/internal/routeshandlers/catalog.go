@@ -0,0 +1,174 @@
+package routeshandlers
+
+import (
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/types"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+func ListCatalog(catalogDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entries, err := dboperations.ListCatalog(c.Request.Context(), catalogDB, c.Query("device"))
+		if err != nil {
+			catalogErrorJSON(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, entries)
+	}
+}
+
+func GetCatalogEntry(catalogDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m, err := dboperations.GetCatalogEntry(c.Request.Context(), catalogDB, c.Param("device"), c.Param("datasource"))
+		if err != nil {
+			catalogErrorJSON(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, m)
+	}
+}
+
+// PutCatalogEntry crée ou remplace la fiche de /catalog/:device/:datasource.
+func PutCatalogEntry(catalogDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.CatalogEntryRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		m := types.DataSourceMeta{
+			Device:         c.Param("device"),
+			DataSource:     c.Param("datasource"),
+			Label:          req.Label,
+			Unit:           req.Unit,
+			PhysMin:        req.PhysMin,
+			PhysMax:        req.PhysMax,
+			CadenceSeconds: req.CadenceSeconds,
+			Kind:           req.Kind,
+			Timezone:       req.Timezone,
+		}
+		if m.Kind == "" {
+			m.Kind = types.DataSourceKindGauge
+		}
+		if err := validateCatalogEntry(m); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := dboperations.UpsertCatalogEntry(c.Request.Context(), catalogDB, m); err != nil {
+			catalogErrorJSON(c, err)
+			return
+		}
+		saved, err := dboperations.GetCatalogEntry(c.Request.Context(), catalogDB, m.Device, m.DataSource)
+		if err != nil {
+			catalogErrorJSON(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, saved)
+	}
+}
+
+func DeleteCatalogEntry(catalogDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := dboperations.DeleteCatalogEntry(c.Request.Context(), catalogDB, c.Param("device"), c.Param("datasource")); err != nil {
+			catalogErrorJSON(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// PolishingDefaults renvoie une PolishingRequest pré-remplie depuis le catalogue
+// (memId à 0 : le front le complète avec la série chargée).
+func PolishingDefaults(catalogDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m, err := dboperations.GetCatalogEntry(c.Request.Context(), catalogDB, c.Param("device"), c.Param("datasource"))
+		if err != nil {
+			catalogErrorJSON(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, polishingDefaults(m))
+	}
+}
+
+// polishingDefaults : bornes physiques pour fixedOutbounds, cadence comme pas
+// de régularisation, agrégation adaptée aux compteurs.
+func polishingDefaults(m types.DataSourceMeta) types.PolishingRequest {
+	req := types.PolishingRequest{
+		Agg:    "average",
+		Interp: "None",
+	}
+	if m.Kind == types.DataSourceKindCounter {
+		req.Agg = "incrementalCounter"
+	}
+	if m.CadenceSeconds != nil {
+		req.FreqSeconds = *m.CadenceSeconds
+	}
+	if m.PhysMin != nil && m.PhysMax != nil {
+		req.Method1 = "fixedOutbounds"
+		req.Min1, req.Max1 = *m.PhysMin, *m.PhysMax
+	}
+	return req
+}
+
+// applyCatalogBounds complète un fixedOutbounds laissé sans bornes (min = max = 0)
+// avec la plage physique du capteur.
+func applyCatalogBounds(req *types.PolishingRequest, m types.DataSourceMeta) {
+	if m.PhysMin == nil || m.PhysMax == nil {
+		return
+	}
+	if req.Method1 == "fixedOutbounds" && req.Min1 == 0 && req.Max1 == 0 {
+		req.Min1, req.Max1 = *m.PhysMin, *m.PhysMax
+	}
+	if req.Method2 == "fixedOutbounds" && req.Min2 == 0 && req.Max2 == 0 {
+		req.Min2, req.Max2 = *m.PhysMin, *m.PhysMax
+	}
+}
+
+func validateCatalogEntry(m types.DataSourceMeta) error {
+	if m.Device == "" || m.DataSource == "" {
+		return errors.New("device and datasource are required")
+	}
+	if m.Kind != types.DataSourceKindGauge && m.Kind != types.DataSourceKindCounter {
+		return fmt.Errorf("kind must be %q or %q, got %q", types.DataSourceKindGauge, types.DataSourceKindCounter, m.Kind)
+	}
+	// l'unité est recopiée sur les séries (today, remotedata, degreedays) :
+	// une unité inconnue ferait échouer ConvertTo et TsContainer.Add
+	if m.Unit != "" {
+		if _, ok := timeseries.LookupUnit(m.Unit); !ok {
+			return fmt.Errorf("unknown unit %q", m.Unit)
+		}
+	}
+	if m.PhysMin != nil && m.PhysMax != nil && *m.PhysMin > *m.PhysMax {
+		return fmt.Errorf("physMin (%g) must be <= physMax (%g)", *m.PhysMin, *m.PhysMax)
+	}
+	if m.CadenceSeconds != nil && *m.CadenceSeconds <= 0 {
+		return fmt.Errorf("cadenceSeconds must be > 0, got %d", *m.CadenceSeconds)
+	}
+	if m.Timezone != "" {
+		if _, err := time.LoadLocation(m.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", m.Timezone)
+		}
+	}
+	return nil
+}
+
+func catalogErrorJSON(c *gin.Context, err error) {
+	if abortedQueryJSON(c, err) {
+		return
+	}
+	if errors.Is(err, dboperations.ErrCatalogEntryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("❌ Erreur catalogue: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
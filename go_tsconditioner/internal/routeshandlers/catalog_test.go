@@ -0,0 +1,42 @@
+package routeshandlers
+
+import (
+	"go_tsconditioner/internal/dboperations"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPutCatalogEntryUnit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	catalog, err := dboperations.OpenCatalogDB(filepath.Join(t.TempDir(), "alldatasources.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer catalog.Close()
+
+	router := gin.New()
+	router.PUT("/catalog/:device/:datasource", PutCatalogEntry(catalog))
+
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/catalog/dev/power", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := put(`{"unit":"kWatt"}`); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "unknown unit") {
+		t.Fatalf("unknown unit: %d %s", w.Code, w.Body)
+	}
+	if w := put(`{"unit":"kW"}`); w.Code != http.StatusOK {
+		t.Fatalf("known unit: %d %s", w.Code, w.Body)
+	}
+	if w := put(`{"label":"sans unité"}`); w.Code != http.StatusOK {
+		t.Fatalf("empty unit: %d %s", w.Code, w.Body)
+	}
+}
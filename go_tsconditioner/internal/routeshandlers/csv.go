@@ -0,0 +1,117 @@
+package routeshandlers
+
+import (
+	"fmt"
+	"go_tsconditioner/internal/timeseries"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportCSV charge un fichier CSV (champ multipart "file") dans le TsStore.
+// Champs optionnels : delimiter, timeFormat, timezone, decimalComma, nan,
+// name, unit, et wide=true pour un conteneur (une colonne par série).
+func ImportCSV(c *gin.Context) {
+	opts, err := csvOptionsFrom(c.PostForm)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	unit := c.PostForm("unit")
+	if unit != "" {
+		if _, ok := timeseries.LookupUnit(unit); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown unit %q", unit)})
+			return
+		}
+	}
+	wide, _ := strconv.ParseBool(c.PostForm("wide"))
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "champ multipart 'file' manquant"})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	name := c.PostForm("name")
+	if name == "" {
+		name = strings.TrimSuffix(fh.Filename, filepath.Ext(fh.Filename))
+	}
+
+	if wide {
+		tsc, err := timeseries.ReadContainerCSV(f, opts)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tsc.Name, tsc.Comment = name, "CSV import "+fh.Filename
+		for _, ts := range tsc.Ts {
+			ts.Unit = unit
+			storeImported(ts)
+		}
+		containerResponse(c, tsc.ToJSON())
+		return
+	}
+
+	ts, err := timeseries.ReadCSV(f, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ts.Name, ts.Unit = name, unit
+	storeImported(&ts)
+	seriesResponse(c, ts.ToJSON())
+}
+
+// csvOptionsFrom lit les options CSV depuis un formulaire ou une query string.
+func csvOptionsFrom(get func(string) string) (timeseries.CSVOptions, error) {
+	var opts timeseries.CSVOptions
+	switch d := get("delimiter"); d {
+	case "":
+	case "tab", `\t`:
+		opts.Delimiter = '\t'
+	default:
+		r, size := utf8.DecodeRuneInString(d)
+		if size != len(d) {
+			return opts, fmt.Errorf("delimiter must be a single character, got %q", d)
+		}
+		opts.Delimiter = r
+	}
+	opts.TimeFormat = get("timeFormat")
+	if tz := get("timezone"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return opts, fmt.Errorf("unknown timezone %q", tz)
+		}
+		opts.Location = loc
+	}
+	opts.NaN = get("nan")
+	for _, b := range []struct {
+		key string
+		dst *bool
+	}{
+		{"decimalComma", &opts.DecimalComma},
+		{"status", &opts.Status},
+		{"dchron", &opts.Dchron},
+		{"dmeas", &opts.Dmeas},
+	} {
+		if v := get(b.key); v != "" {
+			parsed, err := strconv.ParseBool(v)
+			if err != nil {
+				return opts, fmt.Errorf("%s: %w", b.key, err)
+			}
+			*b.dst = parsed
+		}
+	}
+	return opts, nil
+}
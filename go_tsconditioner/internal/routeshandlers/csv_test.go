@@ -0,0 +1,61 @@
+package routeshandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestImportExportCSV(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/import/csv", ImportCSV)
+	router.GET("/export/:file", Export)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("delimiter", ";")
+	_ = mw.WriteField("decimalComma", "true")
+	_ = mw.WriteField("unit", "kW")
+	fw, _ := mw.CreateFormFile("file", "compteur.csv")
+	fmt.Fprint(fw, "time;meas\n2025-01-01T00:00:00Z;1,5\n2025-01-01T00:10:00Z;2,25\n")
+	mw.Close()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/import/csv", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("import: %d %s", w.Code, w.Body)
+	}
+	var imported struct {
+		Id   uint64 `json:"id"`
+		Name string `json:"name"`
+		Unit string `json:"unit"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &imported); err != nil || imported.Id == 0 || imported.Name != "compteur" || imported.Unit != "kW" {
+		t.Fatalf("import: %s", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/export/%d.csv?status=true", imported.Id), nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("export: %d %v", w.Code, w.Header())
+	}
+	if want := "time,meas,status\n2025-01-01T00:00:00Z,1.5,StOK\n2025-01-01T00:10:00Z,2.25,StOK\n"; w.Body.String() != want {
+		t.Fatalf("export body:\n%s", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/export/%d.json", imported.Id), nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("non-csv export: %d", w.Code)
+	}
+}
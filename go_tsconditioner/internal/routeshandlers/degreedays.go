@@ -0,0 +1,261 @@
+package routeshandlers
+
+import (
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/types"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+// daysPerYear sert à annualiser les degrés-jours de la période de référence.
+const daysPerYear = 365.25
+
+type degreeDaysResponse struct {
+	Model            timeseries.PRISMModel       `json:"model"`
+	Unit             string                      `json:"unit,omitempty"`   // unité de la consommation journalière
+	NormalizedAnnual float64                     `json:"normalizedAnnual"` // consommation annuelle à climat normal
+	NormalHDD        float64                     `json:"normalHdd"`        // degrés-jours annuels de référence, aux bases du modèle
+	NormalCDD        float64                     `json:"normalCdd"`
+	NormalDays       int                         `json:"normalDays"` // jours de température utilisés pour la référence
+	Series           *timeseries.TsContainerJSON `json:"series"`
+}
+
+// DegreeDays calcule les degrés-jours d'une datasource de température et
+// régresse la consommation journalière dessus (modèle type PRISM). Le
+// catalogue, s'il est fourni, donne la nature de la consommation (compteur ou
+// puissance), les unités, la cadence et le fuseau horaire.
+func DegreeDays(repo dboperations.TelemetryRepository, catalogDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.DegreeDaysRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !req.To.After(req.From) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'to' doit être postérieur à 'from'"})
+			return
+		}
+		opts := timeseries.PRISMOptions{BaseHeat: req.BaseHeat, BaseCool: req.BaseCool}
+		switch req.Model {
+		case "", "heating":
+			opts.Heating = true
+		case "cooling":
+			opts.Cooling = true
+		case "both":
+			opts.Heating, opts.Cooling = true, true
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown model: %s", req.Model)})
+			return
+		}
+
+		consMeta, ok := catalogMeta(c, catalogDB, req.Device, req.Consumption)
+		if !ok {
+			return
+		}
+		tempMeta, ok := catalogMeta(c, catalogDB, req.Device, req.Temperature)
+		if !ok {
+			return
+		}
+		kind := req.ConsumptionKind
+		if kind == "" {
+			kind = "counter"
+			if consMeta.Kind == types.DataSourceKindGauge {
+				kind = "power"
+			}
+		}
+		if kind != "counter" && kind != "power" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown consumptionKind: %s", kind)})
+			return
+		}
+		tz := req.Timezone
+		if tz == "" {
+			tz = tempMeta.Timezone
+		}
+		loc := time.Local
+		if tz != "" {
+			l, err := time.LoadLocation(tz)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown timezone %q", tz)})
+				return
+			}
+			loc = l
+		}
+
+		// 1) Lectures
+		cons, ok := readRangeJSON(c, repo, req.Device, req.Consumption, req.From, req.To)
+		if !ok {
+			return
+		}
+		cons.Unit = consMeta.Unit
+		temp, ok := readRangeJSON(c, repo, req.Device, req.Temperature, req.From, req.To)
+		if !ok {
+			return
+		}
+		temp.Unit = tempMeta.Unit
+
+		// 2) Séries journalières
+		var daily timeseries.TimeSeries
+		var err error
+		if kind == "counter" {
+			daily = cons.DailyIncrements(loc)
+		} else {
+			// au-delà de 3 cadences sans mesure, l'intervalle est un trou
+			var maxGap time.Duration
+			if consMeta.CadenceSeconds != nil {
+				maxGap = 3 * time.Duration(*consMeta.CadenceSeconds) * time.Second
+			}
+			daily, err = cons.DailyEnergy(timeseries.IntegrateTrapezoid, maxGap, loc)
+		}
+		if err != nil {
+			degreeDaysErrorJSON(c, err)
+			return
+		}
+		tmeans, err := temp.DailyMeanTemperature(loc)
+		if err != nil {
+			degreeDaysErrorJSON(c, err)
+			return
+		}
+
+		// 3) Régression
+		model, err := timeseries.FitPRISM(&daily, &tmeans, opts)
+		if err != nil {
+			degreeDaysErrorJSON(c, err)
+			return
+		}
+
+		// 4) Normalisation sur la période de référence
+		normTemps := &tmeans
+		if req.NormalFrom != nil && req.NormalTo != nil {
+			nt, ok := readRangeJSON(c, repo, req.Device, req.Temperature, *req.NormalFrom, *req.NormalTo)
+			if !ok {
+				return
+			}
+			nt.Unit = tempMeta.Unit
+			means, err := nt.DailyMeanTemperature(loc)
+			if err != nil {
+				degreeDaysErrorJSON(c, err)
+				return
+			}
+			normTemps = &means
+		}
+		resp := degreeDaysResponse{Model: model, Unit: daily.Unit}
+		var hdd, cdd float64
+		for _, du := range normTemps.DataSeries {
+			if du.Status != timeseries.StOK {
+				continue
+			}
+			resp.NormalDays++
+			if model.Heating {
+				hdd += max(0, model.BaseHeat-du.Meas)
+			}
+			if model.Cooling {
+				cdd += max(0, du.Meas-model.BaseCool)
+			}
+		}
+		if resp.NormalDays > 0 {
+			resp.NormalHDD = hdd * daysPerYear / float64(resp.NormalDays)
+			resp.NormalCDD = cdd * daysPerYear / float64(resp.NormalDays)
+			resp.NormalizedAnnual = model.Normalized(daysPerYear, resp.NormalHDD, resp.NormalCDD)
+		}
+
+		// 5) Séries renvoyées
+		tsc := timeseries.TsContainer{
+			Name:    fmt.Sprintf("Degree-days %s / %s", req.Consumption, req.Temperature),
+			Comment: fmt.Sprintf("PRISM R²=%.3f over %d days", model.R2, model.Days),
+			Ts:      make(map[string]*timeseries.TimeSeries),
+		}
+		predicted := timeseries.TimeSeries{Name: "Predicted", Unit: daily.Unit}
+		for _, du := range tmeans.DataSeries {
+			p := timeseries.DataUnit{Chron: du.Chron, Meas: du.Meas, Status: du.Status}
+			if du.Status == timeseries.StOK {
+				p.Meas = model.PredictFromTemperature(du.Meas)
+			}
+			predicted.AddDataUnit(p)
+		}
+		series := map[string]*timeseries.TimeSeries{
+			"Consumption": &daily,
+			"Temperature": &tmeans,
+			"Predicted":   &predicted,
+		}
+		if model.Heating {
+			hddTs, _ := timeseries.DegreeDays(temp, timeseries.HeatingDegreeDays, model.BaseHeat, loc)
+			series["HDD"] = &hddTs
+		}
+		if model.Cooling {
+			cddTs, _ := timeseries.DegreeDays(temp, timeseries.CoolingDegreeDays, model.BaseCool, loc)
+			series["CDD"] = &cddTs
+		}
+		// container sans Unit : unités mélangées (énergie, température, degrés-jours)
+		for key, ts := range series {
+			if err := tsc.Add(key, ts); err != nil {
+				degreeDaysErrorJSON(c, err)
+				return
+			}
+		}
+		for _, ts := range tsc.Ts {
+			ts.Sort_Deltas_Stats()
+		}
+		resp.Series = tsc.ToJSON()
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// catalogMeta renvoie la fiche catalogue si elle existe (fiche vide sinon, ou
+// sans catalogue). false : la réponse d'erreur est déjà écrite.
+func catalogMeta(c *gin.Context, catalogDB *sqlx.DB, device, datasource string) (types.DataSourceMeta, bool) {
+	if catalogDB == nil {
+		return types.DataSourceMeta{}, true
+	}
+	m, err := dboperations.GetCatalogEntry(c.Request.Context(), catalogDB, device, datasource)
+	if errors.Is(err, dboperations.ErrCatalogEntryNotFound) {
+		return types.DataSourceMeta{}, true
+	}
+	if err != nil {
+		catalogErrorJSON(c, err)
+		return m, false
+	}
+	return m, true
+}
+
+// readRangeJSON lit [from, to] et écrit la réponse d'erreur adaptée en cas
+// d'échec ou de plage vide.
+func readRangeJSON(c *gin.Context, repo dboperations.TelemetryRepository, device, datasource string, from, to time.Time) (*timeseries.TimeSeries, bool) {
+	ts, err := repo.ReadRange(c.Request.Context(), dboperations.TelemetryQuery{
+		Device: device, DataSource: datasource, From: from, To: to,
+	})
+	if errors.Is(err, dboperations.ErrTelemetryTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if abortedQueryJSON(c, err) {
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("❌ Erreur SELECT in telemetry (%s/%s): %v", device, datasource, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la lecture de Telemetry"})
+		return nil, false
+	}
+	if len(ts.DataSeries) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Aucune donnée Telemetry pour %s/%s", device, datasource)})
+		return nil, false
+	}
+	return ts, true
+}
+
+func degreeDaysErrorJSON(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, timeseries.ErrTooFewPoints):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, timeseries.ErrIncompatibleUnits), errors.Is(err, timeseries.ErrUnknownUnit), errors.Is(err, timeseries.ErrBounds):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,90 @@
+package routeshandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/types"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestDegreeDaysEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	repo, err := dboperations.OpenSQLiteTelemetry(filepath.Join(t.TempDir(), "telemetry.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	catalog, err := dboperations.OpenCatalogDB(filepath.Join(t.TempDir(), "alldatasources.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer catalog.Close()
+
+	dev := uuid.New()
+	if err := dboperations.UpsertCatalogEntry(ctx, catalog, types.DataSourceMeta{
+		Device: dev.String(), DataSource: "energy", Unit: "kWh", Kind: types.DataSourceKindCounter, Timezone: "UTC",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// consommation journalière = 3 + 1.5 * HDD(17 °C)
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var rows []dboperations.Telemetry
+	add := func(at time.Time, ds string, v float64) {
+		rows = append(rows, dboperations.Telemetry{Id: uuid.New(), Time: at, Device: dev, Value: v, Datasource: ds, Serial: "SN1"})
+	}
+	total := 0.0
+	add(t0.Add(-time.Hour), "energy", total)
+	for d := 0; d < 20; d++ {
+		tm := float64(d) - 2
+		day := t0.AddDate(0, 0, d)
+		add(day.Add(12*time.Hour), "temp", tm)
+		total += 3 + 1.5*math.Max(0, 17-tm)
+		add(day.Add(23*time.Hour), "energy", total)
+	}
+	if err := repo.Insert(ctx, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.POST("/degreedays", DegreeDays(repo, catalog))
+	body, _ := json.Marshal(types.DegreeDaysRequest{
+		Device: dev.String(), Consumption: "energy", Temperature: "temp",
+		From: t0.Add(-2 * time.Hour), To: t0.AddDate(0, 0, 21),
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/degreedays", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("%d %s", w.Code, w.Body)
+	}
+	var resp struct {
+		degreeDaysResponse
+		Series struct {
+			Series map[string]json.RawMessage `json:"series"`
+		} `json:"series"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	m := resp.Model
+	if m.BaseHeat != 17 || math.Abs(m.HeatSlope-1.5) > 1e-6 || math.Abs(m.Intercept-3) > 1e-6 {
+		t.Fatalf("model: %+v", m)
+	}
+	if resp.Unit != "kWh" || resp.NormalDays != 20 || resp.NormalizedAnnual <= 0 {
+		t.Fatalf("normalization: %+v", resp)
+	}
+	if _, ok := resp.Series.Series["HDD"]; !ok {
+		t.Fatalf("series: %v", resp.Series.Series)
+	}
+}
@@ -0,0 +1,75 @@
+package routeshandlers
+
+import (
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/store"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/types"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnergyTransform intègre une série de puissance en énergie cumulée, ou dérive
+// un compteur d'énergie en puissance moyenne par intervalle. Le résultat est
+// rangé dans le store (nouveau memId) et renvoyé.
+func EnergyTransform(c *gin.Context) {
+	var req types.EnergyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ts, ok := store.GlobalTsStore.Get(req.MemId)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "time series not found"})
+		return
+	}
+	// copie triée : la série du store n'est pas modifiée
+	working := ts.Copy()
+	working.SortChronAsc()
+	maxGap := time.Duration(req.MaxGapSeconds) * time.Second
+
+	var out timeseries.TimeSeries
+	var err error
+	switch req.Op {
+	case "integrate":
+		method, merr := getIntegrationMethod(req.Method)
+		if merr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": merr.Error()})
+			return
+		}
+		out, err = working.Integrate(method, maxGap)
+	case "differentiate":
+		out, err = working.Differentiate(maxGap)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown op: %s", req.Op)})
+		return
+	}
+	if errors.Is(err, timeseries.ErrIncompatibleUnits) || errors.Is(err, timeseries.ErrUnknownUnit) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out.ComputeBasicStats()
+	out.MemId = store.NewMemId()
+	store.GlobalTsStore.Save(&out)
+	seriesResponse(c, out.ToJSON())
+}
+
+// getIntegrationMethod mappe le string venant du front vers l'IntegrationMethod
+func getIntegrationMethod(name string) (timeseries.IntegrationMethod, error) {
+	switch name {
+	case "", "trapezoid", "Trapezoid":
+		return timeseries.IntegrateTrapezoid, nil
+	case "step", "Step":
+		return timeseries.IntegrateStep, nil
+	default:
+		return timeseries.IntegrateTrapezoid, fmt.Errorf("unknown integration method: %s", name)
+	}
+}
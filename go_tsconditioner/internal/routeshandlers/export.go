@@ -0,0 +1,116 @@
+package routeshandlers
+
+import (
+	"bytes"
+	"fmt"
+	"go_tsconditioner/internal/columnar"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/store"
+	"go_tsconditioner/internal/timeseries"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+// formats d'export reconnus, par extension
+var exportContentTypes = map[string]string{
+	"csv":     "text/csv; charset=utf-8",
+	"parquet": "application/vnd.apache.parquet",
+	"arrow":   "application/vnd.apache.arrow.stream",
+}
+
+// Export renvoie la série :memId du TsStore (route /export/<memId>.<format>,
+// format csv, parquet ou arrow). Voir writeExport pour les paramètres.
+func Export(c *gin.Context) {
+	file := c.Param("file")
+	ext := strings.TrimPrefix(path.Ext(file), ".")
+	id, err := strconv.ParseUint(strings.TrimSuffix(file, path.Ext(file)), 10, 64)
+	if _, known := exportContentTypes[ext]; !known || err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "expected /export/<memId>.csv|.parquet|.arrow"})
+		return
+	}
+	ts, ok := store.GlobalTsStore.Get(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "time series not found"})
+		return
+	}
+	writeExport(c, ts, ext, file)
+}
+
+// ExportSaved exporte une série persistée (route /saved/:id/export/:format),
+// sans la recharger dans le TsStore.
+func ExportSaved(localDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		format := c.Param("format")
+		if _, known := exportContentTypes[format]; !known {
+			c.JSON(http.StatusNotFound, gin.H{"error": "expected format csv, parquet or arrow"})
+			return
+		}
+		ts, _, err := dboperations.LoadSavedTimeSeries(c.Request.Context(), localDB, id)
+		if err != nil {
+			savedSeriesErrorJSON(c, err)
+			return
+		}
+		writeExport(c, ts, format, fmt.Sprintf("saved-%d.%s", id, format))
+	}
+}
+
+// writeExport écrit ts en pièce jointe :
+//   - csv : paramètres de csvOptionsFrom (delimiter, timeFormat, status...) ;
+//   - parquet, arrow : layout=long (défaut) ou wide ; la série est exportée
+//     comme un conteneur d'une seule série, nommée d'après ts.Name.
+func writeExport(c *gin.Context, ts *timeseries.TimeSeries, format, filename string) {
+	var buf bytes.Buffer
+	switch format {
+	case "csv":
+		opts, err := csvOptionsFrom(c.Query)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := ts.WriteCSV(&buf, opts); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	default:
+		layout, err := columnar.ParseLayout(c.Query("layout"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tsc := singleSeriesContainer(ts)
+		if format == "parquet" {
+			err = columnar.WriteParquet(&buf, &tsc, layout)
+		} else {
+			err = columnar.WriteArrowIPC(&buf, &tsc, layout)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, exportContentTypes[format], buf.Bytes())
+}
+
+func singleSeriesContainer(ts *timeseries.TimeSeries) timeseries.TsContainer {
+	key := ts.Name
+	if key == "" {
+		key = "value"
+	}
+	return timeseries.TsContainer{
+		Name:    ts.Name,
+		Comment: ts.Comment,
+		Unit:    ts.Unit,
+		Ts:      map[string]*timeseries.TimeSeries{key: ts},
+	}
+}
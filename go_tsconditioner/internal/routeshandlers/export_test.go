@@ -0,0 +1,48 @@
+package routeshandlers
+
+import (
+	"bytes"
+	"fmt"
+	"go_tsconditioner/internal/store"
+	"go_tsconditioner/internal/timeseries"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExportColumnarFormats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/export/:file", Export)
+
+	ts := &timeseries.TimeSeries{Name: "power", Unit: "kW"}
+	ts.AddData(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), 1.5)
+	storeImported(ts)
+
+	for _, tc := range []struct {
+		query, contentType string
+		magic              []byte
+	}{
+		{".parquet", "application/vnd.apache.parquet", []byte("PAR1")},
+		{".parquet?layout=wide", "application/vnd.apache.parquet", []byte("PAR1")},
+		{".arrow", "application/vnd.apache.arrow.stream", []byte{0xff, 0xff, 0xff, 0xff}},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/export/%d%s", ts.MemId, tc.query), nil))
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != tc.contentType || !bytes.HasPrefix(w.Body.Bytes(), tc.magic) {
+			t.Fatalf("%s: %d %v", tc.query, w.Code, w.Header())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/export/%d.parquet?layout=tall", ts.MemId), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("bad layout: %d", w.Code)
+	}
+	if _, ok := store.GlobalTsStore.Get(ts.MemId); !ok {
+		t.Fatal("series should stay in the store")
+	}
+}
@@ -0,0 +1,157 @@
+package routeshandlers
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/lineprotocol"
+	"go_tsconditioner/internal/timeseries"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxIngestBytes borne le corps d'une requête d'ingestion (avant décompression).
+const maxIngestBytes = 32 << 20
+
+// maxDecodedIngestBytes borne le line protocol une fois décompressé : un corps
+// gzip de quelques Mo peut en produire des Go (variable pour les tests).
+var maxDecodedIngestBytes int64 = 4 * maxIngestBytes
+
+// ingestSeries : une série par couple device / datasource, avec les tags
+// descriptifs du premier point.
+type ingestSeries struct {
+	tmpl dboperations.Telemetry
+	ts   *timeseries.TimeSeries
+}
+
+// WriteLineProtocol ingère du line protocol InfluxDB (route /write, comme
+// InfluxDB v1) et l'écrit dans "Telemetry" via w.
+//
+//   - device : tag "device" (ou celui nommé par ?deviceTag=), un UUID ;
+//   - datasource : <measurement>.<champ>, ou <measurement> pour le champ "value" ;
+//   - manufacturer, model, serial : tags du même nom s'ils existent ;
+//   - ?precision= : ns (défaut), us, ms, s, m, h ; sans timestamp : l'heure de réception.
+//
+// Les champs chaîne sont ignorés, les booléens valent 1 / 0. Le lot est
+// refusé entier à la première ligne invalide. Succès : 204, comme InfluxDB.
+func WriteLineProtocol(w dboperations.TelemetryWriter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		precision, err := lineprotocol.ParsePrecision(c.Query("precision"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		deviceTag := c.DefaultQuery("deviceTag", "device")
+
+		var body io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, maxIngestBytes)
+		if c.GetHeader("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(body)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			defer gz.Close()
+			body = http.MaxBytesReader(c.Writer, gz, maxDecodedIngestBytes)
+		}
+
+		points, err := lineprotocol.Parse(body, precision, time.Now().UTC())
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		series, err := lineProtocolSeries(points, deviceTag)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var rows []dboperations.Telemetry
+		for _, s := range series {
+			rows = append(rows, dboperations.TimeSeriesToTelemetryRows(s.ts, s.tmpl)...)
+		}
+		if len(rows) > 0 {
+			if err := w.Insert(c.Request.Context(), rows); err != nil {
+				if abortedQueryJSON(c, err) {
+					return
+				}
+				log.Printf("❌ Erreur écriture Telemetry (line protocol): %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'écriture de Telemetry"})
+				return
+			}
+		}
+		log.Printf("📥 Line protocol : %d points, %d lignes Telemetry, %d séries", len(points), len(rows), len(series))
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// lineProtocolSeries regroupe les champs numériques des points par device et
+// datasource (voir sortedIngestSeries pour l'ordre).
+func lineProtocolSeries(points []lineprotocol.Point, deviceTag string) ([]ingestSeries, error) {
+	byKey := make(map[string]*ingestSeries)
+	for _, p := range points {
+		tag, ok := p.Tags[deviceTag]
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing tag %q", p.Line, deviceTag)
+		}
+		device, err := uuid.Parse(tag)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: tag %s=%q is not a device UUID", p.Line, deviceTag, tag)
+		}
+		for _, f := range p.Fields {
+			if !f.Numeric() {
+				continue
+			}
+			datasource := p.Measurement
+			if f.Key != "value" {
+				datasource += "." + f.Key
+			}
+			key := device.String() + "/" + datasource
+			s, ok := byKey[key]
+			if !ok {
+				s = &ingestSeries{
+					tmpl: dboperations.Telemetry{
+						Device:       device,
+						Datasource:   datasource,
+						Manufacturer: p.Tags["manufacturer"],
+						Model:        p.Tags["model"],
+						Serial:       p.Tags["serial"],
+					},
+					ts: &timeseries.TimeSeries{Name: datasource, Comment: "line protocol, device " + device.String()},
+				}
+				byKey[key] = s
+			}
+			s.ts.AddData(p.Time, f.Value)
+		}
+	}
+
+	return sortedIngestSeries(byKey), nil
+}
+
+// sortedIngestSeries trie chaque série par temps, et les séries par device / datasource.
+func sortedIngestSeries(byKey map[string]*ingestSeries) []ingestSeries {
+	out := make([]ingestSeries, 0, len(byKey))
+	for _, s := range byKey {
+		s.ts.SortChronAsc()
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].tmpl, out[j].tmpl
+		if a.Device != b.Device {
+			return a.Device.String() < b.Device.String()
+		}
+		return a.Datasource < b.Datasource
+	})
+	return out
+}
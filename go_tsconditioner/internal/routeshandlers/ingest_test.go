@@ -0,0 +1,102 @@
+package routeshandlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"go_tsconditioner/internal/dboperations"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestWriteLineProtocol(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo, err := dboperations.OpenSQLiteTelemetry(filepath.Join(t.TempDir(), "telemetry.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	router := gin.New()
+	router.POST("/write", WriteLineProtocol(repo))
+
+	dev := uuid.New()
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	body := fmt.Sprintf("meter,device=%[1]s,serial=SN1 power=2.5,temp=20,label=\"x\" %[2]d\n"+
+		"meter,device=%[1]s,serial=SN1 power=1.5,temp=21 %[3]d\n"+
+		"pulse,device=%[1]s value=7i %[2]d\n", dev, t0.Add(time.Minute).Unix(), t0.Unix())
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(body))
+	zw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/write?precision=s", &gz)
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("/write: %d %s", w.Code, w.Body)
+	}
+
+	ds, err := repo.ListDataSources(context.Background(), dev.String())
+	if err != nil || strings.Join(ds, ",") != "meter.power,meter.temp,pulse" {
+		t.Fatalf("datasources: %v %v", ds, err)
+	}
+	ts, err := repo.ReadRange(context.Background(), dboperations.TelemetryQuery{
+		Device: dev.String(), DataSource: "meter.power", From: t0.Add(-time.Hour), To: t0.Add(time.Hour),
+	})
+	if err != nil || len(ts.DataSeries) != 2 || ts.DataSeries[0].Meas != 1.5 || !ts.DataSeries[0].Chron.Equal(t0) {
+		t.Fatalf("meter.power: %+v %v", ts, err)
+	}
+
+	// un device non-UUID fait refuser tout le lot
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/write", strings.NewReader(
+		fmt.Sprintf("cpu,device=%s value=1\ncpu,device=gateway-1 value=2\n", dev))))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "line 2") {
+		t.Fatalf("bad device: %d %s", w.Code, w.Body)
+	}
+	if ds, _ := repo.ListDataSources(context.Background(), dev.String()); len(ds) != 3 {
+		t.Fatalf("rejected batch was written: %v", ds)
+	}
+}
+
+// Un petit corps gzip qui se décompresse au-delà de la borne est refusé.
+func TestWriteLineProtocolGzipBomb(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer func(n int64) { maxDecodedIngestBytes = n }(maxDecodedIngestBytes)
+	maxDecodedIngestBytes = 64 << 10
+
+	repo, err := dboperations.OpenSQLiteTelemetry(filepath.Join(t.TempDir(), "telemetry.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	router := gin.New()
+	router.POST("/write", WriteLineProtocol(repo))
+
+	line := fmt.Sprintf("meter,device=%s power=1\n", uuid.New())
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(strings.Repeat(line, int(maxDecodedIngestBytes)/len(line)+1)))
+	zw.Close()
+	if int64(gz.Len()) >= maxDecodedIngestBytes {
+		t.Fatalf("fixture does not compress: %d bytes", gz.Len())
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/write", &gz)
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("gzip bomb: %d %s", w.Code, w.Body)
+	}
+}
@@ -3,12 +3,12 @@ package routeshandlers
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
+	"go_tsconditioner/internal/dboperations"
 	"log"
 	"net/http"
 )
 
-func ListDataSources(remoteDB *sqlx.DB) gin.HandlerFunc {
+func ListDataSources(repo dboperations.TelemetryRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// ✅ 1) Lire le body JSON du POST
 		type requestBody struct {
@@ -27,17 +27,13 @@ func ListDataSources(remoteDB *sqlx.DB) gin.HandlerFunc {
 		type dataSource struct {
 			DataSourceName string `json:"datasource" db:"datasource"`
 		}
-		var rows []dataSource
 
-		// ✅ 3) Requête SQL : toutes les datasources pour ce device
-		const q = `
-			SELECT DISTINCT datasource
-			FROM "Telemetry"
-			WHERE device = $1
-			ORDER BY datasource;
-		`
-
-		if err := remoteDB.Select(&rows, q, req.DeviceID); err != nil {
+		// ✅ 3) Toutes les datasources pour ce device
+		names, err := repo.ListDataSources(c.Request.Context(), req.DeviceID.String())
+		if abortedQueryJSON(c, err) {
+			return
+		}
+		if err != nil {
 			log.Printf("❌ Erreur SELECT in telemetry (ListDataSources): %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Erreur lors de la lecture de Telemetry (datasources)",
@@ -45,6 +41,11 @@ func ListDataSources(remoteDB *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		rows := make([]dataSource, len(names))
+		for i, n := range names {
+			rows[i] = dataSource{DataSourceName: n}
+		}
+
 		if len(rows) == 0 {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error": "Aucune datasource pour ce device",
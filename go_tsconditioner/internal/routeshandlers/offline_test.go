@@ -0,0 +1,98 @@
+package routeshandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/types"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Le serveur tourne entièrement sur une Telemetry SQLite de fixture.
+func TestTelemetryRoutesOffline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo, err := dboperations.OpenSQLiteTelemetry(filepath.Join(t.TempDir(), "telemetry.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	dev := uuid.New()
+	now := time.Now().UTC().Truncate(time.Second)
+	var rows []dboperations.Telemetry
+	for i := 100; i >= 1; i-- {
+		rows = append(rows, dboperations.Telemetry{
+			Id: uuid.New(), Time: now.Add(-time.Duration(i) * time.Second), Device: dev,
+			Value: float64(i), Datasource: "power", Serial: "SN1",
+		})
+	}
+	if err := repo.Insert(context.Background(), rows); err != nil {
+		t.Fatal(err)
+	}
+
+	catalogDB, err := dboperations.OpenCatalogDB(filepath.Join(t.TempDir(), "alldatasources.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer catalogDB.Close()
+	if err := dboperations.UpsertCatalogEntry(context.Background(), catalogDB, types.DataSourceMeta{
+		Device: dev.String(), DataSource: "power", Unit: "kW", Kind: types.DataSourceKindGauge,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.POST("/remotedata", OneDeviceOneDataSource(repo, catalogDB))
+	router.POST("/getdatasources", ListDataSources(repo))
+	router.GET("/report/latest", LastSeenPoints_json(repo, nil))
+
+	do := func(method, path string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, &buf))
+		return w
+	}
+
+	w := do(http.MethodPost, "/remotedata", types.OneDeviceOneDatasourceRequest{
+		Device: dev.String(), DataSource: "power", From: now.Add(-time.Hour), To: now, MaxPoints: 20,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("/remotedata: %d %s", w.Code, w.Body)
+	}
+	var ts struct {
+		Chron   []time.Time `json:"chron"`
+		FullLen int         `json:"fullLen"`
+		Unit    string      `json:"unit"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &ts); err != nil {
+		t.Fatal(err)
+	}
+	if len(ts.Chron) != 20 || ts.FullLen != 100 {
+		t.Fatalf("/remotedata: %d points sent, fullLen %d", len(ts.Chron), ts.FullLen)
+	}
+	if ts.Unit != "kW" {
+		t.Fatalf("/remotedata: unit %q, want the catalog unit kW", ts.Unit)
+	}
+
+	w = do(http.MethodPost, "/getdatasources", gin.H{"device_id": dev})
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"datasource":"power"`)) {
+		t.Fatalf("/getdatasources: %d %s", w.Code, w.Body)
+	}
+
+	w = do(http.MethodGet, "/report/latest", nil)
+	var rep types.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &rep); err != nil || rep.CountPairs != 1 {
+		t.Fatalf("/report/latest: %d %s", w.Code, w.Body)
+	}
+}
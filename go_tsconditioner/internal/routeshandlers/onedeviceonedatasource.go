@@ -1,7 +1,7 @@
 package routeshandlers
 
 import (
-	"fmt"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 	"go_tsconditioner/internal/dboperations"
@@ -9,9 +9,12 @@ import (
 	"go_tsconditioner/internal/types"
 	"log"
 	"net/http"
+	"time"
 )
 
-func OneDeviceOneDataSource(DB *sqlx.DB) gin.HandlerFunc {
+// OneDeviceOneDataSource lit une plage brute ; l'unité de la série vient du
+// catalogue quand la datasource y a une fiche.
+func OneDeviceOneDataSource(repo dboperations.TelemetryRepository, catalogDB *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 1) Lire le JSON envoyé par React
 		var req types.OneDeviceOneDatasourceRequest
@@ -29,55 +32,113 @@ func OneDeviceOneDataSource(DB *sqlx.DB) gin.HandlerFunc {
 			})
 			return
 		}
+		downsample, err := getDownsampleMethod(req.Downsample)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-		var rows []dboperations.Telemetry
 		if req.DataSource == "" {
 			log.Printf("❌ Erreur SELECT in telemetry (device only)")
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Erreur lors de la lecture de Telemetry",
 			})
 			return
-		} else {
-			sqlQuery := `
-				SELECT *
-				FROM "Telemetry"
-				WHERE device = $1
-					AND datasource = $2
-					AND time>=$3
-				  	AND time<=$4
-			`
-			if req.Limit > 0 {
-				sqlQuery += fmt.Sprintf(" LIMIT %d", req.Limit)
-			}
-			if err := DB.Select(&rows, sqlQuery, req.Device, req.DataSource, req.From, req.To); err != nil {
-				log.Printf("❌ Erreur SELECT in telemetry (device+datasource): %v", err)
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": "Erreur lors de la lecture de Telemetry",
-				})
-				return
-			}
 		}
 
-		if len(rows) == 0 {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Aucune donnée Telemetry pour ce device",
+		// Lecture en flux : les DataUnit sont construits ligne à ligne, triés par SQL
+		pts, err := repo.ReadRange(c.Request.Context(), dboperations.TelemetryQuery{
+			Device:     req.Device,
+			DataSource: req.DataSource,
+			From:       req.From,
+			To:         req.To,
+			Limit:      req.Limit,
+		})
+		if errors.Is(err, dboperations.ErrTelemetryTooLarge) {
+			log.Printf("❌ Plage Telemetry trop volumineuse: %v", err)
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "Plage trop volumineuse : réduire [from, to], fixer 'limit' ou utiliser /remotedata/aggregated",
 			})
 			return
 		}
-
-		// Conversion Telemetry -> TimeSeries via la fonction du package dboperations
-		//var tsc timeseries.TsContainer
-		pts, err := dboperations.TelemetryRowsToTimeSeries(rows)
+		if abortedQueryJSON(c, err) {
+			return
+		}
 		if err != nil {
-			log.Printf("❌ Erreur conversion Telemetry -> TimeSeries: %v", err)
+			log.Printf("❌ Erreur SELECT in telemetry (device+datasource): %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Erreur lors de la conversion en TimeSeries",
+				"error": "Erreur lors de la lecture de Telemetry",
+			})
+			return
+		}
+
+		if len(pts.DataSeries) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Aucune donnée Telemetry pour ce device",
 			})
 			return
 		}
+
+		meta, ok := catalogMeta(c, catalogDB, req.Device, req.DataSource)
+		if !ok {
+			return
+		}
+		pts.Unit = meta.Unit
 		pts.MemId = store.NewMemId()
 		pts.Sort_Deltas_Stats()
 		store.GlobalTsStore.Save(pts)
-		c.JSON(http.StatusOK, pts.ToJSON())
+		seriesResponse(c, pts.ToJSONDownsampled(downsample, req.MaxPoints))
+	}
+}
+
+// OneDeviceOneDataSourceAggregated agrège la plage [from, to] côté SQL par buckets
+// (min / max / moyenne / nombre de points) au lieu de charger tous les points.
+// La taille du bucket est choisie pour viser targetPoints, sauf si bucketSeconds est fourni.
+// Average, Minimum et Maximum prennent l'unité du catalogue ; Count n'en a pas.
+func OneDeviceOneDataSourceAggregated(repo dboperations.TelemetryRepository, catalogDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.AggregatedRangeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Printf("❌ Erreur bind JSON AggregatedRangeRequest: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "JSON invalide ou incomplet",
+			})
+			return
+		}
+		if !req.To.After(req.From) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'to' doit être postérieur à 'from'"})
+			return
+		}
+
+		bucket := time.Duration(req.BucketSeconds) * time.Second
+		if req.BucketSeconds <= 0 {
+			bucket = dboperations.ChooseBucket(req.From, req.To, req.TargetPoints)
+		}
+
+		tsc, err := repo.Buckets(c.Request.Context(), req.Device, req.DataSource, req.From, req.To, bucket)
+		if abortedQueryJSON(c, err) {
+			return
+		}
+		if err != nil {
+			log.Printf("❌ Erreur agrégation Telemetry: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Erreur lors de l'agrégation de Telemetry",
+			})
+			return
+		}
+
+		meta, ok := catalogMeta(c, catalogDB, req.Device, req.DataSource)
+		if !ok {
+			return
+		}
+		for key, ts := range tsc.Ts {
+			if key != "Count" {
+				ts.Unit = meta.Unit
+			}
+			ts.MemId = store.NewMemId()
+			ts.Sort_Deltas_Stats()
+			store.GlobalTsStore.Save(ts)
+		}
+		containerResponse(c, tsc.ToJSON())
 	}
 }
@@ -1,8 +1,11 @@
 package routeshandlers
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"go_tsconditioner/internal/dboperations"
 	"go_tsconditioner/internal/store"
 	"go_tsconditioner/internal/timeseries"
 	"go_tsconditioner/internal/types"
@@ -10,12 +13,31 @@ import (
 	"time"
 )
 
-func Polishing(c *gin.Context) {
-	var req types.PolishingRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+// Polishing applique la chaîne réduction -> nettoyage -> régularisation -> interpolation.
+// Si device/datasource sont fournis, un fixedOutbounds sans bornes prend la plage
+// physique du catalogue.
+func Polishing(catalogDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.PolishingRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if catalogDB != nil && req.Device != "" && req.DataSource != "" {
+			m, err := dboperations.GetCatalogEntry(c.Request.Context(), catalogDB, req.Device, req.DataSource)
+			switch {
+			case err == nil:
+				applyCatalogBounds(&req, m)
+			case !errors.Is(err, dboperations.ErrCatalogEntryNotFound):
+				catalogErrorJSON(c, err)
+				return
+			}
+		}
+		polish(c, req)
 	}
+}
+
+func polish(c *gin.Context, req types.PolishingRequest) {
 	tsc := timeseries.TsContainer{
 		Name:    "Polished by " + req.Method1 + " -> " + req.Method2 + " -> " + req.Agg + " @ " + time.Duration(req.FreqSeconds).String() + "s",
 		Comment: "standard",
@@ -26,14 +48,27 @@ func Polishing(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "time series not found"})
 		return
 	}
+	// toutes les étapes sont exprimées dans l'unité de la série d'entrée, sauf
+	// une régularisation qui change de grandeur : le conteneur reste alors sans unité
+	regularize := req.FreqSeconds > 0 && req.Agg != "" && req.Agg != "none" && req.Agg != "None"
+	if !regularize || aggUnit(req.Agg, ts.Unit) == ts.Unit {
+		tsc.Unit = ts.Unit
+	}
 	ts.Sort_Deltas_Stats()
 	workingTs := ts
+	// Les séries produites ne vont au store qu'après la dernière étape :
+	// l'interpolation modifie workingTs en place, et un store compressé
+	// garde un instantané pris au Save.
+	var produced []*timeseries.TimeSeries
 	// ==================== ÉTAPE 1: REDUCTION =========== ====================
 	if req.Reduce == true {
 		tsreduced := workingTs.Reduce()
 		tsreduced.Sort_Deltas_Stats()
-		store.GlobalTsStore.Save(&tsreduced)
-		tsc.Ts["Reduced"] = &tsreduced
+		tsreduced.MemId = store.NewMemId()
+		produced = append(produced, &tsreduced)
+		if !addToContainer(c, &tsc, "Reduced", &tsreduced) {
+			return
+		}
 		workingTs = &tsreduced
 	}
 	// ==================== ÉTAPE 2: CLEANING PRE-REGULARIZATION ====================
@@ -43,11 +78,13 @@ func Polishing(c *gin.Context) {
 		tsclean.Sort_Deltas_Stats()
 		tsreject.Sort_Deltas_Stats()
 
-		store.GlobalTsStore.Save(&tsclean)
-		store.GlobalTsStore.Save(&tsreject)
+		tsclean.MemId = store.NewMemId()
+		tsreject.MemId = store.NewMemId()
+		produced = append(produced, &tsclean, &tsreject)
 
-		tsc.Ts["Pre Reg Cleaned"] = &tsclean
-		tsc.Ts["Pre Reg Rejected"] = &tsreject
+		if !addToContainer(c, &tsc, "Pre Reg Cleaned", &tsclean) || !addToContainer(c, &tsc, "Pre Reg Rejected", &tsreject) {
+			return
+		}
 
 		// La série nettoyée devient la série de travail pour l'étape suivante
 		workingTs = &tsclean
@@ -56,7 +93,7 @@ func Polishing(c *gin.Context) {
 	var regularizedTs timeseries.TimeSeries
 	//regularizationApplied := false
 
-	if req.FreqSeconds > 0 && req.Agg != "" && req.Agg != "none" && req.Agg != "None" {
+	if regularize {
 		freq := time.Duration(req.FreqSeconds) * time.Second
 		agg, err := getAggFunc(req.Agg, freq)
 		if err != nil {
@@ -67,9 +104,14 @@ func Polishing(c *gin.Context) {
 		workingTs.Sort_Deltas_Stats()
 		regularizedTs = workingTs.Regularize(freq, agg, 0)
 		regularizedTs.Sort_Deltas_Stats()
+		regularizedTs.Name = workingTs.Name + " Regularized"
+		regularizedTs.Unit = aggUnit(req.Agg, workingTs.Unit)
 
-		store.GlobalTsStore.Save(&regularizedTs)
-		tsc.Ts["Regularized"] = &regularizedTs
+		regularizedTs.MemId = store.NewMemId()
+		produced = append(produced, &regularizedTs)
+		if !addToContainer(c, &tsc, "Regularized", &regularizedTs) {
+			return
+		}
 
 		// La série régularisée devient la série de travail pour l'étape suivante
 		workingTs = &regularizedTs
@@ -91,12 +133,14 @@ func Polishing(c *gin.Context) {
 		tsclean.Sort_Deltas_Stats()
 		tsreject.Sort_Deltas_Stats()
 
-		store.GlobalTsStore.Save(&tsclean)
-		store.GlobalTsStore.Save(&tsreject)
+		tsclean.MemId = store.NewMemId()
+		tsreject.MemId = store.NewMemId()
+		produced = append(produced, &tsclean, &tsreject)
 		workingTs = &tsclean
 
-		tsc.Ts["Post Reg Cleaned"] = &tsclean
-		tsc.Ts["Post Reg Rejected"] = &tsreject
+		if !addToContainer(c, &tsc, "Post Reg Cleaned", &tsclean) || !addToContainer(c, &tsc, "Post Reg Rejected", &tsreject) {
+			return
+		}
 	}
 	// ==================== ÉTAPE 4: INTERPOLATION POST-REGULARIZATION ====================
 	if req.Interp != "" && req.Interp != "None" && req.Interp != "none" {
@@ -119,7 +163,25 @@ func Polishing(c *gin.Context) {
 		workingTs.Sort_Deltas_Stats()
 	}
 
-	c.JSON(http.StatusOK, tsc.ToJSON())
+	// Le store garde la pleine résolution : seule la vue renvoyée est réduite.
+	downsample, err := getDownsampleMethod(req.Downsample)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, p := range produced {
+		store.GlobalTsStore.Save(p)
+	}
+	containerResponse(c, tsc.ToJSONDownsampled(downsample, req.MaxPoints))
+}
+
+// addToContainer range ts dans tsc via Add (contrôle d'unité) et répond 400 en cas d'échec.
+func addToContainer(c *gin.Context, tsc *timeseries.TsContainer, key string, ts *timeseries.TimeSeries) bool {
+	if err := tsc.Add(key, ts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
 }
 
 // applyCleaning applique la méthode de nettoyage spécifiée
@@ -141,6 +203,7 @@ func applyCleaning(ts *timeseries.TimeSeries, method string, min, max, percent,
 		// Si méthode inconnue, retourner la série inchangée et une série vide de rejetés
 		empty := timeseries.TimeSeries{
 			Name:       ts.Name + " (no rejection)",
+			Unit:       ts.Unit,
 			DataSeries: []timeseries.DataUnit{},
 		}
 		return *ts, empty
@@ -175,6 +238,20 @@ func getAggFunc(aggName string, freq time.Duration) (timeseries.AggFunc, error)
 	}
 }
 
+// aggUnit renvoie l'unité de la série régularisée avec aggName : celle de la
+// série pour les agrégateurs qui gardent la grandeur, vide pour le comptage et
+// la pente (par pas de grille), l'énergie correspondante pour l'intégrale.
+func aggUnit(aggName, unit string) string {
+	switch aggName {
+	case "countValid", "slope":
+		return ""
+	case "integral":
+		return timeseries.AggIntegralUnit(unit)
+	default:
+		return unit
+	}
+}
+
 // getInterpMethod mappe le string venant du front vers l'InterpolationMethod
 func getInterpMethod(name string) (timeseries.InterpolationMethod, error) {
 	switch name {
@@ -198,3 +275,17 @@ func getInterpMethod(name string) (timeseries.InterpolationMethod, error) {
 		return timeseries.InterpNone, fmt.Errorf("unknown interpolation method: %s", name)
 	}
 }
+
+// getDownsampleMethod mappe le string venant du front vers le DownsampleMethod
+func getDownsampleMethod(name string) (timeseries.DownsampleMethod, error) {
+	switch name {
+	case "", "lttb", "LTTB":
+		return timeseries.DownsampleLTTB, nil
+	case "m4", "M4", "minmax":
+		return timeseries.DownsampleM4, nil
+	case "None", "none":
+		return timeseries.DownsampleNone, nil
+	default:
+		return timeseries.DownsampleNone, fmt.Errorf("unknown downsampling method: %s", name)
+	}
+}
@@ -0,0 +1,93 @@
+package routeshandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"go_tsconditioner/internal/store"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/types"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Avec un store compressé, la série rangée doit déjà contenir l'interpolation.
+func TestPolishingStoresInterpolatedSeries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store.GlobalTsStore.SetCompression(0)
+	defer store.GlobalTsStore.SetCompression(-1)
+
+	in := timeseries.TimeSeries{Name: "power", MemId: store.NewMemId()}
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 60; i++ {
+		if i >= 20 && i < 30 {
+			continue // trou de 10 minutes, comblé par l'interpolation
+		}
+		in.AddData(t0.Add(time.Duration(i)*time.Minute), float64(i))
+	}
+	store.GlobalTsStore.Save(&in)
+
+	router := gin.New()
+	router.POST("/polishing", Polishing(nil))
+	body, _ := json.Marshal(types.PolishingRequest{MemId: in.MemId, FreqSeconds: 60, Agg: "average", Interp: "Linear"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/polishing", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("%d %s", w.Code, w.Body)
+	}
+	var out struct {
+		Series map[string]struct {
+			Id uint64 `json:"id"`
+		} `json:"series"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	reg, ok := store.GlobalTsStore.Get(out.Series["Regularized"].Id)
+	if !ok || len(reg.DataSeries) == 0 {
+		t.Fatalf("regularized series not stored: %s", w.Body)
+	}
+	for _, du := range reg.DataSeries {
+		if math.IsNaN(du.Meas) {
+			t.Fatalf("stored series lacks the interpolation at %v", du.Chron)
+		}
+	}
+}
+
+// La série régularisée porte l'unité de l'agrégat, pas celle de l'entrée.
+func TestPolishingRegularizedUnit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	in := timeseries.TimeSeries{Name: "power", Unit: "kW", MemId: store.NewMemId()}
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		in.AddData(t0.Add(time.Duration(i)*time.Minute), 2)
+	}
+	store.GlobalTsStore.Save(&in)
+
+	router := gin.New()
+	router.POST("/polishing", Polishing(nil))
+	for agg, want := range map[string]string{"average": "kW", "countValid": "", "slope": "", "integral": "kJ"} {
+		body, _ := json.Marshal(types.PolishingRequest{MemId: in.MemId, FreqSeconds: 600, Agg: agg, Method2: "zScore", Lvl2: 3})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/polishing", bytes.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", agg, w.Code, w.Body)
+		}
+		var out struct {
+			Series map[string]struct {
+				Id uint64 `json:"id"`
+			} `json:"series"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		reg, ok := store.GlobalTsStore.Get(out.Series["Regularized"].Id)
+		if !ok || reg.Unit != want {
+			t.Fatalf("%s: regularized unit %q, want %q", agg, reg.Unit, want)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package routeshandlers
+
+import (
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/prompb"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/types"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Métriques exposées en remote read, étiquetées device / datasource (et unit
+// si le catalogue la connaît) :
+//   - tsc_raw : la Telemetry telle quelle ;
+//   - tsc_polished : nettoyée et régularisée selon le catalogue (cf. polishingDefaults).
+const (
+	promRawMetric      = "tsc_raw"
+	promPolishedMetric = "tsc_polished"
+)
+
+// maxRemoteReadSeries borne le nombre de séries d'une requête remote read.
+const maxRemoteReadSeries = 200
+
+// PromRemoteRead répond au remote read Prometheus (réponse SAMPLES, protobuf
+// snappy). Grafana / Prometheus interrogent par exemple
+// tsc_polished{device="<uuid>", datasource=~"power|temp"}.
+func PromRemoteRead(repo dboperations.TelemetryRepository, catalogDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req prompb.ReadRequest
+		if !decodePromBody(c, &req) {
+			return
+		}
+		if len(req.AcceptedResponseTypes) > 0 && !acceptsSamples(req.AcceptedResponseTypes) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only the SAMPLES response type is supported"})
+			return
+		}
+
+		var resp prompb.ReadResponse
+		for _, q := range req.Queries {
+			res, err := promQuery(c, repo, catalogDB, q)
+			if err != nil {
+				var mErr *promMatcherError
+				switch {
+				case errors.Is(err, errPromResponseSent):
+				case errors.As(err, &mErr):
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				case errors.Is(err, dboperations.ErrTelemetryTooLarge):
+					c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+				case abortedQueryJSON(c, err):
+				default:
+					log.Printf("❌ Erreur remote read Prometheus: %v", err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				}
+				return
+			}
+			resp.Results = append(resp.Results, res)
+		}
+
+		c.Header("Content-Encoding", "snappy")
+		c.Data(http.StatusOK, "application/x-protobuf", prompb.Encode(&resp))
+	}
+}
+
+// PromRemoteWrite ingère un WriteRequest Prometheus dans "Telemetry" via w :
+// label device (UUID) obligatoire, datasource = label datasource ou, à
+// défaut, __name__ ; serial, manufacturer et model sont repris s'ils existent.
+// Les NaN (marqueurs de péremption) sont ignorés. Succès : 204.
+func PromRemoteWrite(w dboperations.TelemetryWriter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req prompb.WriteRequest
+		if !decodePromBody(c, &req) {
+			return
+		}
+		series, err := promWriteSeries(&req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var rows []dboperations.Telemetry
+		for _, s := range series {
+			rows = append(rows, dboperations.TimeSeriesToTelemetryRows(s.ts, s.tmpl)...)
+		}
+		if len(rows) > 0 {
+			if err := w.Insert(c.Request.Context(), rows); err != nil {
+				if abortedQueryJSON(c, err) {
+					return
+				}
+				log.Printf("❌ Erreur écriture Telemetry (remote write): %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'écriture de Telemetry"})
+				return
+			}
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// decodePromBody lit un corps protobuf snappy ; false : réponse déjà écrite.
+func decodePromBody(c *gin.Context, m interface{ Unmarshal([]byte) error }) bool {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIngestBytes))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := prompb.Decode(body, m); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, prompb.ErrTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+func acceptsSamples(types []prompb.ResponseType) bool {
+	for _, t := range types {
+		if t == prompb.ResponseSamples {
+			return true
+		}
+	}
+	return false
+}
+
+type promMatcherError struct{ msg string }
+
+// errPromResponseSent : la réponse d'erreur est déjà écrite (catalogMeta).
+var errPromResponseSent = errors.New("response already sent")
+
+func (e *promMatcherError) Error() string { return e.msg }
+
+// promMatcher : un LabelMatcher compilé ; un label absent vaut "".
+type promMatcher struct {
+	m  prompb.LabelMatcher
+	re *regexp.Regexp
+}
+
+func compilePromMatchers(ms []prompb.LabelMatcher) ([]promMatcher, error) {
+	out := make([]promMatcher, len(ms))
+	for i, m := range ms {
+		out[i].m = m
+		switch m.Type {
+		case prompb.MatchEQ, prompb.MatchNEQ:
+		case prompb.MatchRE, prompb.MatchNRE:
+			// ancré comme dans Prometheus
+			re, err := regexp.Compile("^(?:" + m.Value + ")$")
+			if err != nil {
+				return nil, &promMatcherError{fmt.Sprintf("matcher %s: %v", m.Name, err)}
+			}
+			out[i].re = re
+		default:
+			return nil, &promMatcherError{fmt.Sprintf("matcher %s: unknown type %d", m.Name, m.Type)}
+		}
+	}
+	return out, nil
+}
+
+func (pm promMatcher) matches(v string) bool {
+	switch pm.m.Type {
+	case prompb.MatchEQ:
+		return v == pm.m.Value
+	case prompb.MatchNEQ:
+		return v != pm.m.Value
+	case prompb.MatchRE:
+		return pm.re.MatchString(v)
+	default:
+		return !pm.re.MatchString(v)
+	}
+}
+
+// matchAll applique les matchers, sauf ceux portant sur le label skip.
+func matchAll(ms []promMatcher, labels []prompb.Label, skip string) bool {
+	for _, pm := range ms {
+		if pm.m.Name == skip {
+			continue
+		}
+		v := ""
+		for _, l := range labels {
+			if l.Name == pm.m.Name {
+				v = l.Value
+				break
+			}
+		}
+		if !pm.matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// promQuery liste les couples device / datasource candidats, garde ceux dont
+// les labels passent les matchers, puis lit [start, end] pour chacun.
+func promQuery(c *gin.Context, repo dboperations.TelemetryRepository, catalogDB *sqlx.DB, q prompb.Query) (prompb.QueryResult, error) {
+	var res prompb.QueryResult
+	ms, err := compilePromMatchers(q.Matchers)
+	if err != nil {
+		return res, err
+	}
+	ctx := c.Request.Context()
+	from, to := time.UnixMilli(q.StartTimestampMs).UTC(), time.UnixMilli(q.EndTimestampMs).UTC()
+
+	type candidate struct{ device, datasource string }
+	var candidates []candidate
+	var device string
+	for _, pm := range ms {
+		if pm.m.Name == "device" && pm.m.Type == prompb.MatchEQ {
+			device = pm.m.Value
+		}
+	}
+	if device != "" {
+		dss, err := repo.ListDataSources(ctx, device)
+		if err != nil {
+			return res, err
+		}
+		for _, ds := range dss {
+			candidates = append(candidates, candidate{device, ds})
+		}
+	} else {
+		devs, err := repo.ListDevices(ctx, from)
+		if err != nil {
+			return res, err
+		}
+		for _, d := range devs {
+			for _, ds := range d.DataSources {
+				candidates = append(candidates, candidate{d.DeviceID.String(), ds.Name})
+			}
+		}
+	}
+
+	type selected struct {
+		candidate
+		labels []prompb.Label
+		polish bool
+	}
+	var sel []selected
+	metas := make(map[candidate]types.DataSourceMeta)
+	for _, cand := range candidates {
+		for _, name := range []string{promRawMetric, promPolishedMetric} {
+			labels := []prompb.Label{{Name: "__name__", Value: name}, {Name: "datasource", Value: cand.datasource}, {Name: "device", Value: cand.device}}
+			// le catalogue (label unit) n'est lu que pour les séries déjà retenues
+			if !matchAll(ms, labels, "unit") {
+				continue
+			}
+			if _, cached := metas[cand]; !cached {
+				meta, ok := catalogMeta(c, catalogDB, cand.device, cand.datasource)
+				if !ok {
+					return res, errPromResponseSent
+				}
+				metas[cand] = meta
+			}
+			if meta := metas[cand]; meta.Unit != "" {
+				labels = append(labels, prompb.Label{Name: "unit", Value: meta.Unit})
+			}
+			if matchAll(ms, labels, "") {
+				sel = append(sel, selected{cand, labels, name == promPolishedMetric})
+			}
+		}
+	}
+	if len(sel) > maxRemoteReadSeries {
+		return res, &promMatcherError{fmt.Sprintf("query selects %d series (max %d)", len(sel), maxRemoteReadSeries)}
+	}
+
+	for _, s := range sel {
+		ts, err := repo.ReadRange(ctx, dboperations.TelemetryQuery{Device: s.device, DataSource: s.datasource, From: from, To: to})
+		if err != nil {
+			return res, err
+		}
+		if len(ts.DataSeries) == 0 {
+			continue
+		}
+		if s.polish {
+			polished, err := polishWithDefaults(ts, polishingDefaults(metas[s.candidate]))
+			if err != nil {
+				return res, err
+			}
+			ts = &polished
+		}
+		out := prompb.TimeSeries{Labels: s.labels, Samples: promSamples(ts, q.StartTimestampMs, q.EndTimestampMs)}
+		if len(out.Samples) > 0 {
+			res.Timeseries = append(res.Timeseries, out)
+		}
+	}
+	return res, nil
+}
+
+// polishWithDefaults rejoue la chaîne de polish() sans passer par le TsStore :
+// nettoyage 1 puis régularisation, selon req (cf. polishingDefaults).
+func polishWithDefaults(ts *timeseries.TimeSeries, req types.PolishingRequest) (timeseries.TimeSeries, error) {
+	ts.Sort_Deltas_Stats()
+	working := *ts
+	if req.Method1 != "" {
+		working, _ = applyCleaning(&working, req.Method1, req.Min1, req.Max1, req.Percent1, req.Lvl1)
+		working.Sort_Deltas_Stats()
+	}
+	if req.FreqSeconds > 0 {
+		freq := time.Duration(req.FreqSeconds) * time.Second
+		agg, err := getAggFunc(req.Agg, freq)
+		if err != nil {
+			return working, err
+		}
+		working = working.Regularize(freq, agg, 0)
+		working.Unit = aggUnit(req.Agg, working.Unit)
+		working.Sort_Deltas_Stats()
+	}
+	return working, nil
+}
+
+// promSamples garde les points StOK et finis de [startMs, endMs].
+func promSamples(ts *timeseries.TimeSeries, startMs, endMs int64) []prompb.Sample {
+	out := make([]prompb.Sample, 0, len(ts.DataSeries))
+	for _, du := range ts.DataSeries {
+		ms := du.Chron.UnixMilli()
+		if du.Status != timeseries.StOK || math.IsNaN(du.Meas) || math.IsInf(du.Meas, 0) || ms < startMs || ms > endMs {
+			continue
+		}
+		out = append(out, prompb.Sample{Value: du.Meas, Timestamp: ms})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Timestamp < out[j].Timestamp })
+	return out
+}
+
+// promWriteSeries regroupe les échantillons par device / datasource.
+func promWriteSeries(req *prompb.WriteRequest) ([]ingestSeries, error) {
+	byKey := make(map[string]*ingestSeries)
+	for i, pts := range req.Timeseries {
+		labels := make(map[string]string, len(pts.Labels))
+		for _, l := range pts.Labels {
+			labels[l.Name] = l.Value
+		}
+		device, err := uuid.Parse(labels["device"])
+		if err != nil {
+			return nil, fmt.Errorf("series %d: label device=%q is not a device UUID", i, labels["device"])
+		}
+		datasource := labels["datasource"]
+		if datasource == "" {
+			datasource = labels["__name__"]
+		}
+		if datasource == "" {
+			return nil, fmt.Errorf("series %d: missing datasource and __name__ labels", i)
+		}
+		key := device.String() + "/" + datasource
+		s, ok := byKey[key]
+		if !ok {
+			s = &ingestSeries{
+				tmpl: dboperations.Telemetry{
+					Device:       device,
+					Datasource:   datasource,
+					Manufacturer: labels["manufacturer"],
+					Model:        labels["model"],
+					Serial:       labels["serial"],
+				},
+				ts: &timeseries.TimeSeries{Name: datasource, Comment: "remote write, device " + device.String()},
+			}
+			byKey[key] = s
+		}
+		for _, smp := range pts.Samples {
+			if math.IsNaN(smp.Value) {
+				continue
+			}
+			s.ts.AddData(time.UnixMilli(smp.Timestamp).UTC(), smp.Value)
+		}
+	}
+	return sortedIngestSeries(byKey), nil
+}
@@ -0,0 +1,109 @@
+package routeshandlers
+
+import (
+	"bytes"
+	"context"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/prompb"
+	"go_tsconditioner/internal/types"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPrometheusRemoteWriteThenRead(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	repo, err := dboperations.OpenSQLiteTelemetry(filepath.Join(dir, "telemetry.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	catalogDB, err := dboperations.OpenCatalogDB(filepath.Join(dir, "catalog.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer catalogDB.Close()
+
+	router := gin.New()
+	router.POST("/prometheus/write", PromRemoteWrite(repo))
+	router.POST("/prometheus/read", PromRemoteRead(repo, catalogDB))
+
+	// payload enregistré (cf. internal/prompb/testdata) : power x2, temp x1
+	const dev = "6f1c2a0e-5b7d-4c3e-9a41-0d2b8e7f1a23"
+	body, err := os.ReadFile("../prompb/testdata/write_request.snappy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/prometheus/write", bytes.NewReader(body)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("remote write: %d %s", w.Code, w.Body)
+	}
+
+	cadence, lo, hi := int64(120), 0.0, 2.0
+	if err := dboperations.UpsertCatalogEntry(context.Background(), catalogDB, types.DataSourceMeta{
+		Device: dev, DataSource: "power", Unit: "kW", Kind: types.DataSourceKindGauge,
+		CadenceSeconds: &cadence, PhysMin: &lo, PhysMax: &hi,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(matchers ...prompb.LabelMatcher) (*httptest.ResponseRecorder, prompb.ReadResponse) {
+		t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		req := prompb.ReadRequest{Queries: []prompb.Query{{
+			StartTimestampMs: t0.UnixMilli(), EndTimestampMs: t0.Add(time.Hour).UnixMilli(), Matchers: matchers,
+		}}}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/prometheus/read", bytes.NewReader(prompb.Encode(&req))))
+		var resp prompb.ReadResponse
+		if w.Code == http.StatusOK {
+			if err := prompb.Decode(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w, resp
+	}
+
+	w, resp := read(
+		prompb.LabelMatcher{Type: prompb.MatchEQ, Name: "device", Value: dev},
+		prompb.LabelMatcher{Type: prompb.MatchRE, Name: "__name__", Value: "tsc_.*"},
+	)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "snappy" || len(resp.Results) != 1 {
+		t.Fatalf("remote read: %d %s", w.Code, w.Body)
+	}
+	got := map[string]prompb.TimeSeries{}
+	for _, ts := range resp.Results[0].Timeseries {
+		got[ts.Labels[0].Value+"/"+ts.Labels[1].Value] = ts
+	}
+	if len(got) != 4 {
+		t.Fatalf("series: %+v", resp.Results[0].Timeseries)
+	}
+	raw := got["tsc_raw/power"]
+	if len(raw.Samples) != 2 || raw.Samples[1].Value != 2.5 || len(raw.Labels) != 4 || raw.Labels[3] != (prompb.Label{Name: "unit", Value: "kW"}) {
+		t.Fatalf("tsc_raw/power: %+v", raw)
+	}
+	// 2.5 hors plage physique : retiré, puis une moyenne par bucket de 2 min
+	polished := got["tsc_polished/power"]
+	if len(polished.Samples) != 1 || polished.Samples[0].Value != 1.5 {
+		t.Fatalf("tsc_polished/power: %+v", polished)
+	}
+
+	// filtre sur unit : seule la datasource cataloguée reste
+	_, resp = read(
+		prompb.LabelMatcher{Type: prompb.MatchEQ, Name: "__name__", Value: "tsc_raw"},
+		prompb.LabelMatcher{Type: prompb.MatchEQ, Name: "unit", Value: "kW"},
+	)
+	if len(resp.Results[0].Timeseries) != 1 {
+		t.Fatalf("unit matcher: %+v", resp.Results[0].Timeseries)
+	}
+
+	if w, _ := read(prompb.LabelMatcher{Type: prompb.MatchRE, Name: "datasource", Value: "("}); w.Code != http.StatusBadRequest {
+		t.Fatalf("bad regexp: %d", w.Code)
+	}
+}
@@ -2,16 +2,21 @@ package routeshandlers
 
 import (
 	"github.com/gin-gonic/gin"
-	"github.com/jmoiron/sqlx"
-	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/registry"
 	"log"
 	"net/http"
 )
 
-func RefreshDevicesDB(remoteDB *sqlx.DB) gin.HandlerFunc {
+// RefreshDevicesDB force un rafraîchissement du registre (cache SQLite + liste en mémoire)
+// et renvoie les devices ajoutés / retirés.
+func RefreshDevicesDB(reg *registry.Registry) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
-		if _, err := dboperations.LoadDevicesWithDataSourcesRemote(remoteDB); err != nil {
+		diff, err := reg.Refresh(c.Request.Context())
+		if err != nil {
+			if abortedQueryJSON(c, err) {
+				return
+			}
 			log.Printf("❌ Refresh devices failed: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"ok":    false,
@@ -22,7 +27,8 @@ func RefreshDevicesDB(remoteDB *sqlx.DB) gin.HandlerFunc {
 
 		log.Println("✅ Devices database refreshed")
 		c.JSON(http.StatusOK, gin.H{
-			"ok": true,
+			"ok":   true,
+			"diff": diff,
 		})
 	}
 }
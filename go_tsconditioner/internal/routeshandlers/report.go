@@ -2,26 +2,60 @@ package routeshandlers
 
 import (
 	"github.com/gin-gonic/gin"
-	"github.com/jmoiron/sqlx"
 	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/ingest"
 	"go_tsconditioner/internal/types"
 	"net/http"
 	"sort"
 	"time"
 )
 
-func LastSeenPoints_json(db *sqlx.DB) gin.HandlerFunc {
+// lastSeenWindow borne la recherche des derniers points du rapport.
+const lastSeenWindow = time.Hour
+
+// LastSeenPoints_json fusionne les derniers points de Telemetry et ceux ingérés
+// en direct (live, optionnel), en gardant le plus récent de chaque couple.
+func LastSeenPoints_json(repo dboperations.TelemetryRepository, live *ingest.LastSeen) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		rows, err := dboperations.ListLastSeenPoints(c.Request.Context(), db)
+		since := time.Now().Add(-lastSeenWindow)
+		rows, err := repo.LastSeenPoints(c.Request.Context(), since)
+		if abortedQueryJSON(c, err) {
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		if live != nil {
+			rows = mergeLastSeen(rows, live.Since(since))
+		}
 		rep := Build(rows)
 		c.JSON(http.StatusOK, rep)
 	}
 }
 
+func mergeLastSeen(rows, live []types.LastTelemetryPoint) []types.LastTelemetryPoint {
+	type key struct{ device, datasource string }
+	idx := make(map[key]int, len(rows))
+	for i, r := range rows {
+		idx[key{r.Device, r.DataSource}] = i
+	}
+	for _, p := range live {
+		i, ok := idx[key{p.Device, p.DataSource}]
+		switch {
+		case !ok:
+			idx[key{p.Device, p.DataSource}] = len(rows)
+			rows = append(rows, p)
+		case p.LastTime.After(rows[i].LastTime):
+			if p.Serial == nil {
+				p.Serial = rows[i].Serial
+			}
+			rows[i] = p
+		}
+	}
+	return rows
+}
+
 func Build(rows []types.LastTelemetryPoint) types.Report {
 	byDevice := map[string]*types.DeviceSummary{}
 
@@ -0,0 +1,119 @@
+package routeshandlers
+
+import (
+	"errors"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/store"
+	"go_tsconditioner/internal/types"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+// SaveTimeSeries persiste une série du TsStore (et ses paramètres de polishing)
+// dans les tables timeseries / dataunits de la base locale.
+func SaveTimeSeries(localDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.SaveTimeSeriesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ts, ok := store.GlobalTsStore.Get(req.MemId)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "time series not found"})
+			return
+		}
+
+		// copie superficielle : on ne renomme pas la série du store
+		toSave := *ts
+		if req.Name != "" {
+			toSave.Name = req.Name
+		}
+		if toSave.Name == "" {
+			toSave.Name = req.DataSource
+		}
+
+		meta := dboperations.MetaFromPolishing(req.Device, req.Serial, req.DataSource, req.Polishing)
+		id, err := dboperations.SaveTimeSeries(c.Request.Context(), localDB, &toSave, meta)
+		if err != nil {
+			log.Printf("❌ Erreur sauvegarde TimeSeries (memId=%d): %v", req.MemId, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la sauvegarde de la série"})
+			return
+		}
+
+		log.Printf("✅ TimeSeries memId=%d sauvegardée -> timeseries.id=%d (%d points)", req.MemId, id, len(toSave.DataSeries))
+		c.JSON(http.StatusCreated, gin.H{
+			"ok":     true,
+			"id":     id,
+			"points": len(toSave.DataSeries),
+		})
+	}
+}
+
+func ListSavedTimeSeries(localDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := dboperations.ListSavedTimeSeries(c.Request.Context(), localDB)
+		if err != nil {
+			log.Printf("❌ Erreur lecture timeseries sauvegardées: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// LoadSavedTimeSeries relit une série persistée et la replace dans le TsStore
+// sous un nouveau memId, pour pouvoir la re-polir.
+func LoadSavedTimeSeries(localDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+
+		ts, info, err := dboperations.LoadSavedTimeSeries(c.Request.Context(), localDB, id)
+		if err != nil {
+			savedSeriesErrorJSON(c, err)
+			return
+		}
+
+		ts.MemId = store.NewMemId()
+		ts.Sort_Deltas_Stats()
+		store.GlobalTsStore.Save(ts)
+
+		c.JSON(http.StatusOK, gin.H{
+			"info":   info,
+			"series": ts.ToJSON(),
+		})
+	}
+}
+
+func DeleteSavedTimeSeries(localDB *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		if err := dboperations.DeleteSavedTimeSeries(c.Request.Context(), localDB, id); err != nil {
+			savedSeriesErrorJSON(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+}
+
+func savedSeriesErrorJSON(c *gin.Context, err error) {
+	if abortedQueryJSON(c, err) {
+		return
+	}
+	if errors.Is(err, dboperations.ErrSavedSeriesNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("❌ Erreur timeseries sauvegardées: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,116 @@
+package routeshandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go_tsconditioner/internal/store"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/tswire"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxUploadBytes borne le corps d'un envoi vers le TsStore (variable pour les tests).
+var maxUploadBytes int64 = maxIngestBytes
+
+// UploadToStore range dans le TsStore une série (TimeSeriesJSON) ou un
+// conteneur (TsContainerJSON, reconnu à sa clé "series"), par exemple un
+// export renvoyé tel quel. Chaque série reçoit un nouveau memId et ses
+// statistiques sont recalculées ; la réponse porte les nouveaux identifiants.
+// Le corps peut aussi être au format TSW (Content-Type tswire.MediaType).
+func UploadToStore(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadBytes))
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tj, cj, err := decodeUpload(c.ContentType(), body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if cj != nil {
+		tsc, err := timeseries.TsContainerFromJSON(cj)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		for _, ts := range tsc.Ts {
+			storeImported(ts)
+		}
+		containerResponse(c, tsc.ToJSON())
+		return
+	}
+
+	ts, err := timeseries.TimeSeriesFromJSON(tj)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	storeImported(&ts)
+	seriesResponse(c, ts.ToJSON())
+}
+
+// decodeUpload rend soit une série, soit un conteneur.
+func decodeUpload(contentType string, body []byte) (*timeseries.TimeSeriesJSON, *timeseries.TsContainerJSON, error) {
+	if contentType == tswire.MediaType {
+		m, err := tswire.Decode(body)
+		return m.Series, m.Container, err
+	}
+
+	var probe struct {
+		Series json.RawMessage `json:"series"`
+	}
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return nil, nil, err
+	}
+	if probe.Series != nil {
+		var cj timeseries.TsContainerJSON
+		if err := json.Unmarshal(body, &cj); err != nil {
+			return nil, nil, err
+		}
+		return nil, &cj, nil
+	}
+	var tj timeseries.TimeSeriesJSON
+	if err := json.Unmarshal(body, &tj); err != nil {
+		return nil, nil, err
+	}
+	return &tj, nil, nil
+}
+
+// storeImported range une série reçue d'un client : nouveau memId, statistiques recalculées.
+func storeImported(ts *timeseries.TimeSeries) {
+	ts.Sort_Deltas_Stats()
+	ts.MemId = store.NewMemId()
+	store.GlobalTsStore.Save(ts)
+}
+
+// ListStore décrit le contenu du TsStore : séries (taille, âge, inactivité,
+// expiration), de la plus récemment utilisée à la moins récente, et occupation.
+func ListStore(c *gin.Context) {
+	entries, usage := store.GlobalTsStore.List()
+	c.JSON(http.StatusOK, gin.H{"usage": usage, "series": entries})
+}
+
+// DeleteFromStore retire une série du TsStore (route /store/:memId).
+func DeleteFromStore(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("memId"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid memId"})
+		return
+	}
+	if !store.GlobalTsStore.Delete(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "time series not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -0,0 +1,166 @@
+package routeshandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go_tsconditioner/internal/store"
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/tswire"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUploadContainerToStore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/store", UploadToStore)
+
+	in := `{"name":"export","series":{
+		"a":{"name":"a","id":999,"chron":["2025-01-01T00:00:00Z","2025-01-01T00:01:00Z"],"meas":[1,2]},
+		"b":{"name":"b","chron":["2025-01-01T00:00:00Z"],"meas":[null],"status":["StMissing"]}}}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/store", bytes.NewBufferString(in)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("%d %s", w.Code, w.Body)
+	}
+	var out struct {
+		Series map[string]struct {
+			Id uint64 `json:"id"`
+		} `json:"series"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil || len(out.Series) != 2 {
+		t.Fatalf("%s", w.Body)
+	}
+	a := out.Series["a"].Id
+	if a == 999 || a == 0 {
+		t.Fatalf("client id kept: %d", a)
+	}
+	if ts, ok := store.GlobalTsStore.Get(a); !ok || len(ts.DataSeries) != 2 || ts.Len != 2 {
+		t.Fatalf("stored series: %v %+v", ok, ts)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/store", bytes.NewBufferString(`{"chron":["2025-01-01T00:00:00Z"],"meas":[]}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("length mismatch: %d", w.Code)
+	}
+}
+
+func TestUploadToStoreTSW(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/store", UploadToStore)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	in := &timeseries.TimeSeriesJSON{
+		Name:  "tsw",
+		Chron: []time.Time{start, start.Add(time.Minute), start.Add(2 * time.Minute)},
+		Meas:  []timeseries.JSONFloat64{1, 2, 4},
+	}
+	body, err := tswire.EncodeSeries(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// corps TSW, réponse TSW
+	req := httptest.NewRequest(http.MethodPost, "/store", bytes.NewReader(body))
+	req.Header.Set("Content-Type", tswire.MediaType)
+	req.Header.Set("Accept", tswire.MediaType)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != tswire.MediaType || w.Header().Get("Vary") != "Accept" {
+		t.Fatalf("%d %v", w.Code, w.Header())
+	}
+	m, err := tswire.Decode(w.Body.Bytes())
+	if err != nil || m.Series == nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if m.Series.MemId == 0 || len(m.Series.Meas) != 3 || m.Series.Meas[2] != 4 || m.Series.Stats == nil || m.Series.Stats.Len != 3 {
+		t.Fatalf("response: %+v", m.Series)
+	}
+	if ts, ok := store.GlobalTsStore.Get(m.Series.MemId); !ok || len(ts.DataSeries) != 3 {
+		t.Fatalf("stored series: %v", ok)
+	}
+
+	// Accept du navigateur (axios) : JSON
+	req = httptest.NewRequest(http.MethodPost, "/store", bytes.NewReader(body))
+	req.Header.Set("Content-Type", tswire.MediaType)
+	req.Header.Set("Accept", "application/json, text/plain, */*")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !json.Valid(w.Body.Bytes()) {
+		t.Fatalf("json fallback: %d %v", w.Code, w.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/store", bytes.NewReader(body[:len(body)-3]))
+	req.Header.Set("Content-Type", tswire.MediaType)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("truncated body: %d", w.Code)
+	}
+}
+
+func TestListAndDeleteStore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/store", ListStore)
+	router.DELETE("/store/:memId", DeleteFromStore)
+
+	ts := &timeseries.TimeSeries{Name: "listed", Unit: "kW", MemId: store.NewMemId()}
+	ts.DataSeries = []timeseries.DataUnit{timeseries.NewDataUnit(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), 1)}
+	store.GlobalTsStore.Save(ts)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/store", nil))
+	var out struct {
+		Usage  store.Usage   `json:"usage"`
+		Series []store.Entry `json:"series"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &out) != nil {
+		t.Fatalf("list: %d %s", w.Code, w.Body)
+	}
+	// la plus récemment utilisée en tête
+	if len(out.Series) == 0 || out.Series[0].MemId != ts.MemId || out.Series[0].Name != "listed" || out.Series[0].Points != 1 || out.Series[0].Bytes == 0 || out.Usage.Bytes < out.Series[0].Bytes {
+		t.Fatalf("list: %s", w.Body)
+	}
+
+	for _, tc := range []struct {
+		path string
+		code int
+	}{
+		{fmt.Sprintf("/store/%d", ts.MemId), http.StatusOK},
+		{fmt.Sprintf("/store/%d", ts.MemId), http.StatusNotFound},
+		{"/store/abc", http.StatusBadRequest},
+	} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, tc.path, nil))
+		if w.Code != tc.code {
+			t.Fatalf("DELETE %s: %d %s", tc.path, w.Code, w.Body)
+		}
+	}
+	if _, ok := store.GlobalTsStore.Get(ts.MemId); ok {
+		t.Fatal("deleted series still in store")
+	}
+}
+
+func TestUploadToStoreTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/store", UploadToStore)
+
+	defer func(n int64) { maxUploadBytes = n }(maxUploadBytes)
+	maxUploadBytes = 64
+
+	w := httptest.NewRecorder()
+	body := `{"name":"big","chron":["2025-01-01T00:00:00Z","2025-01-01T00:01:00Z"],"meas":[1,2]}`
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/store", bytes.NewBufferString(body)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("%d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,324 @@
+package routeshandlers
+
+import (
+	"context"
+	"fmt"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/ingest"
+	"go_tsconditioner/internal/timeseries"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	maxStreamPairs     = 50
+	streamQueryTimeout = 30 * time.Second // par lecture : le flux lui-même n'a pas d'échéance
+	streamKeepAlive    = 15 * time.Second
+	defaultCleanWindow = 500
+)
+
+// méthodes de applyCleaning utilisables en direct
+var liveCleaningMethods = map[string]bool{
+	"fixedOutbounds": true, "outerPercentile": true, "lowerPercentile": true,
+	"upperPercentile": true, "zScore": true, "peirce": true,
+}
+
+type streamPoint struct {
+	Time   time.Time              `json:"time"`
+	Value  timeseries.JSONFloat64 `json:"value"`
+	Status timeseries.StatusCode  `json:"status"`
+}
+
+type streamEvent struct {
+	ingest.Pair
+	Points []streamPoint `json:"points"`
+}
+
+// streamState : curseur (dernier horodatage envoyé) et, avec nettoyage, la
+// fenêtre glissante des derniers points sur laquelle il est rejoué.
+type streamState struct {
+	pair   ingest.Pair
+	cursor time.Time
+	window []timeseries.DataUnit
+}
+
+// StreamTelemetry diffuse en Server-Sent Events les nouveaux points des séries
+// demandées (route /stream, événements "points", "error" et "ping").
+//
+//   - pair=<device>/<datasource>, répétable ;
+//   - interval : période de relecture de Telemetry en secondes (5 par défaut) ;
+//     les points ingérés localement (MQTT, /write) arrivent sans attendre ;
+//     local, s'il n'est pas nil, est relu avec repo : c'est la table où ils sont
+//     écrits quand elle n'est pas celle de repo (Postgres), et la relecture
+//     rattrape un lot que le Hub n'a pas pu remettre ;
+//   - method, min, max, percent, lvl : nettoyage optionnel (cf. applyCleaning),
+//     rejoué à chaque lot sur les window derniers points (500 par défaut) ;
+//     les points rejetés sont envoyés avec leur statut.
+func StreamTelemetry(repo, local dboperations.TelemetryRepository, hub *ingest.Hub) gin.HandlerFunc {
+	sources := []dboperations.TelemetryRepository{repo}
+	if local != nil {
+		sources = append(sources, local)
+	}
+	return func(c *gin.Context) {
+		pairs, err := streamPairs(c.QueryArray("pair"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		interval := 5 * time.Second
+		if v := c.Query("interval"); v != "" {
+			s, err := strconv.Atoi(v)
+			if err != nil || s < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be an integer >= 1 (seconds)"})
+				return
+			}
+			interval = time.Duration(s) * time.Second
+		}
+		clean, err := liveCleaningFrom(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx := c.Request.Context()
+		states := make([]*streamState, len(pairs))
+		byPair := make(map[ingest.Pair]*streamState, len(pairs))
+		for i, p := range pairs {
+			st, err := openStreamState(ctx, sources, p, clean.method != "")
+			if err != nil {
+				if !abortedQueryJSON(c, err) {
+					log.Printf("❌ Erreur ouverture flux (%s/%s): %v", p.Device, p.DataSource, err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				}
+				return
+			}
+			states[i], byPair[p] = st, st
+		}
+
+		var live <-chan []dboperations.Telemetry
+		if hub != nil {
+			ch, unsubscribe := hub.Subscribe(pairs)
+			defer unsubscribe()
+			live = ch
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no") // nginx : pas de mise en tampon
+		c.Status(http.StatusOK)
+		c.Writer.Flush() // en-têtes envoyés tout de suite, sans attendre le premier événement
+		poll := time.NewTicker(interval)
+		defer poll.Stop()
+		keepAlive := time.NewTicker(streamKeepAlive)
+		defer keepAlive.Stop()
+
+		send := func(st *streamState, dus []timeseries.DataUnit) {
+			if ev, ok := st.advance(dus, clean); ok {
+				c.SSEvent("points", ev)
+			}
+		}
+		c.Stream(func(_ io.Writer) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case rows := <-live:
+				for p, dus := range telemetryRowsByPair(rows) {
+					if st, ok := byPair[p]; ok {
+						send(st, dus)
+					}
+				}
+			case <-poll.C:
+				for _, st := range states {
+					dus, err := st.poll(ctx, sources)
+					if ctx.Err() != nil {
+						return false
+					}
+					if err != nil {
+						c.SSEvent("error", gin.H{"device": st.pair.Device, "datasource": st.pair.DataSource, "error": err.Error()})
+						continue
+					}
+					send(st, dus)
+				}
+			case now := <-keepAlive.C:
+				c.SSEvent("ping", now.UTC())
+			}
+			return true
+		})
+	}
+}
+
+func streamPairs(raw []string) ([]ingest.Pair, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("at least one pair=<device>/<datasource> is required")
+	}
+	if len(raw) > maxStreamPairs {
+		return nil, fmt.Errorf("at most %d pairs per stream", maxStreamPairs)
+	}
+	seen := make(map[ingest.Pair]bool, len(raw))
+	var pairs []ingest.Pair
+	for _, r := range raw {
+		device, datasource, ok := strings.Cut(r, "/")
+		if !ok || device == "" || datasource == "" {
+			return nil, fmt.Errorf("invalid pair %q, expected <device>/<datasource>", r)
+		}
+		// forme canonique, comme les lignes diffusées par le Hub
+		if id, err := uuid.Parse(device); err == nil {
+			device = id.String()
+		}
+		p := ingest.Pair{Device: device, DataSource: datasource}
+		if !seen[p] {
+			seen[p] = true
+			pairs = append(pairs, p)
+		}
+	}
+	return pairs, nil
+}
+
+type liveCleaning struct {
+	method                   string
+	min, max, percent, level float64
+	window                   int
+}
+
+func liveCleaningFrom(c *gin.Context) (liveCleaning, error) {
+	lc := liveCleaning{method: c.Query("method"), window: defaultCleanWindow}
+	if lc.method == "" || lc.method == "none" || lc.method == "None" {
+		lc.method = ""
+		return lc, nil
+	}
+	if !liveCleaningMethods[lc.method] {
+		return lc, fmt.Errorf("unknown cleaning method: %s", lc.method)
+	}
+	for _, f := range []struct {
+		key string
+		dst *float64
+	}{{"min", &lc.min}, {"max", &lc.max}, {"percent", &lc.percent}, {"lvl", &lc.level}} {
+		if v := c.Query(f.key); v != "" {
+			x, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return lc, fmt.Errorf("%s: %w", f.key, err)
+			}
+			*f.dst = x
+		}
+	}
+	if v := c.Query("window"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 2 {
+			return lc, fmt.Errorf("window must be an integer >= 2")
+		}
+		lc.window = n
+	}
+	return lc, nil
+}
+
+// openStreamState place le curseur sur le dernier point connu des sources (ou
+// maintenant) et, pour le nettoyage, précharge la fenêtre avec l'heure écoulée.
+func openStreamState(ctx context.Context, sources []dboperations.TelemetryRepository, p ingest.Pair, prefill bool) (*streamState, error) {
+	ctx, cancel := context.WithTimeout(ctx, streamQueryTimeout)
+	defer cancel()
+	now := time.Now().UTC()
+	st := &streamState{pair: p, cursor: now}
+	var latest time.Time
+	for _, repo := range sources {
+		last, found, err := repo.LastTime(ctx, p.Device, p.DataSource, now.Add(-lastSeenWindow))
+		if err != nil {
+			return st, err
+		}
+		if found && last.After(latest) {
+			latest = last
+		}
+	}
+	if latest.IsZero() {
+		return st, nil
+	}
+	st.cursor = latest
+	if prefill {
+		for _, repo := range sources {
+			ts, err := repo.ReadRange(ctx, dboperations.TelemetryQuery{
+				Device: p.Device, DataSource: p.DataSource, From: now.Add(-lastSeenWindow), To: latest,
+			})
+			if err != nil {
+				return st, err
+			}
+			st.window = append(st.window, ts.DataSeries...)
+		}
+		sort.Slice(st.window, func(i, j int) bool { return st.window[i].Chron.Before(st.window[j].Chron) })
+	}
+	return st, nil
+}
+
+// poll relit, dans chaque source, les points postérieurs au curseur.
+func (st *streamState) poll(ctx context.Context, sources []dboperations.TelemetryRepository) ([]timeseries.DataUnit, error) {
+	ctx, cancel := context.WithTimeout(ctx, streamQueryTimeout)
+	defer cancel()
+	var dus []timeseries.DataUnit
+	for _, repo := range sources {
+		ts, err := repo.ReadRange(ctx, dboperations.TelemetryQuery{
+			Device: st.pair.Device, DataSource: st.pair.DataSource,
+			From: st.cursor.Add(time.Nanosecond), To: time.Now().UTC(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		dus = append(dus, ts.DataSeries...)
+	}
+	return dus, nil
+}
+
+// advance garde les points postérieurs au curseur (un point vu par le Hub
+// puis relu dans Telemetry n'est envoyé qu'une fois) et applique le nettoyage.
+func (st *streamState) advance(dus []timeseries.DataUnit, lc liveCleaning) (streamEvent, bool) {
+	var fresh []timeseries.DataUnit
+	for _, du := range dus {
+		if du.Chron.After(st.cursor) {
+			fresh = append(fresh, du)
+		}
+	}
+	if len(fresh) == 0 {
+		return streamEvent{}, false
+	}
+	sort.Slice(fresh, func(i, j int) bool { return fresh[i].Chron.Before(fresh[j].Chron) })
+	st.cursor = fresh[len(fresh)-1].Chron
+
+	if lc.method != "" {
+		st.window = append(st.window, fresh...)
+		if len(st.window) > lc.window {
+			st.window = append([]timeseries.DataUnit(nil), st.window[len(st.window)-lc.window:]...)
+		}
+		win := timeseries.TimeSeries{DataSeries: append([]timeseries.DataUnit(nil), st.window...)}
+		win.Sort_Deltas_Stats()
+		_, rejected := applyCleaning(&win, lc.method, lc.min, lc.max, lc.percent, lc.level)
+		status := make(map[int64]timeseries.StatusCode, len(rejected.DataSeries))
+		for _, du := range rejected.DataSeries {
+			status[du.Chron.UnixNano()] = du.Status
+		}
+		for i := range fresh {
+			if s, ok := status[fresh[i].Chron.UnixNano()]; ok {
+				fresh[i].Status = s
+			}
+		}
+	}
+
+	ev := streamEvent{Pair: st.pair, Points: make([]streamPoint, len(fresh))}
+	for i, du := range fresh {
+		ev.Points[i] = streamPoint{Time: du.Chron.UTC(), Value: timeseries.JSONFloat64(du.Meas), Status: du.Status}
+	}
+	return ev, true
+}
+
+func telemetryRowsByPair(rows []dboperations.Telemetry) map[ingest.Pair][]timeseries.DataUnit {
+	out := make(map[ingest.Pair][]timeseries.DataUnit)
+	for _, r := range rows {
+		p := ingest.Pair{Device: r.Device.String(), DataSource: r.Datasource}
+		out[p] = append(out[p], r.DataUnit())
+	}
+	return out
+}
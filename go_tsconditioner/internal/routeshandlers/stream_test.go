@@ -0,0 +1,128 @@
+package routeshandlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/ingest"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestStreamTelemetrySSE(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo, err := dboperations.OpenSQLiteTelemetry(filepath.Join(t.TempDir(), "telemetry.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	// table où vont les points poussés quand Telemetry est distante (Postgres)
+	local, err := dboperations.OpenSQLiteTelemetry(filepath.Join(t.TempDir(), "local.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer local.Close()
+
+	dev := uuid.New()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	row := func(at time.Time, v float64) dboperations.Telemetry {
+		return dboperations.Telemetry{Id: uuid.New(), Time: at, Device: dev, Value: v, Datasource: "power", State: "OK"}
+	}
+	ctx := context.Background()
+	if err := repo.Insert(ctx, []dboperations.Telemetry{row(now.Add(-time.Minute), 10), row(now.Add(-30*time.Second), 12)}); err != nil {
+		t.Fatal(err)
+	}
+
+	hub := ingest.NewHub()
+	router := gin.New()
+	router.GET("/stream", StreamTelemetry(repo, local, hub))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(reqCtx, http.MethodGet,
+		srv.URL+"/stream?interval=1&method=fixedOutbounds&min=0&max=100&pair="+strings.ToUpper(dev.String())+"/power", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("stream: %d %v", resp.StatusCode, resp.Header)
+	}
+
+	events := make(chan streamEvent)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		name := ""
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case strings.HasPrefix(line, "event:"):
+				name = strings.TrimPrefix(line, "event:")
+			case strings.HasPrefix(line, "data:") && name == "points":
+				var ev streamEvent
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &ev); err == nil {
+					events <- ev
+				}
+			}
+		}
+		close(events)
+	}()
+	next := func() streamEvent {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatal("stream closed")
+			}
+			return ev
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+		}
+		return streamEvent{}
+	}
+
+	// 1) ingestion locale : diffusée par le Hub sans attendre la relecture
+	tracked := ingest.Tracking(repo, hub)
+	if err := tracked.Insert(ctx, []dboperations.Telemetry{row(now.Add(-20*time.Second), 15)}); err != nil {
+		t.Fatal(err)
+	}
+	ev := next()
+	if ev.Device != dev.String() || len(ev.Points) != 1 || ev.Points[0].Value != 15 || ev.Points[0].Status.String() != "StOK" {
+		t.Fatalf("hub event: %+v", ev)
+	}
+
+	// 2) écrite par un autre système : relue au tick suivant, et hors bornes
+	if err := repo.Insert(ctx, []dboperations.Telemetry{row(now.Add(-10*time.Second), 500)}); err != nil {
+		t.Fatal(err)
+	}
+	ev = next()
+	if len(ev.Points) != 1 || ev.Points[0].Value != 500 || ev.Points[0].Status.String() != "StOutlier" {
+		t.Fatalf("polled event: %+v", ev)
+	}
+
+	// 3) lot écrit localement mais non remis par le Hub (abonné saturé) :
+	// rattrapé en relisant la table locale
+	if err := local.Insert(ctx, []dboperations.Telemetry{row(now.Add(-5*time.Second), 20)}); err != nil {
+		t.Fatal(err)
+	}
+	ev = next()
+	if len(ev.Points) != 1 || ev.Points[0].Value != 20 {
+		t.Fatalf("local catch-up event: %+v", ev)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream?pair=nodatasource", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("bad pair: %d", w.Code)
+	}
+}
@@ -0,0 +1,53 @@
+package routeshandlers
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go_tsconditioner/internal/config"
+	"log"
+	"net/http"
+)
+
+// StatusClientClosedRequest : code non standard (nginx) pour une requête abandonnée par le client.
+const StatusClientClosedRequest = 499
+
+// QueryTimeout borne le contexte de la requête selon la route ; toutes les
+// lectures DB passent c.Request.Context() et s'arrêtent donc à l'échéance
+// ou quand le navigateur abandonne la requête.
+func QueryTimeout(timeouts config.QueryTimeouts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d := timeouts.For(c.Request.Method, c.FullPath())
+		if d <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// abortedQueryJSON répond 499 (client parti) ou 504 (timeout) si err ou le contexte
+// de la requête signalent une annulation, et renvoie true dans ce cas.
+// Le driver PostgreSQL ne renvoie pas toujours l'erreur du contexte, d'où la
+// vérification de c.Request.Context().Err().
+func abortedQueryJSON(c *gin.Context, err error) bool {
+	ctxErr := c.Request.Context().Err()
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctxErr, context.DeadlineExceeded):
+		log.Printf("⏱️ Requête DB expirée (%s %s): %v", c.Request.Method, c.FullPath(), err)
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error": "La requête a dépassé le délai autorisé",
+		})
+		return true
+	case errors.Is(err, context.Canceled) || errors.Is(ctxErr, context.Canceled):
+		log.Printf("🚫 Requête DB annulée par le client (%s %s)", c.Request.Method, c.FullPath())
+		c.JSON(StatusClientClosedRequest, gin.H{
+			"error": "Requête annulée par le client",
+		})
+		return true
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package routeshandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go_tsconditioner/internal/config"
+	"go_tsconditioner/internal/dboperations"
+	"go_tsconditioner/internal/types"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestQueryTimeoutsFor(t *testing.T) {
+	tt := config.QueryTimeouts{DefaultSeconds: 30, Routes: map[string]int{
+		"POST /timeseries/remotedata": 120,
+		"GET /timeseries/saved":       0,
+	}}
+	if d := tt.For("POST", "/timeseries/remotedata"); d != 2*time.Minute {
+		t.Fatalf("route override: %s", d)
+	}
+	if d := tt.For("GET", "/timeseries/today/:device"); d != 30*time.Second {
+		t.Fatalf("default: %s", d)
+	}
+	if d := tt.For("GET", "/timeseries/saved"); d != 0 {
+		t.Fatalf("disabled: %s", d)
+	}
+	// le flux SSE n'a jamais de timeout, même absent du fichier ou configuré
+	if d := tt.For("GET", "/timeseries/stream"); d != 0 {
+		t.Fatalf("stream with default: %s", d)
+	}
+	tt.Routes["GET /timeseries/stream"] = 60
+	if d := tt.For("GET", "/timeseries/stream"); d != 0 {
+		t.Fatalf("stream with explicit timeout: %s", d)
+	}
+}
+
+func TestCancelledQueriesMapTo499And504(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo, err := dboperations.OpenSQLiteTelemetry(filepath.Join(t.TempDir(), "telemetry.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	router := gin.New()
+	router.Use(QueryTimeout(config.DefaultQueryTimeouts()))
+	router.POST("/timeseries/remotedata", OneDeviceOneDataSource(repo, nil))
+
+	body, _ := json.Marshal(types.OneDeviceOneDatasourceRequest{
+		Device: "dev", DataSource: "power", From: time.Now().Add(-time.Hour), To: time.Now(),
+	})
+	do := func(ctx context.Context) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/timeseries/remotedata", bytes.NewReader(body)).WithContext(ctx)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if code := do(cancelled); code != StatusClientClosedRequest {
+		t.Fatalf("cancelled: got %d, want 499", code)
+	}
+
+	expired, cancel2 := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel2()
+	if code := do(expired); code != http.StatusGatewayTimeout {
+		t.Fatalf("expired: got %d, want 504", code)
+	}
+
+	if code := do(context.Background()); code != http.StatusNotFound {
+		t.Fatalf("no data: got %d, want 404", code)
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"go_tsconditioner/internal/dboperations"
-	"go_tsconditioner/internal/types"
+	"go_tsconditioner/internal/registry"
 	"time"
 )
 
-func TodayContainer(db *sqlx.DB, cachedDevices *[]types.Device) gin.HandlerFunc {
+// TodayContainer renvoie la journée en cours de toutes les datasources du device,
+// chaque série portant l'unité de sa fiche au catalogue.
+func TodayContainer(repo dboperations.TelemetryRepository, reg *registry.Registry, catalogDB *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		deviceStr := c.Param("device")
 		deviceID, err := uuid.Parse(deviceStr)
@@ -20,13 +22,29 @@ func TodayContainer(db *sqlx.DB, cachedDevices *[]types.Device) gin.HandlerFunc
 
 		loc, _ := time.LoadLocation("Europe/Luxembourg")
 
-		container, err := dboperations.BuildTodayTsContainerForDevice(db, *cachedDevices, deviceID, loc)
+		container, err := dboperations.BuildTodayTsContainerForDevice(c.Request.Context(), repo, reg.Devices(), deviceID, loc)
+		if abortedQueryJSON(c, err) {
+			return
+		}
 		if err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(200, container.ToJSON())
+		if catalogDB != nil {
+			metas, err := dboperations.ListCatalog(c.Request.Context(), catalogDB, deviceStr)
+			if err != nil {
+				catalogErrorJSON(c, err)
+				return
+			}
+			for _, m := range metas {
+				if ts, ok := container.Ts[m.DataSource]; ok && ts != nil {
+					ts.Unit = m.Unit
+				}
+			}
+		}
+
+		containerResponse(c, container.ToJSON())
 
 	}
 }
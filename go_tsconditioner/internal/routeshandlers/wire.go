@@ -0,0 +1,46 @@
+package routeshandlers
+
+import (
+	"go_tsconditioner/internal/timeseries"
+	"go_tsconditioner/internal/tswire"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// wantsTSW : le client demande le format binaire (Accept, voir tswire/README.md).
+// JSON reste le défaut, y compris pour Accept: */*.
+func wantsTSW(c *gin.Context) bool {
+	c.Header("Vary", "Accept")
+	return c.NegotiateFormat(gin.MIMEJSON, tswire.MediaType) == tswire.MediaType
+}
+
+// seriesResponse répond 200 avec s, en JSON ou en TSW selon Accept.
+func seriesResponse(c *gin.Context, s *timeseries.TimeSeriesJSON) {
+	if !wantsTSW(c) {
+		c.JSON(http.StatusOK, s)
+		return
+	}
+	b, err := tswire.EncodeSeries(s)
+	tswResponse(c, b, err)
+}
+
+// containerResponse répond 200 avec tsc, en JSON ou en TSW selon Accept.
+func containerResponse(c *gin.Context, tsc *timeseries.TsContainerJSON) {
+	if !wantsTSW(c) {
+		c.JSON(http.StatusOK, tsc)
+		return
+	}
+	b, err := tswire.EncodeContainer(tsc)
+	tswResponse(c, b, err)
+}
+
+func tswResponse(c *gin.Context, b []byte, err error) {
+	if err != nil {
+		log.Printf("❌ Erreur encodage TSW: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, tswire.MediaType, b)
+}
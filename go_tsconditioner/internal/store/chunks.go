@@ -0,0 +1,190 @@
+package store
+
+import (
+	"fmt"
+	"go_tsconditioner/internal/gorilla"
+	"go_tsconditioner/internal/timeseries"
+	"math"
+	"time"
+	"unsafe"
+)
+
+// DefaultChunkPoints : points par bloc quand la compression est activée sans taille.
+const DefaultChunkPoints = 4096
+
+// Packed est une série rangée en blocs compressés (internal/gorilla) : temps
+// et Dchron en delta-of-delta, Meas et Dmeas en XOR, statuts en plages. Les
+// métadonnées et les BasicStats restent en clair. Chaque bloc repart de zéro
+// et se décode seul.
+//
+// Quand Dchron et Dmeas sont les écarts au point précédent (cas de
+// Sort_Deltas_Stats), ils ne sont pas stockés mais recalculés au décodage :
+// des écarts de mesures bruitées se compressent mal en XOR.
+type Packed struct {
+	head   timeseries.TimeSeries // sans DataSeries
+	loc    *time.Location        // fuseau commun des Chron, nil s'ils diffèrent
+	n      int
+	chunks []chunk
+}
+
+type chunk struct {
+	n                                  int
+	chron, meas, dchron, dmeas, status []byte
+	// dchron (resp. dmeas) nil : écarts recalculés, sauf celui du premier point
+	dchron0 time.Duration
+	dmeas0  float64
+}
+
+// Pack compresse ts par blocs de chunkPoints points (DefaultChunkPoints si <= 0).
+// ts n'est pas modifiée.
+func Pack(ts *timeseries.TimeSeries, chunkPoints int) *Packed {
+	if chunkPoints <= 0 {
+		chunkPoints = DefaultChunkPoints
+	}
+	p := &Packed{head: *ts, n: len(ts.DataSeries), loc: commonLocation(ts.DataSeries)}
+	p.head.DataSeries = nil
+
+	chron := make([]time.Time, 0, min(chunkPoints, p.n))
+	meas := make([]float64, 0, cap(chron))
+	dchron := make([]int64, 0, cap(chron))
+	dmeas := make([]float64, 0, cap(chron))
+	status := make([]byte, 0, cap(chron))
+	for start := 0; start < p.n; start += chunkPoints {
+		dus := ts.DataSeries[start:min(start+chunkPoints, p.n)]
+		chron, meas, dchron, dmeas, status = chron[:0], meas[:0], dchron[:0], dmeas[:0], status[:0]
+		for _, du := range dus {
+			chron = append(chron, du.Chron)
+			meas = append(meas, du.Meas)
+			dchron = append(dchron, int64(du.Dchron))
+			dmeas = append(dmeas, du.Dmeas)
+			status = append(status, byte(du.Status))
+		}
+		c := chunk{
+			n:       len(dus),
+			chron:   gorilla.EncodeTimes(chron),
+			meas:    gorilla.EncodeFloats(meas),
+			status:  gorilla.EncodeRuns(status),
+			dchron0: dus[0].Dchron,
+			dmeas0:  dus[0].Dmeas,
+		}
+		derivedChron, derivedMeas := derivedDeltas(dus)
+		if !derivedChron {
+			c.dchron = gorilla.EncodeInts(dchron)
+		}
+		if !derivedMeas {
+			c.dmeas = gorilla.EncodeFloats(dmeas)
+		}
+		p.chunks = append(p.chunks, c)
+	}
+	return p
+}
+
+// derivedDeltas : Dchron et Dmeas des points après le premier valent-ils
+// exactement les écarts au point précédent, tels que appendTo les recalcule ?
+func derivedDeltas(dus []timeseries.DataUnit) (chron, meas bool) {
+	chron, meas = true, true
+	for i := 1; i < len(dus) && (chron || meas); i++ {
+		prev, cur := dus[i-1], dus[i]
+		if cur.Dchron != cur.Chron.Round(0).Sub(prev.Chron.Round(0)) { // sans horloge monotone, comme après décodage
+			chron = false
+		}
+		if math.Float64bits(cur.Dmeas) != math.Float64bits(cur.Meas-prev.Meas) {
+			meas = false
+		}
+	}
+	return chron, meas
+}
+
+// commonLocation rend le fuseau partagé par tous les Chron (hors NaDate), nil
+// s'ils en ont plusieurs : on garde alors l'instant, en UTC.
+func commonLocation(dus []timeseries.DataUnit) *time.Location {
+	var loc *time.Location
+	for _, du := range dus {
+		if du.Chron.IsZero() {
+			continue
+		}
+		switch l := du.Chron.Location(); {
+		case loc == nil:
+			loc = l
+		case l != loc:
+			return nil
+		}
+	}
+	return loc
+}
+
+// Len rend le nombre de points.
+func (p *Packed) Len() int { return p.n }
+
+// Size estime la mémoire occupée en octets (blocs compressés et en-tête).
+func (p *Packed) Size() int {
+	size := int(unsafe.Sizeof(*p)) + len(p.head.Name) + len(p.head.Comment) + len(p.head.Unit)
+	for _, c := range p.chunks {
+		size += int(unsafe.Sizeof(c)) + len(c.chron) + len(c.meas) + len(c.dchron) + len(c.dmeas) + len(c.status)
+	}
+	return size
+}
+
+// Unpack décompresse la série entière dans une nouvelle TimeSeries.
+func (p *Packed) Unpack() (*timeseries.TimeSeries, error) {
+	ts := p.head
+	ts.DataSeries = make([]timeseries.DataUnit, 0, p.n)
+	for i, c := range p.chunks {
+		var err error
+		if ts.DataSeries, err = c.appendTo(ts.DataSeries, p.loc); err != nil {
+			return nil, fmt.Errorf("memId %d, chunk %d: %w", p.head.MemId, i, err)
+		}
+	}
+	return &ts, nil
+}
+
+func (c chunk) appendTo(dst []timeseries.DataUnit, loc *time.Location) ([]timeseries.DataUnit, error) {
+	chron, err := gorilla.DecodeTimes(c.chron, c.n)
+	if err != nil {
+		return nil, err
+	}
+	meas, err := gorilla.DecodeFloats(c.meas, c.n)
+	if err != nil {
+		return nil, err
+	}
+	var dchron []int64
+	if c.dchron != nil {
+		if dchron, err = gorilla.DecodeInts(c.dchron, c.n); err != nil {
+			return nil, err
+		}
+	}
+	var dmeas []float64
+	if c.dmeas != nil {
+		if dmeas, err = gorilla.DecodeFloats(c.dmeas, c.n); err != nil {
+			return nil, err
+		}
+	}
+	status, err := gorilla.DecodeRuns(c.status, c.n)
+	if err != nil {
+		return nil, err
+	}
+	for i := range c.n {
+		du := timeseries.DataUnit{Chron: chron[i], Meas: meas[i], Status: timeseries.StatusCode(status[i])}
+		switch {
+		case dchron != nil:
+			du.Dchron = time.Duration(dchron[i])
+		case i == 0:
+			du.Dchron = c.dchron0
+		default:
+			du.Dchron = chron[i].Sub(chron[i-1])
+		}
+		switch {
+		case dmeas != nil:
+			du.Dmeas = dmeas[i]
+		case i == 0:
+			du.Dmeas = c.dmeas0
+		default:
+			du.Dmeas = meas[i] - meas[i-1]
+		}
+		if loc != nil && loc != time.UTC && !du.Chron.IsZero() {
+			du.Chron = du.Chron.In(loc)
+		}
+		dst = append(dst, du)
+	}
+	return dst, nil
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"encoding/json"
+	"go_tsconditioner/internal/timeseries"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// meterSeries : n points au pas de 10 s, valeurs au 1/100 comme un compteur,
+// quelques trous et outliers.
+func meterSeries(n int, loc *time.Location) *timeseries.TimeSeries {
+	rng := rand.New(rand.NewSource(1))
+	start := time.Date(2025, 3, 29, 0, 0, 0, 0, loc)
+	ts := &timeseries.TimeSeries{MemId: 12, Name: "power", Unit: "kW", Comment: "compteur"}
+	v := 20.0
+	for i := range n {
+		v += math.Round(rng.NormFloat64()*10) / 100
+		du := timeseries.NewDataUnit(start.Add(time.Duration(i)*10*time.Second), v)
+		switch {
+		case i%997 == 0:
+			du.Meas, du.Status = math.NaN(), timeseries.StMissing
+		case i%1499 == 0:
+			du.Status = timeseries.StOutlier
+		}
+		ts.DataSeries = append(ts.DataSeries, du)
+	}
+	ts.Sort_Deltas_Stats()
+	return ts
+}
+
+func TestPackRoundTrip(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Luxembourg")
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, ts := range []*timeseries.TimeSeries{
+		meterSeries(10000, loc), // traverse le passage à l'heure d'été
+		meterSeries(1, time.UTC),
+		{Name: "empty"},
+	} {
+		ts.DataSeries = append(ts.DataSeries, timeseries.DataUnit{Dchron: timeseries.NaDuration, Meas: math.Inf(-1)}) // NaDate
+		p := Pack(ts, 1000)
+		got, err := p.Unpack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Len() != len(ts.DataSeries) || len(got.DataSeries) != len(ts.DataSeries) {
+			t.Fatalf("%s: %d points, want %d", ts.Name, len(got.DataSeries), len(ts.DataSeries))
+		}
+		for i, du := range ts.DataSeries {
+			g := got.DataSeries[i]
+			if g.Chron != du.Chron.Round(0) && !(g.Chron.IsZero() && du.Chron.IsZero()) ||
+				math.Float64bits(g.Meas) != math.Float64bits(du.Meas) ||
+				math.Float64bits(g.Dmeas) != math.Float64bits(du.Dmeas) ||
+				g.Dchron != du.Dchron || g.Status != du.Status {
+				t.Fatalf("%s [%d]: %+v, want %+v", ts.Name, i, g, du)
+			}
+		}
+		// même rendu JSON : fuseau, stats et métadonnées conservés
+		want, _ := json.Marshal(ts.ToJSON())
+		have, _ := json.Marshal(got.ToJSON())
+		if string(want) != string(have) {
+			t.Fatalf("%s: JSON differs", ts.Name)
+		}
+	}
+}
+
+func TestStoreCompression(t *testing.T) {
+	s := NewTsStore()
+	plain := meterSeries(100, time.UTC)
+	plain.MemId = NewMemId()
+	s.Save(plain)
+
+	s.SetCompression(0)
+	packed := meterSeries(100, time.UTC)
+	packed.MemId = NewMemId()
+	s.Save(packed)
+
+	if got, ok := s.Get(plain.MemId); !ok || got != plain {
+		t.Fatal("plain entry should be returned as saved")
+	}
+	got, ok := s.Get(packed.MemId)
+	if !ok || got == packed || len(got.DataSeries) != 100 || got.Len != packed.Len {
+		t.Fatalf("packed entry: %v", ok)
+	}
+	// la copie rendue est indépendante du store
+	got.DataSeries[0].Meas = -1
+	if again, _ := s.Get(packed.MemId); again.DataSeries[0].Meas == -1 {
+		t.Fatal("packed entry mutated through a Get copy")
+	}
+	if _, ok := s.Get(0); ok {
+		t.Fatal("unknown memId found")
+	}
+}
+
+const benchPoints = 86400 // une journée à la seconde
+
+// BenchmarkPack rapporte le taux de compression (raw/packed) d'une journée de
+// mesures à la seconde ; DataUnit occupe unsafe.Sizeof(DataUnit{}) octets.
+func BenchmarkPack(b *testing.B) {
+	ts := meterSeries(benchPoints, time.UTC)
+	raw := benchPoints * int(unsafe.Sizeof(timeseries.DataUnit{}))
+	b.SetBytes(int64(raw))
+	b.ReportAllocs()
+	var p *Packed
+	for b.Loop() {
+		p = Pack(ts, DefaultChunkPoints)
+	}
+	b.ReportMetric(float64(raw)/float64(p.Size()), "ratio")
+	b.ReportMetric(float64(p.Size())*8/benchPoints, "bits/point")
+}
+
+// BenchmarkUnpack mesure le débit de décompression, en octets de DataUnit produits.
+func BenchmarkUnpack(b *testing.B) {
+	p := Pack(meterSeries(benchPoints, time.UTC), DefaultChunkPoints)
+	b.SetBytes(int64(benchPoints * int(unsafe.Sizeof(timeseries.DataUnit{}))))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := p.Unpack(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(benchPoints)*float64(b.N)/b.Elapsed().Seconds()/1e6, "Mpoints/s")
+}
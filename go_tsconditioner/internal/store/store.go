@@ -1,28 +1,264 @@
 package store
 
 import (
+	"container/list"
+	"context"
 	"go_tsconditioner/internal/timeseries"
+	"log"
 	"sync"
+	"time"
+	"unsafe"
 )
 
+// TsStore garde les séries en mémoire par memId. Avec SetCompression, les
+// séries sauvegardées ensuite sont rangées compressées (Packed) et Get en
+// rend une copie décompressée ; sinon Get rend la série sauvegardée elle-même.
+//
+// Avec SetLimits, une série non lue depuis ttl expire, et au-delà du budget
+// mémoire les séries les moins récemment utilisées (Save ou Get) sont
+// évincées. Les tailles sont estimées à la sauvegarde (Entry.Bytes).
 type TsStore struct {
-	mu   sync.RWMutex
-	data map[uint64]*timeseries.TimeSeries
+	mu          sync.Mutex
+	data        map[uint64]*entry
+	lru         *list.List // de *entry, la plus récemment utilisée devant
+	chunkPoints int        // 0 : pas de compression
+	ttl         time.Duration
+	budget      int64 // octets, 0 : pas de limite
+	used        int64
+	evicted     int
+	now         func() time.Time
 }
 
-var GlobalTsStore = &TsStore{
-	data: make(map[uint64]*timeseries.TimeSeries),
+// entry : une seule des deux formes, ts ou packed, est renseignée.
+type entry struct {
+	ts         *timeseries.TimeSeries
+	packed     *Packed
+	id         uint64
+	bytes      int64
+	saved      time.Time
+	lastAccess time.Time
+	ttl        time.Duration // fixé à la sauvegarde, 0 : sans expiration
+	elem       *list.Element
+}
+
+// Entry décrit une série du store (route GET /timeseries/store).
+type Entry struct {
+	MemId            uint64  `json:"id"`
+	Name             string  `json:"name"`
+	Unit             string  `json:"unit,omitempty"`
+	Points           int     `json:"points"`
+	Bytes            int64   `json:"bytes"`
+	Compressed       bool    `json:"compressed"`
+	AgeSeconds       float64 `json:"ageSeconds"`
+	IdleSeconds      float64 `json:"idleSeconds"`
+	ExpiresInSeconds float64 `json:"expiresInSeconds,omitempty"` // absent sans TTL
+}
+
+// Usage résume l'occupation du store.
+type Usage struct {
+	Entries     int   `json:"entries"`
+	Bytes       int64 `json:"bytes"`
+	BudgetBytes int64 `json:"budgetBytes,omitempty"`
+	TTLSeconds  int64 `json:"ttlSeconds,omitempty"`
+	Evicted     int   `json:"evicted"` // expirées ou évincées depuis le démarrage
+}
+
+var GlobalTsStore = NewTsStore()
+
+func NewTsStore() *TsStore {
+	return &TsStore{data: make(map[uint64]*entry), lru: list.New(), now: time.Now}
+}
+
+// SetCompression active la compression par blocs de chunkPoints points pour
+// les prochains Save (DefaultChunkPoints si 0), ou la désactive si
+// chunkPoints < 0. Les séries déjà rangées gardent leur forme.
+func (s *TsStore) SetCompression(chunkPoints int) {
+	if chunkPoints == 0 {
+		chunkPoints = DefaultChunkPoints
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chunkPoints = max(chunkPoints, 0)
+}
+
+// SetLimits fixe le TTL des prochaines séries sauvegardées (0 : sans
+// expiration) et le budget mémoire en octets (0 : illimité), appliqué tout
+// de suite.
+func (s *TsStore) SetLimits(ttl time.Duration, budget int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttl, s.budget = max(ttl, 0), max(budget, 0)
+	s.evictOverBudget()
 }
 
 func (s *TsStore) Save(ts *timeseries.TimeSeries) {
+	s.mu.Lock()
+	chunkPoints := s.chunkPoints
+	s.mu.Unlock()
+
+	e := &entry{ts: ts, id: ts.MemId}
+	if chunkPoints > 0 {
+		e = &entry{packed: Pack(ts, chunkPoints), id: ts.MemId} // hors verrou : la compression est le plus long
+		e.bytes = int64(e.packed.Size())
+	} else {
+		e.bytes = seriesSize(ts)
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.data[ts.MemId] = ts
+	if old, ok := s.data[e.id]; ok {
+		s.remove(old)
+	}
+	e.saved = s.now()
+	e.lastAccess, e.ttl = e.saved, s.ttl
+	e.elem = s.lru.PushFront(e)
+	s.data[e.id] = e
+	s.used += e.bytes
+	s.evictOverBudget()
 }
 
 func (s *TsStore) Get(id uint64) (*timeseries.TimeSeries, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	ts, ok := s.data[id]
-	return ts, ok
+	s.mu.Lock()
+	e, ok := s.data[id]
+	if ok && s.expired(e, s.now()) {
+		s.remove(e)
+		s.evicted++
+		ok = false
+	}
+	if ok {
+		e.lastAccess = s.now()
+		s.lru.MoveToFront(e.elem)
+	}
+	s.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	if e.packed == nil {
+		return e.ts, true
+	}
+	ts, err := e.packed.Unpack()
+	if err != nil {
+		log.Printf("❌ Erreur décompression TsStore: %v", err)
+		return nil, false
+	}
+	return ts, true
+}
+
+// Delete retire la série id ; false si elle n'y était pas.
+func (s *TsStore) Delete(id uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.data[id]
+	if ok {
+		s.remove(e)
+	}
+	return ok
+}
+
+// List retire les séries expirées puis décrit les autres, de la plus
+// récemment utilisée à la moins récente, sans les décompresser ni modifier
+// leur dernier accès.
+func (s *TsStore) List() ([]Entry, Usage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.purge(now)
+	out := make([]Entry, 0, len(s.data))
+	for el := s.lru.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*entry)
+		info := Entry{
+			MemId:       e.id,
+			Bytes:       e.bytes,
+			Compressed:  e.packed != nil,
+			AgeSeconds:  now.Sub(e.saved).Seconds(),
+			IdleSeconds: now.Sub(e.lastAccess).Seconds(),
+		}
+		if e.packed != nil {
+			info.Name, info.Unit, info.Points = e.packed.head.Name, e.packed.head.Unit, e.packed.Len()
+		} else {
+			info.Name, info.Unit, info.Points = e.ts.Name, e.ts.Unit, len(e.ts.DataSeries)
+		}
+		if e.ttl > 0 {
+			info.ExpiresInSeconds = e.lastAccess.Add(e.ttl).Sub(now).Seconds()
+		}
+		out = append(out, info)
+	}
+	return out, Usage{
+		Entries:     len(out),
+		Bytes:       s.used,
+		BudgetBytes: s.budget,
+		TTLSeconds:  int64(s.ttl / time.Second),
+		Evicted:     s.evicted,
+	}
+}
+
+// Purge retire les séries expirées et rend leur nombre.
+func (s *TsStore) Purge() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.purge(s.now())
+}
+
+func (s *TsStore) purge(now time.Time) int {
+	n := 0
+	// l'ordre LRU ne suffit pas : le TTL est propre à chaque série
+	for el := s.lru.Back(); el != nil; {
+		e, prev := el.Value.(*entry), el.Prev()
+		if s.expired(e, now) {
+			s.remove(e)
+			n++
+		}
+		el = prev
+	}
+	s.evicted += n
+	return n
+}
+
+// Run purge les séries expirées toutes les every jusqu'à l'annulation de ctx.
+func (s *TsStore) Run(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if n := s.Purge(); n > 0 {
+				log.Printf("🧹 TsStore : %d série(s) expirée(s) retirée(s)", n)
+			}
+		}
+	}
+}
+
+func (s *TsStore) expired(e *entry, now time.Time) bool {
+	return e.ttl > 0 && now.Sub(e.lastAccess) > e.ttl
+}
+
+// evictOverBudget retire les séries les moins récemment utilisées tant que le
+// budget est dépassé ; la dernière sauvegardée reste, même seule hors budget.
+func (s *TsStore) evictOverBudget() {
+	if s.budget <= 0 {
+		return
+	}
+	n := 0
+	for s.used > s.budget && s.lru.Len() > 1 {
+		s.remove(s.lru.Back().Value.(*entry))
+		n++
+	}
+	if n > 0 {
+		s.evicted += n
+		log.Printf("♻️ TsStore : %d série(s) évincée(s) (budget %d octets, utilisés %d)", n, s.budget, s.used)
+	}
+}
+
+func (s *TsStore) remove(e *entry) {
+	s.lru.Remove(e.elem)
+	delete(s.data, e.id)
+	s.used -= e.bytes
+}
+
+// seriesSize estime la mémoire d'une série non compressée.
+func seriesSize(ts *timeseries.TimeSeries) int64 {
+	return int64(unsafe.Sizeof(*ts)) + int64(len(ts.Name)+len(ts.Comment)+len(ts.Unit)) +
+		int64(cap(ts.DataSeries))*int64(unsafe.Sizeof(timeseries.DataUnit{}))
 }
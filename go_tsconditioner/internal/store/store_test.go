@@ -0,0 +1,130 @@
+package store
+
+import (
+	"go_tsconditioner/internal/timeseries"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// fakeClock remplace time.Now dans le store.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+func newClockedStore() (*TsStore, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := NewTsStore()
+	s.now = clock.now
+	return s, clock
+}
+
+func saveSeries(s *TsStore, points int) uint64 {
+	ts := meterSeries(points, time.UTC)
+	ts.MemId = NewMemId()
+	s.Save(ts)
+	return ts.MemId
+}
+
+func TestStoreTTL(t *testing.T) {
+	s, clock := newClockedStore()
+	s.SetLimits(time.Hour, 0)
+	idle := saveSeries(s, 10)
+	read := saveSeries(s, 10)
+
+	clock.advance(40 * time.Minute)
+	if _, ok := s.Get(read); !ok {
+		t.Fatal("read series expired early")
+	}
+	clock.advance(30 * time.Minute)
+	// idle : 70 min sans lecture ; read : lue il y a 30 min
+	if _, ok := s.Get(idle); ok {
+		t.Fatal("idle series should have expired")
+	}
+	entries, usage := s.List()
+	if len(entries) != 1 || entries[0].MemId != read || usage.Evicted != 1 {
+		t.Fatalf("list: %+v %+v", entries, usage)
+	}
+	if e := entries[0]; e.AgeSeconds != 70*60 || e.IdleSeconds != 30*60 || e.ExpiresInSeconds != 30*60 {
+		t.Fatalf("ages: %+v", e)
+	}
+
+	clock.advance(time.Hour)
+	if n := s.Purge(); n != 1 {
+		t.Fatalf("purge: %d", n)
+	}
+	if _, usage := s.List(); usage.Entries != 0 || usage.Bytes != 0 {
+		t.Fatalf("after purge: %+v", usage)
+	}
+}
+
+func TestStoreBudgetLRU(t *testing.T) {
+	s, clock := newClockedStore()
+	a := saveSeries(s, 1000)
+	size := s.data[a].bytes
+	s.SetLimits(0, 3*size+size/2) // trois séries de cette taille
+
+	clock.advance(time.Second)
+	b := saveSeries(s, 1000)
+	clock.advance(time.Second)
+	c := saveSeries(s, 1000)
+	clock.advance(time.Second)
+	s.Get(a) // a redevient la plus récente : b est la moins récemment utilisée
+	clock.advance(time.Second)
+	d := saveSeries(s, 1000)
+
+	if _, ok := s.Get(b); ok {
+		t.Fatal("least recently used series was not evicted")
+	}
+	for _, id := range []uint64{a, c, d} {
+		if _, ok := s.Get(id); !ok {
+			t.Fatalf("series %d evicted", id)
+		}
+	}
+	entries, usage := s.List()
+	if len(entries) != 3 || usage.Bytes != 3*size || usage.Evicted != 1 || entries[0].MemId != d {
+		t.Fatalf("list: %+v %+v", entries, usage)
+	}
+
+	// une série plus grosse que le budget reste seule
+	big := saveSeries(s, 10000)
+	if entries, _ := s.List(); len(entries) != 1 || entries[0].MemId != big {
+		t.Fatalf("oversized series: %+v", entries)
+	}
+
+	// resserrer le budget évince tout de suite
+	saveSeries(s, 10)
+	s.SetLimits(0, 1)
+	if entries, _ := s.List(); len(entries) != 1 {
+		t.Fatalf("after SetLimits: %+v", entries)
+	}
+}
+
+func TestStoreDeleteAndSizes(t *testing.T) {
+	s := NewTsStore()
+	plain := saveSeries(s, 1000)
+	s.SetCompression(0)
+	packed := saveSeries(s, 1000)
+
+	entries, usage := s.List()
+	if len(entries) != 2 || usage.Bytes != entries[0].Bytes+entries[1].Bytes {
+		t.Fatalf("list: %+v %+v", entries, usage)
+	}
+	byId := map[uint64]Entry{entries[0].MemId: entries[0], entries[1].MemId: entries[1]}
+	if p, r := byId[packed], byId[plain]; !p.Compressed || r.Compressed || p.Points != 1000 || r.Points != 1000 || p.Bytes*4 > r.Bytes {
+		t.Fatalf("sizes: packed %+v, plain %+v", p, r)
+	}
+	if r := byId[plain]; r.Bytes < 1000*int64(unsafe.Sizeof(timeseries.DataUnit{})) {
+		t.Fatalf("plain size: %d", r.Bytes)
+	}
+
+	if !s.Delete(plain) || s.Delete(plain) {
+		t.Fatal("delete")
+	}
+	if _, ok := s.Get(plain); ok {
+		t.Fatal("deleted series still found")
+	}
+	if _, usage := s.List(); usage.Entries != 1 || usage.Bytes != byId[packed].Bytes {
+		t.Fatalf("after delete: %+v", usage)
+	}
+}
@@ -109,6 +109,8 @@ func AggSlope(local []float64) float64 {
 // AggIntegral renvoie un agrégateur qui calcule l'aire simple
 // somme(v) * freq.Seconds() (ou un autre dt si tu préfères).
 // Les NaN sont ignorés.
+// Suppose une grille régulière (un point par freq) : sur des données
+// irrégulières, utiliser TimeSeries.Integrate.
 func AggIntegral(freq time.Duration) AggFunc {
 	dt := freq.Seconds()
 	return func(local []float64) float64 {
@@ -122,6 +124,18 @@ func AggIntegral(freq time.Duration) AggFunc {
 	}
 }
 
+// Unités de puissance et énergie obtenue en les intégrant sur des secondes.
+var energyOfPowerSeconds = map[string]string{"W": "J", "kW": "kJ", "MW": "MJ"}
+
+// AggIntegralUnit renvoie l'unité des valeurs produites par AggIntegral sur une
+// série en unit (unit·s) : J, kJ ou MJ pour une puissance, vide sinon.
+func AggIntegralUnit(unit string) string {
+	if u, ok := LookupUnit(unit); ok {
+		return energyOfPowerSeconds[u.Symbol]
+	}
+	return ""
+}
+
 // AggIncrementalCounter renvoie une AggFunc qui, pour chaque fenêtre,
 // retourne la consommation de cette fenêtre en calculant
 // last(window) - last(window_précédente).
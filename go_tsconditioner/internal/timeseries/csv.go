@@ -0,0 +1,454 @@
+package timeseries
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Special TimeFormat values for numeric epoch timestamps.
+const (
+	TimeFormatUnix   = "unix"   // seconds since epoch
+	TimeFormatUnixMs = "unixms" // milliseconds since epoch
+)
+
+// CSVOptions configures the CSV codecs. The zero value reads and writes
+// comma-separated RFC 3339 timestamps in UTC with a decimal point.
+type CSVOptions struct {
+	Delimiter    rune           // field separator, ',' by default (';' with DecimalComma)
+	TimeFormat   string         // Go layout, TimeFormatUnix or TimeFormatUnixMs; RFC 3339 by default
+	Location     *time.Location // zone for layouts without offset and for writing; UTC by default
+	DecimalComma bool           // "3,14" instead of "3.14" (French locale)
+	NaN          string         // written for NaN values, empty by default
+
+	// Optional columns written by WriteCSV. Readers detect them from the header.
+	Status bool // "status" (StatusCode name)
+	Dchron bool // "dchron_ns" (nanoseconds)
+	Dmeas  bool // "dmeas"
+}
+
+func (o CSVOptions) withDefaults() (CSVOptions, error) {
+	if o.Delimiter == 0 {
+		o.Delimiter = ','
+		if o.DecimalComma {
+			o.Delimiter = ';'
+		}
+	}
+	if o.DecimalComma && o.Delimiter == ',' {
+		return o, errors.New("csv: decimal comma needs a delimiter other than ','")
+	}
+	if o.TimeFormat == "" {
+		o.TimeFormat = time.RFC3339Nano
+	}
+	if o.Location == nil {
+		o.Location = time.UTC
+	}
+	return o, nil
+}
+
+func (o CSVOptions) formatTime(t time.Time) string {
+	switch o.TimeFormat {
+	case TimeFormatUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case TimeFormatUnixMs:
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	default:
+		return t.In(o.Location).Format(o.TimeFormat)
+	}
+}
+
+func (o CSVOptions) parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	switch o.TimeFormat {
+	case TimeFormatUnix, TimeFormatUnixMs:
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("timestamp %q: %w", s, err)
+		}
+		if o.TimeFormat == TimeFormatUnix {
+			return time.Unix(v, 0).UTC(), nil
+		}
+		return time.UnixMilli(v).UTC(), nil
+	default:
+		return time.ParseInLocation(o.TimeFormat, s, o.Location)
+	}
+}
+
+func (o CSVOptions) formatFloat(v float64) string {
+	if math.IsNaN(v) {
+		return o.NaN
+	}
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	if o.DecimalComma {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
+
+// isNaNToken recognises the usual spellings of a missing value.
+func (o CSVOptions) isNaNToken(s string) bool {
+	if o.NaN != "" && s == o.NaN {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "", "nan", "null", "na", "n/a":
+		return true
+	}
+	return false
+}
+
+func (o CSVOptions) parseFloat(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if o.isNaNToken(s) {
+		return math.NaN(), nil
+	}
+	if o.DecimalComma {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func (o CSVOptions) reader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.Comma = o.Delimiter
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+	return cr
+}
+
+// WriteCSV writes the series with a header line: time, meas, then the
+// optional columns selected in opts.
+func (ts *TimeSeries) WriteCSV(w io.Writer, opts CSVOptions) error {
+	o, err := opts.withDefaults()
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = o.Delimiter
+
+	header := []string{"time", "meas"}
+	if o.Status {
+		header = append(header, "status")
+	}
+	if o.Dchron {
+		header = append(header, "dchron_ns")
+	}
+	if o.Dmeas {
+		header = append(header, "dmeas")
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	rec := make([]string, 0, len(header))
+	for _, du := range ts.DataSeries {
+		rec = append(rec[:0], o.formatTime(du.Chron), o.formatFloat(du.Meas))
+		if o.Status {
+			rec = append(rec, du.Status.String())
+		}
+		if o.Dchron {
+			d := ""
+			if du.Dchron != NaDuration {
+				d = strconv.FormatInt(du.Dchron.Nanoseconds(), 10)
+			}
+			rec = append(rec, d)
+		}
+		if o.Dmeas {
+			rec = append(rec, o.formatFloat(du.Dmeas))
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvColumns locates the columns of a single-series header. Unnamed files
+// fall back to time in the first column and meas in the second.
+type csvColumns struct {
+	time, meas, status, dchron, dmeas int
+	measName                          string
+}
+
+func findColumns(header []string) (csvColumns, error) {
+	cols := csvColumns{time: -1, meas: -1, status: -1, dchron: -1, dmeas: -1}
+	for i, h := range header {
+		switch strings.ToLower(strings.TrimSpace(h)) {
+		case "time", "chron", "timestamp", "date":
+			cols.time = i
+		case "meas", "value", "valeur":
+			cols.meas = i
+		case "status":
+			cols.status = i
+		case "dchron_ns", "dchron":
+			cols.dchron = i
+		case "dmeas":
+			cols.dmeas = i
+		}
+	}
+	if cols.time < 0 {
+		cols.time = 0
+	}
+	if cols.meas < 0 {
+		for i := range header {
+			if i != cols.time && i != cols.status && i != cols.dchron && i != cols.dmeas {
+				cols.meas = i
+				break
+			}
+		}
+	}
+	if cols.meas < 0 {
+		return cols, errors.New("csv: no measurement column")
+	}
+	cols.measName = strings.TrimSpace(header[cols.meas])
+	return cols, nil
+}
+
+// ReadCSV reads a series written by WriteCSV or any file with a header line
+// holding a time column and a measurement column ("meas" or "value", else
+// the first column that is not time). Empty and "NaN"-like cells give NaN
+// with Status StMissing unless a status column says otherwise. The series
+// is named after the measurement column and returned in file order.
+func ReadCSV(r io.Reader, opts CSVOptions) (TimeSeries, error) {
+	o, err := opts.withDefaults()
+	if err != nil {
+		return TimeSeries{}, err
+	}
+	cr := o.reader(r)
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return TimeSeries{}, fmt.Errorf("csv: %w", ErrEmptyInput)
+	}
+	if err != nil {
+		return TimeSeries{}, fmt.Errorf("csv header: %w", err)
+	}
+	header[0] = strings.TrimPrefix(header[0], utf8BOM)
+	cols, err := findColumns(header)
+	if err != nil {
+		return TimeSeries{}, err
+	}
+
+	ts := TimeSeries{Name: cols.measName}
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return TimeSeries{}, fmt.Errorf("csv line %d: %w", line, err)
+		}
+		du, err := o.parseRow(rec, cols)
+		if err != nil {
+			return TimeSeries{}, fmt.Errorf("csv line %d: %w", line, err)
+		}
+		ts.AddDataUnit(du)
+	}
+	return ts, nil
+}
+
+func (o CSVOptions) parseRow(rec []string, cols csvColumns) (DataUnit, error) {
+	du := DataUnit{Dchron: NaDuration, Dmeas: math.NaN()}
+	var err error
+	if du.Chron, err = o.parseTime(rec[cols.time]); err != nil {
+		return du, err
+	}
+	if du.Meas, err = o.parseFloat(rec[cols.meas]); err != nil {
+		return du, fmt.Errorf("meas %q: %w", rec[cols.meas], err)
+	}
+	if math.IsNaN(du.Meas) {
+		du.Status = StMissing
+	}
+	if cols.status >= 0 && strings.TrimSpace(rec[cols.status]) != "" {
+		if du.Status, err = ParseStatusCode(rec[cols.status]); err != nil {
+			return du, err
+		}
+	}
+	if cols.dchron >= 0 && strings.TrimSpace(rec[cols.dchron]) != "" {
+		ns, err := strconv.ParseInt(strings.TrimSpace(rec[cols.dchron]), 10, 64)
+		if err != nil {
+			return du, fmt.Errorf("dchron_ns %q: %w", rec[cols.dchron], err)
+		}
+		du.Dchron = time.Duration(ns)
+	}
+	if cols.dmeas >= 0 {
+		if du.Dmeas, err = o.parseFloat(rec[cols.dmeas]); err != nil {
+			return du, fmt.Errorf("dmeas %q: %w", rec[cols.dmeas], err)
+		}
+	}
+	return du, nil
+}
+
+// utf8BOM is stripped from the first header cell (spreadsheet exports).
+const utf8BOM = "\ufeff"
+
+// statusSuffix names the optional per-series status columns of the wide format.
+const statusSuffix = "_status"
+
+// WriteCSV writes the container in wide format: a time column, then one
+// column per series (sorted by key, followed by "<key>_status" when
+// opts.Status is set). Rows are the union of all timestamps in ascending
+// order; a series without a point at a timestamp leaves its cell empty.
+func (c *TsContainer) WriteCSV(w io.Writer, opts CSVOptions) error {
+	o, err := opts.withDefaults()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(c.Ts))
+	for k, ts := range c.Ts {
+		if ts != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	byTime := make([]map[int64]DataUnit, len(keys))
+	stamps := make(map[int64]time.Time)
+	for i, k := range keys {
+		byTime[i] = make(map[int64]DataUnit, len(c.Ts[k].DataSeries))
+		for _, du := range c.Ts[k].DataSeries {
+			n := du.Chron.UnixNano()
+			byTime[i][n] = du
+			stamps[n] = du.Chron
+		}
+	}
+	order := make([]int64, 0, len(stamps))
+	for n := range stamps {
+		order = append(order, n)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+
+	cw := csv.NewWriter(w)
+	cw.Comma = o.Delimiter
+	header := []string{"time"}
+	for _, k := range keys {
+		header = append(header, k)
+		if o.Status {
+			header = append(header, k+statusSuffix)
+		}
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	rec := make([]string, 0, len(header))
+	for _, n := range order {
+		rec = append(rec[:0], o.formatTime(stamps[n]))
+		for i := range keys {
+			du, ok := byTime[i][n]
+			switch {
+			case !ok:
+				rec = append(rec, "")
+				if o.Status {
+					rec = append(rec, "")
+				}
+			default:
+				rec = append(rec, o.formatFloat(du.Meas))
+				if o.Status {
+					rec = append(rec, du.Status.String())
+				}
+			}
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadContainerCSV reads the wide format written by TsContainer.WriteCSV:
+// the first column is the time, every other column is a series keyed by its
+// header, and "<key>_status" columns carry the status of series <key>. An
+// empty cell means "no point"; other NaN spellings give a NaN point with
+// Status StMissing. Write NaN values with a non-empty opts.NaN to keep them
+// through a round trip.
+func ReadContainerCSV(r io.Reader, opts CSVOptions) (TsContainer, error) {
+	o, err := opts.withDefaults()
+	if err != nil {
+		return TsContainer{}, err
+	}
+	cr := o.reader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return TsContainer{}, fmt.Errorf("csv: %w", ErrEmptyInput)
+	}
+	if err != nil {
+		return TsContainer{}, fmt.Errorf("csv header: %w", err)
+	}
+	header = append([]string(nil), header...)
+	header[0] = strings.TrimPrefix(header[0], utf8BOM)
+
+	type column struct {
+		key          string
+		meas, status int
+	}
+	var series []column
+	statusOf := make(map[string]int)
+	for i, h := range header[1:] {
+		h = strings.TrimSpace(h)
+		if key, ok := strings.CutSuffix(h, statusSuffix); ok {
+			statusOf[key] = i + 1
+			continue
+		}
+		series = append(series, column{key: h, meas: i + 1, status: -1})
+	}
+	for i := range series {
+		if j, ok := statusOf[series[i].key]; ok {
+			series[i].status = j
+		}
+	}
+	if len(series) == 0 {
+		return TsContainer{}, errors.New("csv: no series column")
+	}
+
+	built := make([]*TimeSeries, len(series))
+	for i, s := range series {
+		built[i] = &TimeSeries{Name: s.key}
+	}
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return TsContainer{}, fmt.Errorf("csv line %d: %w", line, err)
+		}
+		t, err := o.parseTime(rec[0])
+		if err != nil {
+			return TsContainer{}, fmt.Errorf("csv line %d: %w", line, err)
+		}
+		for i, s := range series {
+			cell := strings.TrimSpace(rec[s.meas])
+			if cell == "" {
+				continue
+			}
+			du := DataUnit{Chron: t, Dchron: NaDuration, Dmeas: math.NaN()}
+			if du.Meas, err = o.parseFloat(cell); err != nil {
+				return TsContainer{}, fmt.Errorf("csv line %d, %s %q: %w", line, s.key, cell, err)
+			}
+			if math.IsNaN(du.Meas) {
+				du.Status = StMissing
+			}
+			if s.status >= 0 && strings.TrimSpace(rec[s.status]) != "" {
+				if du.Status, err = ParseStatusCode(rec[s.status]); err != nil {
+					return TsContainer{}, fmt.Errorf("csv line %d: %w", line, err)
+				}
+			}
+			built[i].AddDataUnit(du)
+		}
+	}
+	c := NewTsContainer()
+	for i, s := range series {
+		if err := c.Add(s.key, built[i]); err != nil {
+			return TsContainer{}, err
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,96 @@
+package timeseries
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVRoundTripFrenchLocale(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("tzdata indisponible")
+	}
+	t0 := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	ts := TimeSeries{Name: "temp"}
+	ts.AddData(t0, 12.5)
+	ts.AddDataUnit(DataUnit{Chron: t0.Add(time.Hour), Meas: math.NaN(), Status: StMissing})
+	ts.AddDataUnit(DataUnit{Chron: t0.Add(2 * time.Hour), Meas: 99, Status: StOutlier})
+	ts.DeltasFiller()
+
+	opts := CSVOptions{DecimalComma: true, TimeFormat: "02/01/2006 15:04:05", Location: paris, Status: true, Dmeas: true}
+	var buf bytes.Buffer
+	if err := ts.WriteCSV(&buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[0] != "time;meas;status;dmeas" || lines[1] != "01/03/2025 01:00:00;12,5;StOK;" {
+		t.Fatalf("csv:\n%s", buf.String())
+	}
+
+	back, err := ReadCSV(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(back.DataSeries) != 3 || !back.DataSeries[0].Chron.Equal(t0) || back.DataSeries[0].Meas != 12.5 {
+		t.Fatalf("round trip: %+v", back.DataSeries)
+	}
+	if !math.IsNaN(back.DataSeries[1].Meas) || back.DataSeries[1].Status != StMissing || back.DataSeries[2].Status != StOutlier {
+		t.Fatalf("status: %+v", back.DataSeries)
+	}
+
+	if err := ts.WriteCSV(&buf, CSVOptions{DecimalComma: true, Delimiter: ','}); err == nil {
+		t.Fatal("decimal comma with ',' delimiter accepted")
+	}
+}
+
+func TestReadCSVDefaultsAndErrors(t *testing.T) {
+	in := "\ufeffdate,value\n1735689600,1.5\n1735689660,NA\n"
+	ts, err := ReadCSV(strings.NewReader(in), CSVOptions{TimeFormat: TimeFormatUnix})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.Name != "value" || len(ts.DataSeries) != 2 || ts.DataSeries[1].Status != StMissing {
+		t.Fatalf("%+v", ts)
+	}
+	_, err = ReadCSV(strings.NewReader("time,meas\n2025-01-01T00:00:00Z,abc\n"), CSVOptions{})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("bad value: %v", err)
+	}
+}
+
+func TestContainerCSVWide(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewTsContainer()
+	a, b := &TimeSeries{}, &TimeSeries{}
+	a.AddData(t0, 1)
+	a.AddData(t0.Add(time.Minute), 2)
+	b.AddData(t0.Add(time.Minute), 20)
+	b.AddDataUnit(DataUnit{Chron: t0.Add(2 * time.Minute), Meas: math.NaN(), Status: StMissing})
+	c.Ts["a"], c.Ts["b"] = a, b
+
+	var buf bytes.Buffer
+	if err := c.WriteCSV(&buf, CSVOptions{NaN: "NaN", Status: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := "time,a,a_status,b,b_status\n" +
+		"2025-01-01T00:00:00Z,1,StOK,,\n" +
+		"2025-01-01T00:01:00Z,2,StOK,20,StOK\n" +
+		"2025-01-01T00:02:00Z,,,NaN,StMissing\n"
+	if buf.String() != want {
+		t.Fatalf("wide csv:\n%s", buf.String())
+	}
+
+	back, err := ReadContainerCSV(&buf, CSVOptions{NaN: "NaN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(back.Ts) != 2 || len(back.Ts["a"].DataSeries) != 2 || len(back.Ts["b"].DataSeries) != 2 {
+		t.Fatalf("read back: %+v", back.Ts)
+	}
+	if back.Ts["b"].DataSeries[1].Status != StMissing {
+		t.Fatalf("status column: %+v", back.Ts["b"].DataSeries[1])
+	}
+}
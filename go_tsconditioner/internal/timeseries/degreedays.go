@@ -0,0 +1,201 @@
+package timeseries
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// DegreeDayKind selects heating (base - T) or cooling (T - base) degree-days.
+type DegreeDayKind int
+
+const (
+	HeatingDegreeDays DegreeDayKind = iota
+	CoolingDegreeDays
+)
+
+func (k DegreeDayKind) String() string {
+	switch k {
+	case HeatingDegreeDays:
+		return "HDD"
+	case CoolingDegreeDays:
+		return "CDD"
+	default:
+		return fmt.Sprintf("DegreeDayKind(%d)", int(k))
+	}
+}
+
+// dayStart returns local midnight of the day holding t.
+func dayStart(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// dailyGrid returns every local day start from the day of the first point to
+// the day of the last one (dus sorted), and their index keyed by Unix seconds.
+func dailyGrid(dus []DataUnit, loc *time.Location) ([]time.Time, map[int64]int) {
+	if len(dus) == 0 {
+		return nil, map[int64]int{}
+	}
+	last := dayStart(dus[len(dus)-1].Chron, loc)
+	var days []time.Time
+	idx := make(map[int64]int)
+	for d := dayStart(dus[0].Chron, loc); !d.After(last); d = d.AddDate(0, 0, 1) {
+		idx[d.Unix()] = len(days)
+		days = append(days, d)
+	}
+	return days, idx
+}
+
+// DailyMeans returns one point per local day (stamped at midnight in loc)
+// holding the mean of the usable points of that day. Days without any usable
+// point are NaN with Status StMissing. The mean is taken over samples, so
+// the series should be reasonably regular (regularize it first otherwise).
+func (ts *TimeSeries) DailyMeans(loc *time.Location) TimeSeries {
+	sorted := ts.Copy()
+	sorted.SortChronAsc()
+	days, idx := dailyGrid(sorted.DataSeries, loc)
+
+	sums := make([]float64, len(days))
+	counts := make([]int, len(days))
+	for _, du := range sorted.DataSeries {
+		if !usable(du) {
+			continue
+		}
+		i := idx[dayStart(du.Chron, loc).Unix()]
+		sums[i] += du.Meas
+		counts[i]++
+	}
+
+	out := TimeSeries{Name: ts.Name + " Daily mean", Unit: ts.Unit, DataSeries: make([]DataUnit, 0, len(days))}
+	for i, d := range days {
+		du := DataUnit{Chron: d, Meas: math.NaN(), Dmeas: math.NaN(), Status: StMissing}
+		if counts[i] > 0 {
+			du.Meas = sums[i] / float64(counts[i])
+			du.Status = StOK
+		}
+		out.AddDataUnit(du)
+	}
+	return out
+}
+
+// DailyIncrements turns a cumulative counter into the consumption of each
+// local day: last usable reading of the day minus the last usable reading of
+// the previous day. A day whose baseline is missing (no reading on the day
+// before) is NaN with Status StMissing; a decreasing counter gives NaN with
+// Status StInvalid.
+func (ts *TimeSeries) DailyIncrements(loc *time.Location) TimeSeries {
+	sorted := ts.Copy()
+	sorted.SortChronAsc()
+	days, idx := dailyGrid(sorted.DataSeries, loc)
+
+	lastOfDay := make([]float64, len(days))
+	seen := make([]bool, len(days))
+	for _, du := range sorted.DataSeries {
+		if !usable(du) {
+			continue
+		}
+		i := idx[dayStart(du.Chron, loc).Unix()]
+		lastOfDay[i] = du.Meas
+		seen[i] = true
+	}
+
+	out := TimeSeries{Name: ts.Name + " Daily", Unit: ts.Unit, DataSeries: make([]DataUnit, 0, len(days))}
+	for i, d := range days {
+		du := DataUnit{Chron: d, Meas: math.NaN(), Dmeas: math.NaN(), Status: StMissing}
+		switch {
+		case i == 0 || !seen[i] || !seen[i-1]:
+		case lastOfDay[i] < lastOfDay[i-1]:
+			du.Status = StInvalid
+		default:
+			du.Meas = lastOfDay[i] - lastOfDay[i-1]
+			du.Status = StOK
+		}
+		out.AddDataUnit(du)
+	}
+	return out
+}
+
+// DailyEnergy integrates a power series (see Integrate) and sums the energy
+// of the intervals ending on each local day. A day holding a skipped interval
+// is NaN with Status StMissing, since its total would be understated.
+// Intervals crossing midnight are booked on the day they end.
+func (ts *TimeSeries) DailyEnergy(method IntegrationMethod, maxGap time.Duration, loc *time.Location) (TimeSeries, error) {
+	sorted := ts.Copy()
+	sorted.SortChronAsc()
+	cum, err := sorted.Integrate(method, maxGap)
+	if err != nil {
+		return TimeSeries{}, err
+	}
+	days, idx := dailyGrid(cum.DataSeries, loc)
+
+	sums := make([]float64, len(days))
+	counts := make([]int, len(days))
+	broken := make([]bool, len(days))
+	for k, du := range cum.DataSeries {
+		if k == 0 {
+			continue
+		}
+		i := idx[dayStart(du.Chron, loc).Unix()]
+		if du.Status != StOK {
+			broken[i] = true
+			continue
+		}
+		sums[i] += du.Dmeas
+		counts[i]++
+	}
+
+	out := TimeSeries{Name: ts.Name + " Daily energy", Unit: cum.Unit, DataSeries: make([]DataUnit, 0, len(days))}
+	for i, d := range days {
+		du := DataUnit{Chron: d, Meas: math.NaN(), Dmeas: math.NaN(), Status: StMissing}
+		if counts[i] > 0 && !broken[i] {
+			du.Meas = sums[i]
+			du.Status = StOK
+		}
+		out.AddDataUnit(du)
+	}
+	return out, nil
+}
+
+// DegreeDays computes daily heating or cooling degree-days from an outdoor
+// temperature series: the daily mean (see DailyMeans) against base, in °C.
+// A temperature series carrying another temperature unit is converted to °C
+// first; a unitless one is taken as °C. Days without data are NaN/StMissing.
+func DegreeDays(temp *TimeSeries, kind DegreeDayKind, base float64, loc *time.Location) (TimeSeries, error) {
+	means, err := temp.DailyMeanTemperature(loc)
+	if err != nil {
+		return TimeSeries{}, err
+	}
+	out := TimeSeries{
+		Name:       fmt.Sprintf("%s %g°C", kind, base),
+		Comment:    "degree-days of " + temp.Name,
+		DataSeries: make([]DataUnit, 0, len(means.DataSeries)),
+	}
+	for _, du := range means.DataSeries {
+		if du.Status == StOK {
+			du.Meas = degreeDay(du.Meas, kind, base)
+		}
+		out.AddDataUnit(du)
+	}
+	return out, nil
+}
+
+func degreeDay(tmean float64, kind DegreeDayKind, base float64) float64 {
+	if kind == CoolingDegreeDays {
+		return math.Max(0, tmean-base)
+	}
+	return math.Max(0, base-tmean)
+}
+
+// DailyMeanTemperature is DailyMeans expressed in °C (a unitless series is
+// taken as °C), the input expected by DegreeDays and FitPRISM.
+func (ts *TimeSeries) DailyMeanTemperature(loc *time.Location) (TimeSeries, error) {
+	means := ts.DailyMeans(loc)
+	if ts.Unit == "" || ts.Unit == "°C" {
+		return means, nil
+	}
+	if u, ok := LookupUnit(ts.Unit); ok && u.Dimension != DimTemperature {
+		return TimeSeries{}, fmt.Errorf("%w: %s (%s), temperature expected", ErrIncompatibleUnits, ts.Unit, u.Dimension)
+	}
+	return means.ConvertTo("°C")
+}
@@ -0,0 +1,92 @@
+package timeseries
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Une journée par température, mesurée toutes les 6 h ; consommation
+// journalière = 5 + 2 * HDD(16 °C) sur un compteur cumulatif relevé à 23 h.
+func degreeDayFixture() (temp, counter TimeSeries) {
+	loc := time.UTC
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, loc)
+	temps := []float64{-2, 0, 3, 5, 8, 10, 12, 14, 16, 18, 20, 4, 7, 11, 15, 1}
+	temp = TimeSeries{Name: "outdoor", Unit: "°C"}
+	counter = TimeSeries{Name: "meter", Unit: "kWh"}
+	total := 1000.0
+	counter.AddData(t0.Add(-time.Hour), total)
+	for d, tm := range temps {
+		day := t0.AddDate(0, 0, d)
+		for h := 0; h < 24; h += 6 {
+			temp.AddData(day.Add(time.Duration(h)*time.Hour), tm)
+		}
+		total += 5 + 2*math.Max(0, 16-tm)
+		counter.AddData(day.Add(23*time.Hour), total)
+	}
+	return temp, counter
+}
+
+func TestDegreeDays(t *testing.T) {
+	temp, _ := degreeDayFixture()
+	hdd, err := DegreeDays(&temp, HeatingDegreeDays, 16, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hdd.DataSeries) != 16 || hdd.DataSeries[0].Meas != 18 || hdd.DataSeries[9].Meas != 0 {
+		t.Fatalf("hdd: %d days, first %g, tenth %g", len(hdd.DataSeries), hdd.DataSeries[0].Meas, hdd.DataSeries[9].Meas)
+	}
+	cdd, _ := DegreeDays(&temp, CoolingDegreeDays, 18, time.UTC)
+	if cdd.DataSeries[10].Meas != 2 || cdd.DataSeries[0].Meas != 0 {
+		t.Fatalf("cdd: %g %g", cdd.DataSeries[10].Meas, cdd.DataSeries[0].Meas)
+	}
+
+	// °F converti avant comparaison à la base en °C
+	f, _ := temp.ConvertTo("°F")
+	hddF, err := DegreeDays(&f, HeatingDegreeDays, 16, time.UTC)
+	if err != nil || math.Abs(hddF.DataSeries[0].Meas-18) > 1e-9 {
+		t.Fatalf("°F input: %v %g", err, hddF.DataSeries[0].Meas)
+	}
+}
+
+func TestDailyIncrements(t *testing.T) {
+	_, counter := degreeDayFixture()
+	daily := counter.DailyIncrements(time.UTC)
+	// premier jour : relevé de la veille à 23 h
+	if daily.DataSeries[1].Status != StOK || daily.DataSeries[1].Meas != 5+2*18 {
+		t.Fatalf("first day: %+v", daily.DataSeries[1])
+	}
+	if daily.DataSeries[0].Status != StMissing {
+		t.Fatalf("baseline day: %+v", daily.DataSeries[0])
+	}
+}
+
+func TestFitPRISMFindsBase(t *testing.T) {
+	temp, counter := degreeDayFixture()
+	daily := counter.DailyIncrements(time.UTC)
+	means, _ := temp.DailyMeanTemperature(time.UTC)
+
+	m, err := FitPRISM(&daily, &means, PRISMOptions{Heating: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.BaseHeat != 16 || math.Abs(m.HeatSlope-2) > 1e-6 || math.Abs(m.Intercept-5) > 1e-6 || m.R2 < 0.999 {
+		t.Fatalf("model: %+v", m)
+	}
+	if got := m.PredictFromTemperature(6); math.Abs(got-25) > 1e-6 {
+		t.Fatalf("predict 6 °C: %g", got)
+	}
+	if got := m.Normalized(365, 2000, 0); math.Abs(got-(365*5+2*2000)) > 1e-3 {
+		t.Fatalf("normalized: %g", got)
+	}
+
+	// pas de dépendance au climat : repli sur la moyenne
+	flat := TimeSeries{}
+	for _, du := range daily.DataSeries {
+		flat.AddDataUnit(DataUnit{Chron: du.Chron, Meas: 7, Status: du.Status})
+	}
+	m, err = FitPRISM(&flat, &means, PRISMOptions{Heating: true})
+	if err != nil || m.Heating || m.Intercept != 7 {
+		t.Fatalf("flat: %+v %v", m, err)
+	}
+}
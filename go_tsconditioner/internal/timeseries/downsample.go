@@ -0,0 +1,217 @@
+package timeseries
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// DownsampleMethod selects the visual downsampling operator used to reduce a
+// series before it is sent to a chart. Downsampling never touches the source
+// series: it returns a new, shorter TimeSeries made of original DataUnits.
+type DownsampleMethod int
+
+const (
+	DownsampleNone DownsampleMethod = iota
+	DownsampleLTTB                  // Largest-Triangle-Three-Buckets
+	DownsampleM4                    // first/last/min/max per pixel bucket
+)
+
+func (m DownsampleMethod) String() string {
+	switch m {
+	case DownsampleNone:
+		return "None"
+	case DownsampleLTTB:
+		return "LTTB"
+	case DownsampleM4:
+		return "M4"
+	default:
+		return fmt.Sprintf("DownsampleMethod(%d)", int(m))
+	}
+}
+
+// Downsample returns a copy of ts reduced to at most maxPoints DataUnits with
+// the chosen method. If maxPoints <= 0, method is DownsampleNone or the series
+// is already short enough, the copy holds every DataUnit.
+//
+// The series is expected to be sorted by Chron (see Sort_Deltas_Stats); it is
+// read only, so the stored full-resolution series can be shared safely.
+// The returned series keeps Name, Comment and MemId but has no BasicStats.
+func (ts *TimeSeries) Downsample(method DownsampleMethod, maxPoints int) TimeSeries {
+	out := TimeSeries{MemId: ts.MemId, Name: ts.Name, Comment: ts.Comment, Unit: ts.Unit}
+	if maxPoints <= 0 || method == DownsampleNone || len(ts.DataSeries) <= maxPoints {
+		out.DataSeries = append([]DataUnit(nil), ts.DataSeries...)
+		return out
+	}
+	switch method {
+	case DownsampleLTTB:
+		out.DataSeries = lttb(ts.DataSeries, maxPoints)
+	case DownsampleM4:
+		if maxPoints < 4 {
+			// not even one full pixel bucket: fall back to LTTB
+			out.DataSeries = lttb(ts.DataSeries, maxPoints)
+			break
+		}
+		out.DataSeries = m4(ts.DataSeries, maxPoints/4)
+	default:
+		out.DataSeries = append([]DataUnit(nil), ts.DataSeries...)
+	}
+	return out
+}
+
+// LTTB reduces the series to threshold points with the Largest-Triangle-Three-Buckets
+// algorithm (Steinarsson, 2013). The first and last points are always kept;
+// below 3 points there is no inner bucket and only they are returned (the
+// first one alone for threshold 1).
+//
+// NaN measurements are never selected as triangle vertices; a bucket holding
+// only NaN values contributes its first DataUnit so that gaps stay visible.
+func (ts *TimeSeries) LTTB(threshold int) TimeSeries {
+	return ts.Downsample(DownsampleLTTB, threshold)
+}
+
+// M4 reduces the series to at most 4*pixels points: the time range is split
+// into pixels buckets of equal width and, for each bucket, the first, last,
+// minimum and maximum points are kept in chronological order. Peaks are thus
+// preserved exactly, which makes M4 suitable for line charts rendered at a
+// known pixel width.
+//
+// A bucket holding only NaN values contributes its first DataUnit (gap marker).
+func (ts *TimeSeries) M4(pixels int) TimeSeries {
+	return ts.Downsample(DownsampleM4, 4*pixels)
+}
+
+func lttb(src []DataUnit, threshold int) []DataUnit {
+	n := len(src)
+	switch {
+	case threshold <= 1:
+		return []DataUnit{src[0]}
+	case threshold == 2:
+		return []DataUnit{src[0], src[n-1]}
+	}
+	t0 := src[0].Chron
+	x := func(i int) float64 { return src[i].Chron.Sub(t0).Seconds() }
+
+	out := make([]DataUnit, 0, threshold)
+	out = append(out, src[0])
+	anchor := 0 // last selected point with a valid Meas
+	if math.IsNaN(src[0].Meas) {
+		anchor = -1
+	}
+
+	// the n-2 inner points are split into threshold-2 buckets
+	every := float64(n-2) / float64(threshold-2)
+	for b := 0; b < threshold-2; b++ {
+		lo := int(float64(b)*every) + 1
+		hi := int(float64(b+1)*every) + 1
+
+		// average of the next bucket (the last point for the final bucket)
+		nlo, nhi := hi, int(float64(b+2)*every)+1
+		if nhi > n-1 || b == threshold-3 {
+			nlo, nhi = n-1, n
+		}
+		var ax, ay float64
+		cnt := 0
+		for j := nlo; j < nhi; j++ {
+			if math.IsNaN(src[j].Meas) {
+				continue
+			}
+			ax += x(j)
+			ay += src[j].Meas
+			cnt++
+		}
+
+		best, bestArea := -1, -1.0
+		for j := lo; j < hi; j++ {
+			if math.IsNaN(src[j].Meas) {
+				continue
+			}
+			if anchor < 0 || cnt == 0 {
+				// no triangle available: keep the first valid point
+				best = j
+				break
+			}
+			px, py := x(anchor), src[anchor].Meas
+			cx, cy := ax/float64(cnt), ay/float64(cnt)
+			area := math.Abs((px-cx)*(src[j].Meas-py) - (px-x(j))*(cy-py))
+			if area > bestArea {
+				best, bestArea = j, area
+			}
+		}
+		if best < 0 {
+			out = append(out, src[lo])
+			continue
+		}
+		out = append(out, src[best])
+		anchor = best
+	}
+
+	return append(out, src[n-1])
+}
+
+func m4(src []DataUnit, pixels int) []DataUnit {
+	if pixels < 1 {
+		pixels = 1
+	}
+	first, last := src[0].Chron, src[len(src)-1].Chron
+	span := last.Sub(first)
+	width := span / time.Duration(pixels)
+	if width <= 0 {
+		width = 1
+	}
+
+	out := make([]DataUnit, 0, 4*pixels)
+	i := 0
+	for p := 0; p < pixels && i < len(src); p++ {
+		end := first.Add(time.Duration(p+1) * width)
+		lo := i
+		for i < len(src) && (p == pixels-1 || src[i].Chron.Before(end)) {
+			i++
+		}
+		if i == lo {
+			continue
+		}
+		out = append(out, m4Bucket(src[lo:i])...)
+	}
+	return out
+}
+
+// m4Bucket returns the first, min, max and last valid points of a bucket,
+// without duplicates and in chronological order.
+func m4Bucket(bucket []DataUnit) []DataUnit {
+	iFirst, iLast, iMin, iMax := -1, -1, -1, -1
+	for j, du := range bucket {
+		if math.IsNaN(du.Meas) {
+			continue
+		}
+		if iFirst < 0 {
+			iFirst, iMin, iMax = j, j, j
+		}
+		iLast = j
+		if du.Meas < bucket[iMin].Meas {
+			iMin = j
+		}
+		if du.Meas > bucket[iMax].Meas {
+			iMax = j
+		}
+	}
+	if iFirst < 0 {
+		return bucket[:1]
+	}
+
+	idx := []int{iFirst, iMin, iMax, iLast}
+	// insertion sort of the 4 indices, then drop duplicates
+	for a := 1; a < len(idx); a++ {
+		for b := a; b > 0 && idx[b] < idx[b-1]; b-- {
+			idx[b], idx[b-1] = idx[b-1], idx[b]
+		}
+	}
+	out := make([]DataUnit, 0, 4)
+	for k, j := range idx {
+		if k > 0 && j == idx[k-1] {
+			continue
+		}
+		out = append(out, bucket[j])
+	}
+	return out
+}
@@ -0,0 +1,140 @@
+package timeseries
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func sineWithSpike(n int) TimeSeries {
+	ts := TimeSeries{Name: "sine", MemId: 42}
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		v := math.Sin(float64(i) / 50)
+		if i == n/3 {
+			v = 100 // isolated peak that must survive
+		}
+		ts.AddData(t0.Add(time.Duration(i)*time.Second), v)
+	}
+	return ts
+}
+
+func assertChronAsc(t *testing.T, dus []DataUnit) {
+	t.Helper()
+	for i := 1; i < len(dus); i++ {
+		if !dus[i].Chron.After(dus[i-1].Chron) {
+			t.Fatalf("points %d/%d not strictly increasing: %v then %v", i-1, i, dus[i-1].Chron, dus[i].Chron)
+		}
+	}
+}
+
+func hasMeas(dus []DataUnit, v float64) bool {
+	for _, du := range dus {
+		if du.Meas == v {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLTTBKeepsEndsAndPeak(t *testing.T) {
+	ts := sineWithSpike(10000)
+	out := ts.LTTB(200)
+
+	if len(out.DataSeries) != 200 {
+		t.Fatalf("len = %d, want 200", len(out.DataSeries))
+	}
+	if out.MemId != 42 || out.Name != "sine" {
+		t.Fatalf("identity not kept: %d %q", out.MemId, out.Name)
+	}
+	if out.DataSeries[0] != ts.DataSeries[0] || out.DataSeries[199] != ts.DataSeries[9999] {
+		t.Fatalf("first/last points must be kept")
+	}
+	if !hasMeas(out.DataSeries, 100) {
+		t.Fatalf("peak lost by LTTB")
+	}
+	assertChronAsc(t, out.DataSeries)
+	if len(ts.DataSeries) != 10000 {
+		t.Fatalf("source series modified")
+	}
+}
+
+func TestM4KeepsExtremaPerBucket(t *testing.T) {
+	ts := sineWithSpike(10000)
+	out := ts.M4(100)
+
+	if n := len(out.DataSeries); n == 0 || n > 400 {
+		t.Fatalf("len = %d, want 1..400", n)
+	}
+	if !hasMeas(out.DataSeries, 100) {
+		t.Fatalf("peak lost by M4")
+	}
+	min := math.Inf(1)
+	for _, du := range ts.DataSeries {
+		min = math.Min(min, du.Meas)
+	}
+	if !hasMeas(out.DataSeries, min) {
+		t.Fatalf("global minimum %g lost by M4", min)
+	}
+	assertChronAsc(t, out.DataSeries)
+}
+
+func TestDownsampleGapsAndShortSeries(t *testing.T) {
+	ts := sineWithSpike(1000)
+	for i := 400; i < 600; i++ {
+		ts.DataSeries[i].Meas = math.NaN()
+	}
+
+	for _, m := range []DownsampleMethod{DownsampleLTTB, DownsampleM4} {
+		out := ts.Downsample(m, 40)
+		if len(out.DataSeries) > 40 {
+			t.Fatalf("%s: len = %d, want <= 40", m, len(out.DataSeries))
+		}
+		nan := 0
+		for _, du := range out.DataSeries {
+			if math.IsNaN(du.Meas) {
+				nan++
+			}
+		}
+		if nan == 0 {
+			t.Fatalf("%s: gap not visible in downsampled view", m)
+		}
+	}
+
+	if out := ts.Downsample(DownsampleLTTB, 5000); len(out.DataSeries) != 1000 {
+		t.Fatalf("short series must be copied as is, got %d points", len(out.DataSeries))
+	}
+}
+
+func TestToJSONDownsampledKeepsFullStats(t *testing.T) {
+	ts := sineWithSpike(5000)
+	ts.Sort_Deltas_Stats()
+
+	j := ts.ToJSONDownsampled(DownsampleLTTB, 100)
+	if len(j.Chron) != 100 || j.FullLen != 5000 || j.Downsampling != "LTTB" {
+		t.Fatalf("unexpected view: len=%d fullLen=%d method=%q", len(j.Chron), j.FullLen, j.Downsampling)
+	}
+	if j.Stats == nil || j.Stats.Len != ts.Len {
+		t.Fatalf("stats must describe the full series")
+	}
+
+	if full := ts.ToJSONDownsampled(DownsampleLTTB, 0); len(full.Chron) != 5000 || full.FullLen != 0 {
+		t.Fatalf("maxPoints=0 must return the full series")
+	}
+}
+
+func TestDownsampleTinyMaxPoints(t *testing.T) {
+	ts := sineWithSpike(1000)
+	first, last := ts.DataSeries[0], ts.DataSeries[len(ts.DataSeries)-1]
+	for _, m := range []DownsampleMethod{DownsampleLTTB, DownsampleM4} {
+		for maxPoints := 1; maxPoints <= 3; maxPoints++ {
+			out := ts.Downsample(m, maxPoints).DataSeries
+			if len(out) != maxPoints {
+				t.Fatalf("%s maxPoints=%d: got %d points", m, maxPoints, len(out))
+			}
+			if out[0] != first || (maxPoints > 1 && out[len(out)-1] != last) {
+				t.Fatalf("%s maxPoints=%d: ends not kept: %+v", m, maxPoints, out)
+			}
+		}
+	}
+}
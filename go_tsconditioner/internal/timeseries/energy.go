@@ -0,0 +1,177 @@
+package timeseries
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// IntegrationMethod selects how a power series is integrated between two samples.
+type IntegrationMethod int
+
+const (
+	IntegrateTrapezoid IntegrationMethod = iota // mean of both ends times the interval
+	IntegrateStep                               // left value held until the next sample
+)
+
+func (m IntegrationMethod) String() string {
+	switch m {
+	case IntegrateTrapezoid:
+		return "Trapezoid"
+	case IntegrateStep:
+		return "Step"
+	default:
+		return fmt.Sprintf("IntegrationMethod(%d)", int(m))
+	}
+}
+
+// Power units and the energy unit obtained by integrating them over hours.
+var energyOfPower = map[string]string{"W": "Wh", "kW": "kWh", "MW": "MWh"}
+
+// Integrate turns a power series into a cumulative energy series, one point
+// per input point, using the actual spacing of Chron (hours are the time
+// base, so kW integrates to kWh). Unlike AggIntegral it does not assume a
+// regular grid.
+//
+// An interval contributes only if its ends are usable (Status StOK or
+// StSimulated and Meas not NaN; for IntegrateStep only the left end matters)
+// and, when maxGap > 0, if it lasts at most maxGap. The point closing a
+// skipped interval carries the cumulative value unchanged with Status
+// StMissing, so the total is a lower bound wherever such points appear. The
+// first point is 0.
+//
+// The receiver is expected in chronological order. A series whose Unit is a
+// known non-power unit is rejected with ErrIncompatibleUnits; an empty Unit
+// is integrated as is.
+func (ts *TimeSeries) Integrate(method IntegrationMethod, maxGap time.Duration) (TimeSeries, error) {
+	unit, err := integratedUnit(ts.Unit)
+	if err != nil {
+		return TimeSeries{}, err
+	}
+	out := TimeSeries{
+		Name:       ts.Name + " Energy",
+		Comment:    fmt.Sprintf("%s integration of %s", method, ts.Name),
+		Unit:       unit,
+		DataSeries: make([]DataUnit, 0, len(ts.DataSeries)),
+	}
+	if len(ts.DataSeries) == 0 {
+		return out, nil
+	}
+
+	cum := 0.0
+	out.AddDataUnit(DataUnit{Chron: ts.DataSeries[0].Chron, Meas: 0, Dchron: NaDuration, Dmeas: math.NaN(), Status: StOK})
+	for i := 1; i < len(ts.DataSeries); i++ {
+		prev, cur := ts.DataSeries[i-1], ts.DataSeries[i]
+		dt := cur.Chron.Sub(prev.Chron)
+
+		ok := usable(prev) && dt > 0 && (maxGap <= 0 || dt <= maxGap)
+		var area float64
+		switch method {
+		case IntegrateStep:
+			area = prev.Meas * dt.Hours()
+		default:
+			ok = ok && usable(cur)
+			area = (prev.Meas + cur.Meas) / 2 * dt.Hours()
+		}
+
+		du := DataUnit{Chron: cur.Chron, Dchron: dt, Status: StOK}
+		if ok {
+			cum += area
+			du.Dmeas = area
+		} else {
+			du.Dmeas = math.NaN()
+			du.Status = StMissing
+		}
+		du.Meas = cum
+		out.AddDataUnit(du)
+	}
+	return out, nil
+}
+
+// Differentiate turns a cumulative energy counter into the average power
+// over each interval, stamped at the end of the interval (hours are the time
+// base, so kWh differentiates to kW; J is read as Wh/3600 and gives W).
+//
+// The first point and every interval with an unusable end (Status neither
+// StOK nor StSimulated, or NaN) or, when maxGap > 0, longer than maxGap yield NaN with Status
+// StMissing. A decreasing counter (reset or rollover) yields NaN with Status
+// StInvalid rather than a negative power.
+//
+// The receiver is expected in chronological order. A series whose Unit is a
+// known non-energy unit is rejected with ErrIncompatibleUnits.
+func (ts *TimeSeries) Differentiate(maxGap time.Duration) (TimeSeries, error) {
+	unit, scale, err := differentiatedUnit(ts.Unit)
+	if err != nil {
+		return TimeSeries{}, err
+	}
+	out := TimeSeries{
+		Name:       ts.Name + " Power",
+		Comment:    "average power per interval of " + ts.Name,
+		Unit:       unit,
+		DataSeries: make([]DataUnit, 0, len(ts.DataSeries)),
+	}
+	if len(ts.DataSeries) == 0 {
+		return out, nil
+	}
+
+	out.AddDataUnit(DataUnit{Chron: ts.DataSeries[0].Chron, Meas: math.NaN(), Dchron: NaDuration, Dmeas: math.NaN(), Status: StMissing})
+	for i := 1; i < len(ts.DataSeries); i++ {
+		prev, cur := ts.DataSeries[i-1], ts.DataSeries[i]
+		dt := cur.Chron.Sub(prev.Chron)
+
+		du := DataUnit{Chron: cur.Chron, Meas: math.NaN(), Dchron: dt, Dmeas: math.NaN(), Status: StOK}
+		switch {
+		case !usable(prev) || !usable(cur) || dt <= 0 || (maxGap > 0 && dt > maxGap):
+			du.Status = StMissing
+		case cur.Meas < prev.Meas:
+			du.Status = StInvalid
+		default:
+			du.Meas = (cur.Meas - prev.Meas) * scale / dt.Hours()
+		}
+		out.AddDataUnit(du)
+	}
+	return out, nil
+}
+
+// usable reports whether du carries a value to compute with: valid or
+// simulated (interpolated, generated) points count, missing, invalid and
+// flagged points (StOutlier, StRejected) do not.
+func usable(du DataUnit) bool {
+	return (du.Status == StOK || du.Status == StSimulated) && !math.IsNaN(du.Meas)
+}
+
+func integratedUnit(unit string) (string, error) {
+	if unit == "" {
+		return "", nil
+	}
+	u, ok := LookupUnit(unit)
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownUnit, unit)
+	}
+	if u.Dimension != DimPower {
+		return "", fmt.Errorf("%w: cannot integrate %s (%s), power expected", ErrIncompatibleUnits, unit, u.Dimension)
+	}
+	return energyOfPower[u.Symbol], nil
+}
+
+// differentiatedUnit returns the power unit and the factor bringing the
+// counter to the matching "<power>h" unit.
+func differentiatedUnit(unit string) (string, float64, error) {
+	if unit == "" {
+		return "", 1, nil
+	}
+	u, ok := LookupUnit(unit)
+	if !ok {
+		return "", 0, fmt.Errorf("%w: %q", ErrUnknownUnit, unit)
+	}
+	if u.Dimension != DimEnergy {
+		return "", 0, fmt.Errorf("%w: cannot differentiate %s (%s), energy expected", ErrIncompatibleUnits, unit, u.Dimension)
+	}
+	for p, e := range energyOfPower {
+		if e == u.Symbol {
+			return p, 1, nil
+		}
+	}
+	wh, _ := LookupUnit("Wh")
+	return "W", u.Scale / wh.Scale, nil
+}
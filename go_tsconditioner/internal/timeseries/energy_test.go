@@ -0,0 +1,141 @@
+package timeseries
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestIntegrateIrregularPower(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts := TimeSeries{Name: "p", Unit: "kW"}
+	// 2 kW constants, pas de 15 min puis 45 min : 2 kWh sur l'heure
+	ts.AddData(t0, 2)
+	ts.AddData(t0.Add(15*time.Minute), 2)
+	ts.AddData(t0.Add(time.Hour), 2)
+
+	e, err := ts.Integrate(IntegrateTrapezoid, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Unit != "kWh" || len(e.DataSeries) != 3 {
+		t.Fatalf("unit %q, %d points", e.Unit, len(e.DataSeries))
+	}
+	if got := e.DataSeries[2].Meas; math.Abs(got-2) > 1e-9 {
+		t.Fatalf("total: got %g kWh, want 2", got)
+	}
+
+	// rampe 0 -> 4 kW sur une heure : trapèze 2 kWh, palier gauche 0
+	ramp := TimeSeries{Unit: "kW"}
+	ramp.AddData(t0, 0)
+	ramp.AddData(t0.Add(time.Hour), 4)
+	tr, _ := ramp.Integrate(IntegrateTrapezoid, 0)
+	st, _ := ramp.Integrate(IntegrateStep, 0)
+	if tr.DataSeries[1].Meas != 2 || st.DataSeries[1].Meas != 0 {
+		t.Fatalf("ramp: trapezoid %g, step %g", tr.DataSeries[1].Meas, st.DataSeries[1].Meas)
+	}
+}
+
+func TestIntegrateFlagsGaps(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts := TimeSeries{Unit: "W"}
+	ts.AddData(t0, 1000)
+	ts.AddData(t0.Add(time.Hour), 1000)
+	ts.AddDataUnit(DataUnit{Chron: t0.Add(2 * time.Hour), Meas: 5000, Status: StOutlier})
+	ts.AddData(t0.Add(3*time.Hour), 1000)
+	ts.AddData(t0.Add(10*time.Hour), 1000) // trou de 7 h
+
+	e, err := ts.Integrate(IntegrateTrapezoid, 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantStatus := []StatusCode{StOK, StOK, StMissing, StMissing, StMissing}
+	for i, du := range e.DataSeries {
+		if du.Status != wantStatus[i] {
+			t.Fatalf("point %d: status %s, want %s", i, du.Status, wantStatus[i])
+		}
+	}
+	if got := e.DataSeries[4].Meas; got != 1000 {
+		t.Fatalf("cumulative over gaps: got %g Wh, want 1000", got)
+	}
+
+	if _, err := (&TimeSeries{Unit: "°C"}).Integrate(IntegrateStep, 0); !errors.Is(err, ErrIncompatibleUnits) {
+		t.Fatalf("temperature: %v", err)
+	}
+}
+
+// Les points simulés et ceux comblés par l'interpolation d'une série
+// régularisée comptent : seuls les manquants et les rejetés font des trous.
+func TestIntegrateSimulatedAndInterpolated(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	sim := TimeSeries{Unit: "kW"}
+	for i := 0; i <= 4; i++ {
+		sim.AddDataUnit(DataUnit{Chron: t0.Add(time.Duration(i) * 15 * time.Minute), Meas: 2, Status: StSimulated})
+	}
+	e, err := sim.Integrate(IntegrateTrapezoid, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.DataSeries[4].Meas; math.Abs(got-2) > 1e-9 {
+		t.Fatalf("simulated: got %g kWh, want 2", got)
+	}
+
+	raw := TimeSeries{Unit: "kW"}
+	for i := 0; i <= 60; i++ {
+		if i > 10 && i < 50 {
+			continue
+		}
+		raw.AddData(t0.Add(time.Duration(i)*time.Minute), 3)
+	}
+	reg := raw.Regularize(time.Minute, AggAverage, 0)
+	reg.Interpolate(InterpLinear)
+	e, err = reg.Integrate(IntegrateTrapezoid, 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.DataSeries[len(e.DataSeries)-1].Meas; math.Abs(got-3) > 0.1 {
+		t.Fatalf("polished: got %g kWh, want about 3", got)
+	}
+}
+
+func TestDifferentiateCounter(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts := TimeSeries{Name: "meter", Unit: "kWh"}
+	ts.AddData(t0, 100)
+	ts.AddData(t0.Add(30*time.Minute), 101)        // 1 kWh / 0.5 h = 2 kW
+	ts.AddData(t0.Add(2*time.Hour), 104)           // 3 kWh / 1.5 h = 2 kW
+	ts.AddData(t0.Add(3*time.Hour), 4)             // remise à zéro
+	ts.AddData(t0.Add(3*time.Hour+time.Minute), 4) // 0 kW
+
+	p, err := ts.Differentiate(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Unit != "kW" {
+		t.Fatalf("unit %q", p.Unit)
+	}
+	if p.DataSeries[0].Status != StMissing || !math.IsNaN(p.DataSeries[0].Meas) {
+		t.Fatalf("first point: %+v", p.DataSeries[0])
+	}
+	for _, i := range []int{1, 2} {
+		if math.Abs(p.DataSeries[i].Meas-2) > 1e-9 {
+			t.Fatalf("point %d: got %g kW, want 2", i, p.DataSeries[i].Meas)
+		}
+	}
+	if p.DataSeries[3].Status != StInvalid || !math.IsNaN(p.DataSeries[3].Meas) {
+		t.Fatalf("reset: %+v", p.DataSeries[3])
+	}
+	if p.DataSeries[4].Meas != 0 || p.DataSeries[4].Status != StOK {
+		t.Fatalf("flat: %+v", p.DataSeries[4])
+	}
+
+	// J : ramené en Wh, donne des W
+	j := TimeSeries{Unit: "J"}
+	j.AddData(t0, 0)
+	j.AddData(t0.Add(time.Hour), 3.6e6)
+	w, _ := j.Differentiate(0)
+	if w.Unit != "W" || math.Abs(w.DataSeries[1].Meas-1000) > 1e-9 {
+		t.Fatalf("J -> W: %q %g", w.Unit, w.DataSeries[1].Meas)
+	}
+}
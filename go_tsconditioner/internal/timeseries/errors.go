@@ -32,4 +32,10 @@ var (
 	ErrInfValue = statsError{"Value is infinite."}
 	// ErrYCoord Y Value must be greater than zero
 	ErrYCoord = statsError{"Y Value must be greater than zero."}
+	// ErrUnknownUnit Unit is not in the registry
+	ErrUnknownUnit = statsError{"Unknown unit."}
+	// ErrIncompatibleUnits Units measure different dimensions
+	ErrIncompatibleUnits = statsError{"Incompatible units."}
+	// ErrTooFewPoints Not enough usable points for the computation
+	ErrTooFewPoints = statsError{"Not enough usable points."}
 )
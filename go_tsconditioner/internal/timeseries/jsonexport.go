@@ -36,12 +36,17 @@ type TimeSeriesJSON struct {
 	Name    string           `json:"name"`
 	MemId   uint64           `json:"id"`
 	Comment string           `json:"comment,omitempty"`
+	Unit    string           `json:"unit,omitempty"`
 	Chron   []time.Time      `json:"chron"`               // RFC3339
 	Meas    []JSONFloat64    `json:"meas"`                // NaN -> null
 	Dchron  []JSONDurationNS `json:"dchron_ns,omitempty"` // NaDuration -> null
 	Dmeas   []JSONFloat64    `json:"dmeas,omitempty"`     // NaN -> null
 	Status  []StatusCode     `json:"status,omitempty"`
 	Stats   *BasicStatsJSON  `json:"stats,omitempty"`
+
+	// Set only when the arrays above are a downsampled view (see ToJSONDownsampled).
+	Downsampling string `json:"downsampling,omitempty"`
+	FullLen      int    `json:"fullLen,omitempty"`
 }
 type BasicStatsJSON struct {
 	Len        int         `json:"len"`
@@ -79,7 +84,9 @@ type BasicStatsJSON struct {
 type TsContainerJSON struct {
 	Name    string                     `json:"name"`
 	Comment string                     `json:"comment,omitempty"`
+	Unit    string                     `json:"unit,omitempty"`
 	Series  map[string]*TimeSeriesJSON `json:"series"`
+	Errors  map[string]string          `json:"errors,omitempty"`
 }
 
 func (ts *TimeSeries) ToJSON() *TimeSeriesJSON {
@@ -107,6 +114,7 @@ func (ts *TimeSeries) ToJSON() *TimeSeriesJSON {
 		Name:    ts.Name,
 		MemId:   ts.MemId,
 		Comment: ts.Comment,
+		Unit:    ts.Unit,
 		Chron:   chron,
 		Meas:    meas,
 		Dchron:  dchron,
@@ -116,6 +124,21 @@ func (ts *TimeSeries) ToJSON() *TimeSeriesJSON {
 	}
 }
 
+// ToJSONDownsampled is ToJSON applied to a view reduced to at most maxPoints
+// DataUnits (see Downsample). Stats are those of the full-resolution series;
+// Downsampling and FullLen tell the client that the arrays were reduced.
+func (ts *TimeSeries) ToJSONDownsampled(method DownsampleMethod, maxPoints int) *TimeSeriesJSON {
+	if maxPoints <= 0 || method == DownsampleNone || len(ts.DataSeries) <= maxPoints {
+		return ts.ToJSON()
+	}
+	view := ts.Downsample(method, maxPoints)
+	view.BasicStats = ts.BasicStats
+	out := view.ToJSON()
+	out.Downsampling = method.String()
+	out.FullLen = len(ts.DataSeries)
+	return out
+}
+
 func basicStatsToJSON(bs *BasicStats) *BasicStatsJSON {
 	if bs == nil {
 		return nil
@@ -159,7 +182,9 @@ func (c *TsContainer) ToJSON() *TsContainerJSON {
 	out := &TsContainerJSON{
 		Name:    c.Name,
 		Comment: c.Comment,
+		Unit:    c.Unit,
 		Series:  make(map[string]*TimeSeriesJSON, len(c.Ts)),
+		Errors:  c.Errors,
 	}
 	for key, ts := range c.Ts {
 		if ts == nil {
@@ -169,3 +194,21 @@ func (c *TsContainer) ToJSON() *TsContainerJSON {
 	}
 	return out
 }
+
+// ToJSONDownsampled is ToJSON with every series reduced by TimeSeries.ToJSONDownsampled.
+func (c *TsContainer) ToJSONDownsampled(method DownsampleMethod, maxPoints int) *TsContainerJSON {
+	out := &TsContainerJSON{
+		Name:    c.Name,
+		Comment: c.Comment,
+		Unit:    c.Unit,
+		Series:  make(map[string]*TimeSeriesJSON, len(c.Ts)),
+		Errors:  c.Errors,
+	}
+	for key, ts := range c.Ts {
+		if ts == nil {
+			continue
+		}
+		out.Series[key] = ts.ToJSONDownsampled(method, maxPoints)
+	}
+	return out
+}
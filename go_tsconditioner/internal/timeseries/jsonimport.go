@@ -0,0 +1,167 @@
+package timeseries
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+var jsonNull = []byte("null")
+
+// UnmarshalJSON reverses MarshalJSON: null decodes to NaN.
+func (f *JSONFloat64) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(bytes.TrimSpace(b), jsonNull) {
+		*f = JSONFloat64(math.NaN())
+		return nil
+	}
+	var v float64
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*f = JSONFloat64(v)
+	return nil
+}
+
+// UnmarshalJSON reverses MarshalJSON: null decodes to NaDuration.
+func (d *JSONDurationNS) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(bytes.TrimSpace(b), jsonNull) {
+		*d = JSONDurationNS(NaDuration)
+		return nil
+	}
+	var ns int64
+	if err := json.Unmarshal(b, &ns); err != nil {
+		return err
+	}
+	*d = JSONDurationNS(ns)
+	return nil
+}
+
+// UnmarshalJSON accepts the names written by MarshalJSON ("StOK", ...) and,
+// through ParseStatusCode, their short forms and the numeric codes.
+func (s *StatusCode) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		var code uint8
+		if err := json.Unmarshal(b, &code); err != nil {
+			return fmt.Errorf("status code: %s", b)
+		}
+		name = fmt.Sprint(code)
+	}
+	st, err := ParseStatusCode(name)
+	if err != nil {
+		return err
+	}
+	*s = st
+	return nil
+}
+
+// TimeSeriesFromJSON rebuilds a TimeSeries from its JSON form. Chron and
+// Meas must have the same length; Dchron, Dmeas and Status are optional but
+// must match it when present. Without Status, NaN values are StMissing and
+// the others StOK; without deltas, Dchron is NaDuration and Dmeas NaN.
+// Stats are restored as sent: call Sort_Deltas_Stats to recompute them from
+// the data. A downsampled view (Downsampling set) decodes to the reduced
+// series only.
+func TimeSeriesFromJSON(j *TimeSeriesJSON) (TimeSeries, error) {
+	n := len(j.Chron)
+	if len(j.Meas) != n {
+		return TimeSeries{}, fmt.Errorf("%w: %d chron, %d meas", ErrSize, n, len(j.Meas))
+	}
+	for name, l := range map[string]int{"dchron_ns": len(j.Dchron), "dmeas": len(j.Dmeas), "status": len(j.Status)} {
+		if l != 0 && l != n {
+			return TimeSeries{}, fmt.Errorf("%w: %d chron, %d %s", ErrSize, n, l, name)
+		}
+	}
+
+	ts := TimeSeries{
+		MemId:      j.MemId,
+		Name:       j.Name,
+		Comment:    j.Comment,
+		Unit:       j.Unit,
+		DataSeries: make([]DataUnit, n),
+	}
+	for i := range j.Chron {
+		du := DataUnit{Chron: j.Chron[i], Meas: float64(j.Meas[i]), Dchron: NaDuration, Dmeas: math.NaN()}
+		if len(j.Dchron) > 0 {
+			du.Dchron = time.Duration(j.Dchron[i])
+		}
+		if len(j.Dmeas) > 0 {
+			du.Dmeas = float64(j.Dmeas[i])
+		}
+		switch {
+		case len(j.Status) > 0:
+			du.Status = j.Status[i]
+		case math.IsNaN(du.Meas):
+			du.Status = StMissing
+		}
+		ts.DataSeries[i] = du
+	}
+	if j.Stats != nil {
+		ts.BasicStats = basicStatsFromJSON(j.Stats)
+	}
+	return ts, nil
+}
+
+// TsContainerFromJSON rebuilds a container with TimeSeriesFromJSON. Series
+// join it through Add: when the container has a Unit, series in another unit
+// of the same dimension are converted and the others fail with
+// ErrIncompatibleUnits.
+func TsContainerFromJSON(j *TsContainerJSON) (TsContainer, error) {
+	c := TsContainer{
+		Name:    j.Name,
+		Comment: j.Comment,
+		Unit:    j.Unit,
+		Ts:      make(map[string]*TimeSeries, len(j.Series)),
+		Errors:  j.Errors,
+	}
+	for key, sj := range j.Series {
+		if sj == nil {
+			continue
+		}
+		ts, err := TimeSeriesFromJSON(sj)
+		if err != nil {
+			return TsContainer{}, fmt.Errorf("series %q: %w", key, err)
+		}
+		if err := c.Add(key, &ts); err != nil {
+			return TsContainer{}, err
+		}
+	}
+	return c, nil
+}
+
+func basicStatsFromJSON(bj *BasicStatsJSON) BasicStats {
+	return BasicStats{
+		Len:        bj.Len,
+		Chmin:      bj.Chmin,
+		ValAtChmin: float64(bj.ValAtChmin),
+		Chmax:      bj.Chmax,
+		ValAtChmax: float64(bj.ValAtChmax),
+		Chmed:      bj.Chmed,
+		Chmean:     bj.Chmean,
+		Chstd:      bj.Chstd,
+		Msmin:      float64(bj.Msmin),
+		ChAtMsmin:  bj.ChAtMsmin,
+		Msmax:      float64(bj.Msmax),
+		ChAtMsmax:  bj.ChAtMsmax,
+		Msmean:     float64(bj.Msmean),
+		Msmed:      float64(bj.Msmed),
+		Msstd:      float64(bj.Msstd),
+
+		DChmin:     time.Duration(bj.DChminNS),
+		ChAtDChmin: bj.ChAtDChmin,
+		DChmax:     time.Duration(bj.DChmaxNS),
+		ChAtDchmax: bj.ChAtDChmax,
+		DChmean:    float64(bj.DChmeanNS),
+		DChmed:     float64(bj.DChmedNS),
+		DChstd:     float64(bj.DChstdNS),
+
+		DMsmin:    float64(bj.DMsmin),
+		DMsmax:    float64(bj.DMsmax),
+		DMsmed:    float64(bj.DMsmed),
+		DMsmean:   float64(bj.DMsmean),
+		DMsstd:    float64(bj.DMsstd),
+		NbreOfNaN: bj.NbreOfNaN,
+	}
+}
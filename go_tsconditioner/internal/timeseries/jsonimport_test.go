@@ -0,0 +1,93 @@
+package timeseries
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts := TimeSeries{MemId: 7, Name: "power", Unit: "kW"}
+	ts.AddData(t0, 1)
+	ts.AddDataUnit(DataUnit{Chron: t0.Add(time.Minute), Meas: math.NaN(), Status: StMissing})
+	ts.AddDataUnit(DataUnit{Chron: t0.Add(2 * time.Minute), Meas: 50, Status: StOutlier})
+	ts.Sort_Deltas_Stats()
+
+	b, err := json.Marshal(ts.ToJSON())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var j TimeSeriesJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		t.Fatal(err)
+	}
+	back, err := TimeSeriesFromJSON(&j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.MemId != 7 || back.Unit != "kW" || len(back.DataSeries) != 3 {
+		t.Fatalf("metadata: %+v", back)
+	}
+	for i, du := range ts.DataSeries {
+		got := back.DataSeries[i]
+		sameMeas := got.Meas == du.Meas || (math.IsNaN(got.Meas) && math.IsNaN(du.Meas))
+		if !got.Chron.Equal(du.Chron) || !sameMeas || got.Status != du.Status || got.Dchron != du.Dchron {
+			t.Fatalf("point %d: got %+v, want %+v", i, got, du)
+		}
+	}
+	if back.DataSeries[0].Dchron != NaDuration {
+		t.Fatalf("first dchron: %v", back.DataSeries[0].Dchron)
+	}
+	if back.Len != ts.Len || back.Msmax != ts.Msmax {
+		t.Fatalf("stats: %+v", back.BasicStats)
+	}
+}
+
+func TestFromJSONMinimalAndInvalid(t *testing.T) {
+	var j TimeSeriesJSON
+	in := `{"name":"client","chron":["2025-01-01T00:00:00Z","2025-01-01T00:01:00Z"],"meas":[1.5,null],"status":["ok","StMissing"]}`
+	if err := json.Unmarshal([]byte(in), &j); err != nil {
+		t.Fatal(err)
+	}
+	ts, err := TimeSeriesFromJSON(&j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.DataSeries[0].Status != StOK || !math.IsNaN(ts.DataSeries[1].Meas) || ts.DataSeries[1].Status != StMissing {
+		t.Fatalf("%+v", ts.DataSeries)
+	}
+
+	j.Meas = j.Meas[:1]
+	if _, err := TimeSeriesFromJSON(&j); !errors.Is(err, ErrSize) {
+		t.Fatalf("length mismatch: %v", err)
+	}
+	var st StatusCode
+	if err := json.Unmarshal([]byte(`"StBogus"`), &st); err == nil {
+		t.Fatal("unknown status accepted")
+	}
+}
+
+func TestContainerFromJSONUnits(t *testing.T) {
+	var j TsContainerJSON
+	in := `{"name":"energy","unit":"kWh","series":{
+		"a":{"name":"a","unit":"Wh","chron":["2025-01-01T00:00:00Z"],"meas":[1500]},
+		"b":{"name":"b","unit":"kWh","chron":["2025-01-01T00:00:00Z"],"meas":[2]}}}`
+	if err := json.Unmarshal([]byte(in), &j); err != nil {
+		t.Fatal(err)
+	}
+	c, err := TsContainerFromJSON(&j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := c.Ts["a"]; a.Unit != "kWh" || a.DataSeries[0].Meas != 1.5 {
+		t.Fatalf("Wh series not converted: %s %+v", a.Unit, a.DataSeries)
+	}
+
+	j.Series["a"].Unit = "°C"
+	if _, err := TsContainerFromJSON(&j); !errors.Is(err, ErrIncompatibleUnits) {
+		t.Fatalf("°C in a kWh container: %v", err)
+	}
+}
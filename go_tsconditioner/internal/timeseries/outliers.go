@@ -61,6 +61,7 @@ func (tsin *TimeSeries) RemoveOutbounds(
 
 	tsout.Name = tsin.Name + " Cleaned"
 	tsrej.Name = tsin.Name + " Removed"
+	tsout.Unit, tsrej.Unit = tsin.Unit, tsin.Unit
 
 	return tsout, tsrej
 }
@@ -124,6 +125,7 @@ func (tsin *TimeSeries) PeirceOutlierRemoval() (TimeSeries, TimeSeries) {
 			tsout.AddDataUnit(tsin.DataSeries[k])
 		}
 	}
+	tsout.Unit, tsrej.Unit = tsin.Unit, tsin.Unit
 	return tsout, tsrej
 }
 
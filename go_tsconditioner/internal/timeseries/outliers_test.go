@@ -23,6 +23,8 @@ func mkTS(vals ...float64) TimeSeries {
 	return ts
 }
 
+func f64p(v float64) *float64 { return &v }
+
 func measSlice(ts TimeSeries) []float64 {
 	out := make([]float64, len(ts.DataSeries))
 	for i, du := range ts.DataSeries {
@@ -31,6 +33,19 @@ func measSlice(ts TimeSeries) []float64 {
 	return out
 }
 
+// measEq compare deux séries de mesures, NaN égal à NaN.
+func measEq(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !almostEq(a[i], b[i], 0) {
+			return false
+		}
+	}
+	return true
+}
+
 func chronIsSortedAsc(ts TimeSeries) bool {
 	for i := 1; i < len(ts.DataSeries); i++ {
 		if ts.DataSeries[i-1].Chron.After(ts.DataSeries[i].Chron) {
@@ -44,9 +59,10 @@ func chronIsSortedAsc(ts TimeSeries) bool {
 
 func TestRemoveOutbounds_Basic(t *testing.T) {
 	// Input: 1,2,3,4,5  -> keep [2..4], reject 1 and 5
+	// La série nettoyée garde la position des rejetés, avec Meas = NaN.
 	in := mkTS(1, 2, 3, 4, 5)
 
-	clean, rej := in.RemoveOutbounds(2, 4, "test bounds")
+	clean, rej := in.RemoveOutbounds(f64p(2), f64p(4), "test bounds")
 
 	if !chronIsSortedAsc(in) {
 		t.Fatalf("original TimeSeries should be restored in chronological order")
@@ -58,10 +74,10 @@ func TestRemoveOutbounds_Basic(t *testing.T) {
 	gotClean := measSlice(clean)
 	gotRej := measSlice(rej)
 
-	wantClean := []float64{2, 3, 4}
+	wantClean := []float64{math.NaN(), 2, 3, 4, math.NaN()}
 	wantRej := []float64{1, 5}
 
-	if !reflect.DeepEqual(gotClean, wantClean) {
+	if !measEq(gotClean, wantClean) {
 		t.Fatalf("cleaned Meas = %v, want %v", gotClean, wantClean)
 	}
 	if !reflect.DeepEqual(gotRej, wantRej) {
@@ -86,11 +102,12 @@ func TestZscoreCleaning_Level1(t *testing.T) {
 	gotClean := measSlice(clean)
 	gotRej := measSlice(rej)
 
-	// On tolère les arrondis : on s'attend à garder {-0.5, 0, 0.5}
-	wantClean := []float64{-0.5, 0, 0.5}
+	// On tolère les arrondis : on s'attend à garder {-0.5, 0, 0.5},
+	// -2 et 2 restant en place sous forme de NaN
+	wantClean := []float64{math.NaN(), -0.5, 0, 0.5, math.NaN()}
 	wantRej := []float64{-2, 2}
 
-	if !reflect.DeepEqual(gotClean, wantClean) {
+	if !measEq(gotClean, wantClean) {
 		t.Fatalf("cleaned Meas = %v, want %v", gotClean, wantClean)
 	}
 	if !reflect.DeepEqual(gotRej, wantRej) {
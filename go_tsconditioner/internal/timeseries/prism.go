@@ -0,0 +1,227 @@
+package timeseries
+
+import (
+	"fmt"
+	"math"
+)
+
+// PRISMOptions configures FitPRISM. A nil base is searched on the grid
+// [SearchMin, SearchMax] by SearchStep (defaults 10, 25 and 0.5 °C) and the
+// base giving the best R² is kept.
+type PRISMOptions struct {
+	Heating    bool
+	Cooling    bool
+	BaseHeat   *float64
+	BaseCool   *float64
+	SearchMin  float64
+	SearchMax  float64
+	SearchStep float64
+}
+
+// PRISMModel is a degree-day regression of daily consumption:
+//
+//	E(day) = Intercept + HeatSlope*HDD(BaseHeat) + CoolSlope*CDD(BaseCool)
+//
+// Intercept is the weather-independent base load per day. A term that is
+// not fitted has a zero slope. When no weather-dependent fit has
+// positive slopes, the model falls back to Intercept only (the mean),
+// with Heating and Cooling false and R2 = 0.
+type PRISMModel struct {
+	Heating   bool    `json:"heating"`
+	Cooling   bool    `json:"cooling"`
+	BaseHeat  float64 `json:"baseHeat"`
+	BaseCool  float64 `json:"baseCool"`
+	Intercept float64 `json:"intercept"`
+	HeatSlope float64 `json:"heatSlope"`
+	CoolSlope float64 `json:"coolSlope"`
+	R2        float64 `json:"r2"`
+	Days      int     `json:"days"`
+}
+
+// Predict returns the modelled consumption of a day with the given degree-days.
+func (m PRISMModel) Predict(hdd, cdd float64) float64 {
+	return m.Intercept + m.HeatSlope*hdd + m.CoolSlope*cdd
+}
+
+// PredictFromTemperature returns the modelled consumption of a day whose
+// mean outdoor temperature is tmean (°C).
+func (m PRISMModel) PredictFromTemperature(tmean float64) float64 {
+	return m.Predict(degreeDay(tmean, HeatingDegreeDays, m.BaseHeat), degreeDay(tmean, CoolingDegreeDays, m.BaseCool))
+}
+
+// Normalized returns the weather-normalized consumption of a period of
+// `days` days whose normal degree-day totals are hdd and cdd (taken at the
+// model bases).
+func (m PRISMModel) Normalized(days, hdd, cdd float64) float64 {
+	return days*m.Intercept + m.HeatSlope*hdd + m.CoolSlope*cdd
+}
+
+// FitPRISM regresses daily consumption against degree-days computed from
+// daily mean temperatures (°C), both as produced by DailyIncrements /
+// DailyEnergy and DailyMeans. Days are paired on Chron; only days usable in
+// both series enter the fit.
+func FitPRISM(dailyCons, dailyTemp *TimeSeries, opts PRISMOptions) (PRISMModel, error) {
+	if !opts.Heating && !opts.Cooling {
+		return PRISMModel{}, fmt.Errorf("%w: heating and/or cooling must be selected", ErrBounds)
+	}
+	if opts.SearchStep <= 0 {
+		opts.SearchStep = 0.5
+	}
+	if opts.SearchMin == 0 && opts.SearchMax == 0 {
+		opts.SearchMin, opts.SearchMax = 10, 25
+	}
+	if opts.SearchMax < opts.SearchMin {
+		return PRISMModel{}, fmt.Errorf("%w: search range [%g, %g]", ErrBounds, opts.SearchMin, opts.SearchMax)
+	}
+
+	temps := make(map[int64]float64, len(dailyTemp.DataSeries))
+	for _, du := range dailyTemp.DataSeries {
+		if usable(du) {
+			temps[du.Chron.Unix()] = du.Meas
+		}
+	}
+	var y, t []float64
+	for _, du := range dailyCons.DataSeries {
+		if tm, ok := temps[du.Chron.Unix()]; ok && usable(du) {
+			y = append(y, du.Meas)
+			t = append(t, tm)
+		}
+	}
+	params := 1
+	if opts.Heating {
+		params++
+	}
+	if opts.Cooling {
+		params++
+	}
+	if len(y) < params+2 {
+		return PRISMModel{}, fmt.Errorf("%w: %d paired days for %d parameters", ErrTooFewPoints, len(y), params)
+	}
+
+	heatBases := candidateBases(opts.Heating, opts.BaseHeat, opts)
+	coolBases := candidateBases(opts.Cooling, opts.BaseCool, opts)
+
+	ybar, _ := Mean(y)
+	best := PRISMModel{Intercept: ybar, Days: len(y)}
+	found := false
+	for _, bh := range heatBases {
+		for _, bc := range coolBases {
+			if opts.Heating && opts.Cooling && bc < bh {
+				continue
+			}
+			m, ok := fitDegreeDays(y, t, opts.Heating, opts.Cooling, bh, bc)
+			if ok && (!found || m.R2 > best.R2) {
+				best, found = m, true
+			}
+		}
+	}
+	return best, nil
+}
+
+func candidateBases(enabled bool, fixed *float64, opts PRISMOptions) []float64 {
+	if !enabled {
+		return []float64{0}
+	}
+	if fixed != nil {
+		return []float64{*fixed}
+	}
+	var out []float64
+	for b := opts.SearchMin; b <= opts.SearchMax+1e-9; b += opts.SearchStep {
+		out = append(out, b)
+	}
+	return out
+}
+
+// fitDegreeDays solves the least squares for one pair of bases and rejects
+// singular systems, non-positive slopes and fits explaining nothing.
+func fitDegreeDays(y, t []float64, heating, cooling bool, baseHeat, baseCool float64) (PRISMModel, bool) {
+	rows := make([][]float64, len(y))
+	for i, tm := range t {
+		row := []float64{1}
+		if heating {
+			row = append(row, degreeDay(tm, HeatingDegreeDays, baseHeat))
+		}
+		if cooling {
+			row = append(row, degreeDay(tm, CoolingDegreeDays, baseCool))
+		}
+		rows[i] = row
+	}
+	beta, ok := leastSquares(rows, y)
+	if !ok {
+		return PRISMModel{}, false
+	}
+
+	m := PRISMModel{Heating: heating, Cooling: cooling, Intercept: beta[0], Days: len(y)}
+	k := 1
+	if heating {
+		m.BaseHeat, m.HeatSlope = baseHeat, beta[k]
+		k++
+	}
+	if cooling {
+		m.BaseCool, m.CoolSlope = baseCool, beta[k]
+	}
+	if (heating && m.HeatSlope <= 0) || (cooling && m.CoolSlope <= 0) {
+		return PRISMModel{}, false
+	}
+
+	ybar, _ := Mean(y)
+	var ssRes, ssTot float64
+	for i, row := range rows {
+		pred := 0.0
+		for j, x := range row {
+			pred += beta[j] * x
+		}
+		ssRes += (y[i] - pred) * (y[i] - pred)
+		ssTot += (y[i] - ybar) * (y[i] - ybar)
+	}
+	if ssTot == 0 {
+		return PRISMModel{}, false
+	}
+	m.R2 = 1 - ssRes/ssTot
+	return m, m.R2 > 0
+}
+
+// leastSquares solves the normal equations (XᵀX)β = Xᵀy by Gaussian
+// elimination with partial pivoting.
+func leastSquares(x [][]float64, y []float64) ([]float64, bool) {
+	p := len(x[0])
+	a := make([][]float64, p)
+	for i := range a {
+		a[i] = make([]float64, p+1)
+	}
+	for r, row := range x {
+		for i := 0; i < p; i++ {
+			for j := 0; j < p; j++ {
+				a[i][j] += row[i] * row[j]
+			}
+			a[i][p] += row[i] * y[r]
+		}
+	}
+
+	for col := 0; col < p; col++ {
+		pivot := col
+		for r := col + 1; r < p; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return nil, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		for r := 0; r < p; r++ {
+			if r == col {
+				continue
+			}
+			f := a[r][col] / a[col][col]
+			for c := col; c <= p; c++ {
+				a[r][c] -= f * a[col][c]
+			}
+		}
+	}
+	beta := make([]float64, p)
+	for i := range beta {
+		beta[i] = a[i][p] / a[i][i]
+	}
+	return beta, true
+}
@@ -6,9 +6,12 @@ import (
 	"time"
 )
 
+// Regularize agrège la série sur une grille de pas freq avec agg. La sortie
+// garde l'unité de ts : pour un agrégateur qui change de grandeur (comptage,
+// pente, intégrale), l'appelant fixe Unit (cf. AggIntegralUnit).
 func (ts *TimeSeries) Regularize(freq time.Duration, agg AggFunc, tolerance int) TimeSeries {
 
-	var out TimeSeries
+	out := TimeSeries{Unit: ts.Unit}
 	if len(ts.DataSeries) == 0 {
 		return out
 	}
@@ -78,7 +81,7 @@ func (ts *TimeSeries) Regularize(freq time.Duration, agg AggFunc, tolerance int)
 	return out
 }
 func (ts *TimeSeries) Reduce() TimeSeries {
-	var out TimeSeries
+	out := TimeSeries{Unit: ts.Unit}
 	if len(ts.DataSeries) == 0 {
 		return out
 	}
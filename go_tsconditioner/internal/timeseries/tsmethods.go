@@ -56,6 +56,7 @@ func (ts *TimeSeries) Copy() TimeSeries {
 	var copyofts TimeSeries
 	copyofts.Name = ts.Name
 	copyofts.Comment = ts.Comment
+	copyofts.Unit = ts.Unit
 	for _, value := range ts.DataSeries {
 		copyofts.DataSeries = append(copyofts.DataSeries, value)
 	}
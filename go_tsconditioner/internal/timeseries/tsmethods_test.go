@@ -211,8 +211,8 @@ func TestDeltasFiller(t *testing.T) {
 	ts.SortChronAsc()
 	ts.DeltasFiller()
 
-	if ts.DataSeries[0].Dchron != 0 || !almostEq(ts.DataSeries[0].Dmeas, 0, 0) {
-		t.Fatalf("First delta must be zero (Dchron and Dmeas)")
+	if ts.DataSeries[0].Dchron != NaDuration || !math.IsNaN(ts.DataSeries[0].Dmeas) {
+		t.Fatalf("First delta must be undefined (NaDuration and NaN)")
 	}
 
 	if !almostDurEq(ts.DataSeries[1].Dchron, 10*time.Second, time.Nanosecond) {
@@ -268,14 +268,23 @@ func TestSortDeltasStatsAndComputeBasicStats(t *testing.T) {
 	// DChmin / DChmax / DChmean / DChmed (sur deltas > 0)
 	// gaps (après tri): 10s, 2s, 13s  => min=2s, max=13s
 
+	// Le NaN rend indéfinis les deux Dmeas qui l'encadrent : les stats DMs*
+	// et le Comment final se vérifient sur la même série sans NaN.
+	clean := &TimeSeries{Name: "sample clean"}
+	clean.AddData(t0, 10)
+	clean.AddData(t0.Add(25*time.Second), 20)
+	clean.AddData(t0.Add(10*time.Second), 8)
+	clean.AddData(t0.Add(12*time.Second), 5)
+	clean.Sort_Deltas_Stats()
+
 	// DMs* existent car >= 3 points -> 2 deltas sur Meas après suppression du premier
-	if math.IsNaN(ts.DMsmean) || math.IsNaN(ts.DMsmed) || math.IsNaN(ts.DMsstd) {
+	if math.IsNaN(clean.DMsmean) || math.IsNaN(clean.DMsmed) || math.IsNaN(clean.DMsstd) {
 		t.Fatalf("DMs* stats should be defined (not NaN)")
 	}
 
 	// Comment final
-	if ts.Comment != " Time Series ok." {
-		t.Fatalf("Comment expected ' Time Series ok.', got %q", ts.Comment)
+	if clean.Comment != " Time Series ok." {
+		t.Fatalf("Comment expected ' Time Series ok.', got %q", clean.Comment)
 	}
 }
 
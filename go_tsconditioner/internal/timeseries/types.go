@@ -2,6 +2,8 @@ package timeseries
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -49,6 +51,23 @@ func (s StatusCode) String() string {
 	}
 }
 
+// ParseStatusCode is the inverse of String. It also accepts the names without
+// the "St" prefix, case-insensitively ("ok", "missing", ...), and the numeric
+// codes.
+func ParseStatusCode(name string) (StatusCode, error) {
+	n := strings.TrimSpace(name)
+	for s := StOK; s <= StSimulated; s++ {
+		full := s.String()
+		if strings.EqualFold(n, full) || strings.EqualFold(n, strings.TrimPrefix(full, "St")) {
+			return s, nil
+		}
+	}
+	if v, err := strconv.ParseUint(n, 10, 8); err == nil && StatusCode(v) <= StSimulated {
+		return StatusCode(v), nil
+	}
+	return StOK, fmt.Errorf("unknown status code %q", name)
+}
+
 // DataUnit represents a single timestamped measurement and its meta-state.
 //
 // Fields (typical usage):
@@ -81,6 +100,7 @@ type TimeSeries struct {
 	MemId      uint64
 	Name       string
 	Comment    string
+	Unit       string // symbol from the units registry ("kWh", "°C", ...), empty if unknown
 	DataSeries []DataUnit
 	BasicStats
 }
@@ -134,6 +154,11 @@ type TsContainer struct {
 	Name    string
 	Comment string
 	Ts      map[string]*TimeSeries
+	// Unit, when set, is enforced by Add: every series is converted to it.
+	Unit string
+	// Errors lists the series that could not be built, keyed like Ts,
+	// when the container is returned partially filled.
+	Errors map[string]string
 }
 
 // NewDataUnit constructs a DataUnit from a timestamp and a value,
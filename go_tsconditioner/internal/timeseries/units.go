@@ -0,0 +1,220 @@
+package timeseries
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Dimension identifies the physical quantity a unit measures. Two units can
+// be converted into one another only if they share the same Dimension.
+type Dimension string
+
+const (
+	DimTemperature Dimension = "temperature"
+	DimPower       Dimension = "power"
+	DimEnergy      Dimension = "energy"
+	DimVolume      Dimension = "volume"
+)
+
+// UnitDef describes a unit as an affine map onto the base unit of its
+// dimension: base = value*Scale + Offset. Offset is only non-zero for
+// temperatures (°C and °F against K).
+type UnitDef struct {
+	Symbol    string
+	Dimension Dimension
+	Scale     float64
+	Offset    float64
+}
+
+func (u UnitDef) toBase(v float64) float64   { return v*u.Scale + u.Offset }
+func (u UnitDef) fromBase(v float64) float64 { return (v - u.Offset) / u.Scale }
+
+var (
+	unitsMu sync.RWMutex
+	units   = map[string]UnitDef{}
+)
+
+func init() {
+	// temperature, base K
+	RegisterUnit(UnitDef{Symbol: "K", Dimension: DimTemperature, Scale: 1})
+	RegisterUnit(UnitDef{Symbol: "°C", Dimension: DimTemperature, Scale: 1, Offset: 273.15}, "degC", "C")
+	RegisterUnit(UnitDef{Symbol: "°F", Dimension: DimTemperature, Scale: 5.0 / 9.0, Offset: 273.15 - 32*5.0/9.0}, "degF", "F")
+	// power, base W
+	RegisterUnit(UnitDef{Symbol: "W", Dimension: DimPower, Scale: 1})
+	RegisterUnit(UnitDef{Symbol: "kW", Dimension: DimPower, Scale: 1e3})
+	RegisterUnit(UnitDef{Symbol: "MW", Dimension: DimPower, Scale: 1e6})
+	// energy, base J
+	RegisterUnit(UnitDef{Symbol: "J", Dimension: DimEnergy, Scale: 1})
+	RegisterUnit(UnitDef{Symbol: "kJ", Dimension: DimEnergy, Scale: 1e3})
+	RegisterUnit(UnitDef{Symbol: "MJ", Dimension: DimEnergy, Scale: 1e6})
+	RegisterUnit(UnitDef{Symbol: "Wh", Dimension: DimEnergy, Scale: 3600})
+	RegisterUnit(UnitDef{Symbol: "kWh", Dimension: DimEnergy, Scale: 3.6e6})
+	RegisterUnit(UnitDef{Symbol: "MWh", Dimension: DimEnergy, Scale: 3.6e9})
+	// volume, base m³
+	RegisterUnit(UnitDef{Symbol: "m³", Dimension: DimVolume, Scale: 1}, "m3")
+	RegisterUnit(UnitDef{Symbol: "L", Dimension: DimVolume, Scale: 1e-3}, "l")
+}
+
+// RegisterUnit adds (or replaces) a unit in the registry, reachable by its
+// symbol and by any alias. Scale must be non-zero.
+func RegisterUnit(u UnitDef, aliases ...string) {
+	if u.Scale == 0 || math.IsNaN(u.Scale) || math.IsInf(u.Scale, 0) {
+		panic(fmt.Sprintf("timeseries: invalid scale for unit %q", u.Symbol))
+	}
+	unitsMu.Lock()
+	defer unitsMu.Unlock()
+	units[u.Symbol] = u
+	for _, a := range aliases {
+		units[a] = u
+	}
+}
+
+// LookupUnit returns the definition registered under symbol (or one of its aliases).
+func LookupUnit(symbol string) (UnitDef, bool) {
+	unitsMu.RLock()
+	defer unitsMu.RUnlock()
+	u, ok := units[strings.TrimSpace(symbol)]
+	return u, ok
+}
+
+// Units returns the canonical symbols of the registered units of dimension d,
+// sorted; an empty d returns every unit.
+func Units(d Dimension) []string {
+	unitsMu.RLock()
+	defer unitsMu.RUnlock()
+	seen := make(map[string]struct{})
+	var out []string
+	for _, u := range units {
+		if d != "" && u.Dimension != d {
+			continue
+		}
+		if _, ok := seen[u.Symbol]; !ok {
+			seen[u.Symbol] = struct{}{}
+			out = append(out, u.Symbol)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Converter returns the function mapping a value expressed in from into to.
+// It fails with ErrUnknownUnit if either symbol is not registered and with
+// ErrIncompatibleUnits if they measure different dimensions.
+func Converter(from, to string) (func(float64) float64, error) {
+	uf, ok := LookupUnit(from)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownUnit, from)
+	}
+	ut, ok := LookupUnit(to)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownUnit, to)
+	}
+	if uf.Dimension != ut.Dimension {
+		return nil, fmt.Errorf("%w: %s (%s) -> %s (%s)", ErrIncompatibleUnits, from, uf.Dimension, to, ut.Dimension)
+	}
+	if uf == ut {
+		return func(v float64) float64 { return v }, nil
+	}
+	return func(v float64) float64 { return ut.fromBase(uf.toBase(v)) }, nil
+}
+
+// CompatibleUnits reports whether values in a and b can be converted into one another.
+func CompatibleUnits(a, b string) bool {
+	_, err := Converter(a, b)
+	return err == nil
+}
+
+// ConvertTo returns a copy of the series expressed in unit. Meas values are
+// converted; Dmeas, being differences, only take the scale ratio (an offset
+// cancels out). Statistics are recomputed when the receiver had some.
+// The receiver must carry a Unit.
+func (ts *TimeSeries) ConvertTo(unit string) (TimeSeries, error) {
+	if ts.Unit == "" {
+		return TimeSeries{}, fmt.Errorf("%w: series %q has no unit", ErrUnknownUnit, ts.Name)
+	}
+	conv, err := Converter(ts.Unit, unit)
+	if err != nil {
+		return TimeSeries{}, err
+	}
+	uf, _ := LookupUnit(ts.Unit)
+	ut, _ := LookupUnit(unit)
+	ratio := uf.Scale / ut.Scale
+
+	out := TimeSeries{
+		MemId:      ts.MemId,
+		Name:       ts.Name,
+		Comment:    ts.Comment,
+		Unit:       ut.Symbol,
+		DataSeries: make([]DataUnit, len(ts.DataSeries)),
+	}
+	for i, du := range ts.DataSeries {
+		du.Meas = conv(du.Meas)
+		du.Dmeas *= ratio
+		out.DataSeries[i] = du
+	}
+	if ts.Len > 0 {
+		out.ComputeBasicStats()
+	}
+	return out, nil
+}
+
+// Add stores ts under key. When the container has a Unit, ts must carry a
+// unit of the same dimension and is converted to the container unit (a kWh
+// series joins a Wh container as Wh); a unitless or incompatible series is
+// rejected. A container without Unit accepts series of any dimension, but a
+// series sharing its dimension with one already stored is converted to that
+// series' unit, so Wh and kWh never sit side by side.
+func (c *TsContainer) Add(key string, ts *TimeSeries) error {
+	if c.Ts == nil {
+		c.Ts = make(map[string]*TimeSeries)
+	}
+	if ts == nil {
+		c.Ts[key] = ts
+		return nil
+	}
+	if c.Unit == "" {
+		if unit := c.unitOfDimension(key, ts.Unit); unit != "" && unit != ts.Unit {
+			converted, err := ts.ConvertTo(unit)
+			if err != nil {
+				return fmt.Errorf("add %q to container %q: %w", key, c.Name, err)
+			}
+			ts = &converted
+		}
+		c.Ts[key] = ts
+		return nil
+	}
+	if ts.Unit == "" {
+		return fmt.Errorf("%w: series %q has no unit, container %q is in %s", ErrIncompatibleUnits, key, c.Name, c.Unit)
+	}
+	if ts.Unit != c.Unit {
+		converted, err := ts.ConvertTo(c.Unit)
+		if err != nil {
+			return fmt.Errorf("add %q to container %q: %w", key, c.Name, err)
+		}
+		ts = &converted
+	}
+	c.Ts[key] = ts
+	return nil
+}
+
+// unitOfDimension returns the unit of a series already stored (other than
+// under key) that measures the same dimension as unit, or "" if none does or
+// unit is unknown.
+func (c *TsContainer) unitOfDimension(key, unit string) string {
+	u, ok := LookupUnit(unit)
+	if !ok {
+		return ""
+	}
+	for k, other := range c.Ts {
+		if k == key || other == nil {
+			continue
+		}
+		if o, ok := LookupUnit(other.Unit); ok && o.Dimension == u.Dimension {
+			return other.Unit
+		}
+	}
+	return ""
+}
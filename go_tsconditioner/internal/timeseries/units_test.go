@@ -0,0 +1,150 @@
+package timeseries
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConverter(t *testing.T) {
+	cases := []struct {
+		from, to string
+		in, want float64
+	}{
+		{"°C", "K", 0, 273.15},
+		{"°C", "°F", 100, 212},
+		{"°F", "°C", 32, 0},
+		{"degC", "K", -273.15, 0},
+		{"kW", "W", 1.5, 1500},
+		{"MW", "kW", 2, 2000},
+		{"kWh", "Wh", 1, 1000},
+		{"Wh", "J", 1, 3600},
+		{"m³", "L", 1, 1000},
+		{"l", "m3", 250, 0.25},
+	}
+	for _, c := range cases {
+		conv, err := Converter(c.from, c.to)
+		if err != nil {
+			t.Fatalf("%s -> %s: %v", c.from, c.to, err)
+		}
+		if got := conv(c.in); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%g %s -> %s: got %g, want %g", c.in, c.from, c.to, got, c.want)
+		}
+	}
+
+	if _, err := Converter("kWh", "kW"); !errors.Is(err, ErrIncompatibleUnits) {
+		t.Fatalf("energy -> power: %v", err)
+	}
+	if _, err := Converter("furlong", "m³"); !errors.Is(err, ErrUnknownUnit) {
+		t.Fatalf("unknown unit: %v", err)
+	}
+}
+
+func TestConvertToKeepsDeltasConsistent(t *testing.T) {
+	ts := TimeSeries{Name: "temp", Unit: "°C"}
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, v := range []float64{10, 12, 11} {
+		ts.AddData(t0.Add(time.Duration(i)*time.Minute), v)
+	}
+	ts.DeltasFiller()
+
+	f, err := ts.ConvertTo("°F")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Unit != "°F" || f.Name != "temp" {
+		t.Fatalf("metadata: %+v", f)
+	}
+	if math.Abs(f.DataSeries[0].Meas-50) > 1e-9 {
+		t.Fatalf("meas: got %g, want 50", f.DataSeries[0].Meas)
+	}
+	// 2 °C d'écart = 3.6 °F, l'offset ne doit pas s'y ajouter
+	if math.Abs(f.DataSeries[1].Dmeas-3.6) > 1e-9 {
+		t.Fatalf("dmeas: got %g, want 3.6", f.DataSeries[1].Dmeas)
+	}
+	if ts.DataSeries[0].Meas != 10 {
+		t.Fatal("receiver was modified")
+	}
+
+	if _, err := (&TimeSeries{Name: "raw"}).ConvertTo("K"); !errors.Is(err, ErrUnknownUnit) {
+		t.Fatalf("unitless series: %v", err)
+	}
+}
+
+func TestTsContainerAddEnforcesUnit(t *testing.T) {
+	c := NewTsContainer()
+	c.Name, c.Unit = "energy", "Wh"
+
+	kwh := TimeSeries{Name: "vendorA", Unit: "kWh"}
+	kwh.AddData(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), 1.2)
+	if err := c.Add("a", &kwh); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Ts["a"]; got.Unit != "Wh" || math.Abs(got.DataSeries[0].Meas-1200) > 1e-9 {
+		t.Fatalf("kWh not converted: %+v", got)
+	}
+
+	power := TimeSeries{Name: "vendorB", Unit: "kW"}
+	if err := c.Add("b", &power); !errors.Is(err, ErrIncompatibleUnits) {
+		t.Fatalf("power into energy container: %v", err)
+	}
+	if err := c.Add("c", &TimeSeries{Name: "raw"}); !errors.Is(err, ErrIncompatibleUnits) {
+		t.Fatalf("unitless into energy container: %v", err)
+	}
+	if len(c.Ts) != 1 {
+		t.Fatalf("rejected series were stored: %d", len(c.Ts))
+	}
+
+	// sans Unit, le conteneur reste hétérogène (datasources d'un device)
+	mixed := NewTsContainer()
+	if err := mixed.Add("p", &power); err != nil {
+		t.Fatal(err)
+	}
+	if err := mixed.Add("e", &kwh); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Sans Unit, deux séries de même grandeur partagent l'unité de la première.
+func TestTsContainerAddUnitlessAlignsDimension(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	wh := TimeSeries{Name: "vendorA", Unit: "Wh"}
+	wh.AddData(t0, 1500)
+	kwh := TimeSeries{Name: "vendorB", Unit: "kWh"}
+	kwh.AddData(t0, 1.2)
+	temp := TimeSeries{Name: "outdoor", Unit: "°C"}
+	temp.AddData(t0, 4)
+
+	c := NewTsContainer()
+	if err := c.Add("a", &wh); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add("b", &kwh); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Ts["b"]; got.Unit != "Wh" || math.Abs(got.DataSeries[0].Meas-1200) > 1e-9 {
+		t.Fatalf("kWh beside Wh not converted: %+v", got)
+	}
+	if kwh.Unit != "kWh" {
+		t.Fatalf("input series modified: %s", kwh.Unit)
+	}
+	if err := c.Add("t", &temp); err != nil || c.Ts["t"].Unit != "°C" {
+		t.Fatalf("other dimension: %v", err)
+	}
+	// une série remplacée se compare aux autres, pas à elle-même
+	if err := c.Add("a", &kwh); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Ts["a"]; got.Unit != "Wh" {
+		t.Fatalf("replacement should follow b (Wh), got %s", got.Unit)
+	}
+}
+
+func TestAggIntegralUnit(t *testing.T) {
+	for in, want := range map[string]string{"W": "J", "kW": "kJ", "MW": "MJ", "kWh": "", "°C": "", "": ""} {
+		if got := AggIntegralUnit(in); got != want {
+			t.Errorf("AggIntegralUnit(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
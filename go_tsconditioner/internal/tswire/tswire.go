@@ -0,0 +1,365 @@
+// Package tswire encode les TimeSeriesJSON et TsContainerJSON dans un format
+// binaire compact (TSW), négocié par l'en-tête Accept (MediaType) : temps en
+// delta-of-delta, valeurs en XOR (voir internal/gorilla), statuts en plages.
+// Une série régulière au pas fixe et à valeurs lentes y tient en quelques
+// bits par point, contre une quarantaine d'octets en JSON.
+//
+// La spécification, destinée aussi au client React, est dans README.md.
+package tswire
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_tsconditioner/internal/gorilla"
+	"go_tsconditioner/internal/timeseries"
+	"math"
+	"sort"
+	"time"
+)
+
+// MediaType identifie le format dans Accept et Content-Type.
+const MediaType = "application/vnd.tsconditioner.tsw"
+
+const (
+	magic   = "TSCW"
+	version = 1
+
+	kindSeries    = 1
+	kindContainer = 2
+
+	hasDchron = 1 << 0
+	hasDmeas  = 1 << 1
+	hasStatus = 1 << 2
+	hasStats  = 1 << 3
+)
+
+var ErrFormat = errors.New("tswire: malformed message")
+
+// Message est le résultat de Decode : exactement un des deux champs est non nil.
+type Message struct {
+	Series    *timeseries.TimeSeriesJSON
+	Container *timeseries.TsContainerJSON
+}
+
+// EncodeSeries encode une série seule.
+func EncodeSeries(s *timeseries.TimeSeriesJSON) ([]byte, error) {
+	buf := append([]byte(magic), version, kindSeries)
+	return appendSeries(buf, s)
+}
+
+// EncodeContainer encode un conteneur ; les séries sont écrites dans l'ordre
+// de leurs clés, les séries nil sont omises.
+func EncodeContainer(c *timeseries.TsContainerJSON) ([]byte, error) {
+	buf := append([]byte(magic), version, kindContainer)
+	buf = appendString(buf, c.Name)
+	buf = appendString(buf, c.Comment)
+	buf = appendString(buf, c.Unit)
+
+	keys := make([]string, 0, len(c.Series))
+	for k, s := range c.Series {
+		if s != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	buf = binary.AppendUvarint(buf, uint64(len(keys)))
+	for _, k := range keys {
+		buf = appendString(buf, k)
+		var err error
+		if buf, err = appendSeries(buf, c.Series[k]); err != nil {
+			return nil, fmt.Errorf("series %q: %w", k, err)
+		}
+	}
+
+	errKeys := make([]string, 0, len(c.Errors))
+	for k := range c.Errors {
+		errKeys = append(errKeys, k)
+	}
+	sort.Strings(errKeys)
+	buf = binary.AppendUvarint(buf, uint64(len(errKeys)))
+	for _, k := range errKeys {
+		buf = appendString(buf, k)
+		buf = appendString(buf, c.Errors[k])
+	}
+	return buf, nil
+}
+
+func appendSeries(buf []byte, s *timeseries.TimeSeriesJSON) ([]byte, error) {
+	n := len(s.Chron)
+	if len(s.Meas) != n {
+		return nil, fmt.Errorf("%w: %d chron, %d meas", timeseries.ErrSize, n, len(s.Meas))
+	}
+	var flags byte
+	for _, col := range []struct {
+		l    int
+		flag byte
+	}{{len(s.Dchron), hasDchron}, {len(s.Dmeas), hasDmeas}, {len(s.Status), hasStatus}} {
+		switch col.l {
+		case 0:
+		case n:
+			flags |= col.flag
+		default:
+			return nil, fmt.Errorf("%w: %d chron, %d in an optional column", timeseries.ErrSize, n, col.l)
+		}
+	}
+	if s.Stats != nil {
+		flags |= hasStats
+	}
+
+	buf = appendString(buf, s.Name)
+	buf = binary.AppendUvarint(buf, s.MemId)
+	buf = appendString(buf, s.Comment)
+	buf = appendString(buf, s.Unit)
+	buf = appendString(buf, s.Downsampling)
+	buf = binary.AppendUvarint(buf, uint64(s.FullLen))
+	buf = binary.AppendUvarint(buf, uint64(n))
+	buf = append(buf, flags)
+
+	buf = appendBlock(buf, gorilla.EncodeTimes(s.Chron))
+	buf = appendBlock(buf, gorilla.EncodeFloats(floats(s.Meas)))
+	if flags&hasDchron != 0 {
+		ns := make([]int64, n)
+		for i, d := range s.Dchron {
+			ns[i] = int64(d)
+		}
+		buf = appendBlock(buf, gorilla.EncodeInts(ns))
+	}
+	if flags&hasDmeas != 0 {
+		buf = appendBlock(buf, gorilla.EncodeFloats(floats(s.Dmeas)))
+	}
+	if flags&hasStatus != 0 {
+		codes := make([]byte, n)
+		for i, st := range s.Status {
+			codes[i] = byte(st)
+		}
+		buf = appendBlock(buf, gorilla.EncodeRuns(codes))
+	}
+	if flags&hasStats != 0 {
+		stats, err := json.Marshal(s.Stats)
+		if err != nil {
+			return nil, err
+		}
+		buf = appendBlock(buf, stats)
+	}
+	return buf, nil
+}
+
+func floats[F ~float64](vs []F) []float64 {
+	out := make([]float64, len(vs))
+	for i, v := range vs {
+		out[i] = float64(v)
+	}
+	return out
+}
+
+func appendString(buf []byte, s string) []byte {
+	return appendBlock(buf, []byte(s))
+}
+
+func appendBlock(buf, b []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// Decode relit un message écrit par EncodeSeries ou EncodeContainer. Les
+// temps sont rendus en UTC, les valeurs NaN comme en JSON (null).
+func Decode(b []byte) (Message, error) {
+	if len(b) < len(magic)+2 || string(b[:len(magic)]) != magic {
+		return Message{}, fmt.Errorf("%w: missing %s header", ErrFormat, magic)
+	}
+	if b[len(magic)] != version {
+		return Message{}, fmt.Errorf("%w: unsupported version %d", ErrFormat, b[len(magic)])
+	}
+	r := &reader{buf: b[len(magic)+2:]}
+	var m Message
+	switch b[len(magic)+1] {
+	case kindSeries:
+		m.Series = r.series()
+	case kindContainer:
+		m.Container = r.container()
+	default:
+		return Message{}, fmt.Errorf("%w: unknown kind %d", ErrFormat, b[len(magic)+1])
+	}
+	if r.err == nil && len(r.buf) > 0 {
+		r.err = fmt.Errorf("%w: %d trailing bytes", ErrFormat, len(r.buf))
+	}
+	if r.err != nil {
+		return Message{}, r.err
+	}
+	return m, nil
+}
+
+// reader mémorise la première erreur ; les lectures suivantes sont sans effet.
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// column reprend une erreur de internal/gorilla en ErrFormat.
+func (r *reader) column(err error) {
+	if err != nil {
+		r.fail(fmt.Errorf("%w: %v", ErrFormat, err))
+	}
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, k := binary.Uvarint(r.buf)
+	if k <= 0 {
+		r.fail(fmt.Errorf("%w: bad varint", ErrFormat))
+		return 0
+	}
+	r.buf = r.buf[k:]
+	return v
+}
+
+func (r *reader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf) == 0 {
+		r.fail(fmt.Errorf("%w: truncated", ErrFormat))
+		return 0
+	}
+	v := r.buf[0]
+	r.buf = r.buf[1:]
+	return v
+}
+
+func (r *reader) block() []byte {
+	l := r.uvarint()
+	if r.err != nil {
+		return nil
+	}
+	if l > uint64(len(r.buf)) {
+		r.fail(fmt.Errorf("%w: truncated", ErrFormat))
+		return nil
+	}
+	b := r.buf[:l]
+	r.buf = r.buf[l:]
+	return b
+}
+
+func (r *reader) string() string {
+	return string(r.block())
+}
+
+func (r *reader) count() int {
+	v := r.uvarint()
+	if v > math.MaxInt32 {
+		r.fail(fmt.Errorf("%w: count %d", ErrFormat, v))
+		return 0
+	}
+	return int(v)
+}
+
+func (r *reader) series() *timeseries.TimeSeriesJSON {
+	s := &timeseries.TimeSeriesJSON{Name: r.string(), MemId: r.uvarint()}
+	s.Comment = r.string()
+	s.Unit = r.string()
+	s.Downsampling = r.string()
+	s.FullLen = r.count()
+	n := r.count()
+	flags := r.byte()
+	if r.err != nil {
+		return nil
+	}
+
+	chron, err := gorilla.DecodeTimes(r.block(), n)
+	r.column(err)
+	s.Chron = nonNil(chron)
+	s.Meas = jsonFloats(r.floats(n))
+	if flags&hasDchron != 0 {
+		ns, err := gorilla.DecodeInts(r.block(), n)
+		r.column(err)
+		s.Dchron = make([]timeseries.JSONDurationNS, len(ns))
+		for i, v := range ns {
+			s.Dchron[i] = timeseries.JSONDurationNS(v)
+		}
+	}
+	if flags&hasDmeas != 0 {
+		s.Dmeas = jsonFloats(r.floats(n))
+	}
+	if flags&hasStatus != 0 {
+		s.Status = r.status(n)
+	}
+	if flags&hasStats != 0 {
+		s.Stats = &timeseries.BasicStatsJSON{}
+		if err := json.Unmarshal(r.block(), s.Stats); err != nil {
+			r.fail(fmt.Errorf("%w: stats: %v", ErrFormat, err))
+		}
+	}
+	if r.err != nil {
+		return nil
+	}
+	return s
+}
+
+func (r *reader) floats(n int) []float64 {
+	vs, err := gorilla.DecodeFloats(r.block(), n)
+	r.column(err)
+	return vs
+}
+
+func (r *reader) status(n int) []timeseries.StatusCode {
+	codes, err := gorilla.DecodeRuns(r.block(), n)
+	r.column(err)
+	out := make([]timeseries.StatusCode, len(codes))
+	for i, c := range codes {
+		out[i] = timeseries.StatusCode(c)
+	}
+	return out
+}
+
+func (r *reader) container() *timeseries.TsContainerJSON {
+	c := &timeseries.TsContainerJSON{Name: r.string(), Comment: r.string(), Unit: r.string()}
+	ns := r.count()
+	c.Series = make(map[string]*timeseries.TimeSeriesJSON, min(ns, 1024))
+	for range ns {
+		if r.err != nil {
+			return nil
+		}
+		k := r.string()
+		c.Series[k] = r.series()
+	}
+	if ne := r.count(); ne > 0 {
+		c.Errors = make(map[string]string, min(ne, 1024))
+		for range ne {
+			if r.err != nil {
+				return nil
+			}
+			k := r.string()
+			c.Errors[k] = r.string()
+		}
+	}
+	if r.err != nil {
+		return nil
+	}
+	return c
+}
+
+func jsonFloats(vs []float64) []timeseries.JSONFloat64 {
+	out := make([]timeseries.JSONFloat64, len(vs))
+	for i, v := range vs {
+		out[i] = timeseries.JSONFloat64(v)
+	}
+	return out
+}
+
+// nonNil garde Chron non nil pour une série vide, comme ToJSON.
+func nonNil(ts []time.Time) []time.Time {
+	if ts == nil {
+		return []time.Time{}
+	}
+	return ts
+}
@@ -0,0 +1,109 @@
+package tswire
+
+import (
+	"encoding/json"
+	"errors"
+	"go_tsconditioner/internal/timeseries"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// daySeries : une journée au pas de 10 s, comme /today/:device.
+func daySeries(name string) *timeseries.TimeSeries {
+	start := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	ts := &timeseries.TimeSeries{Name: name, MemId: 7, Unit: "kW", Comment: "compteur"}
+	for i := range 8640 {
+		v := 20 + math.Round(10*math.Sin(float64(i)/500))/4
+		du := timeseries.NewDataUnit(start.Add(time.Duration(i)*10*time.Second), v)
+		if i%1000 == 999 {
+			du = timeseries.NewDataUnitWithStatus(du.Chron, math.NaN(), timeseries.StMissing)
+		}
+		ts.DataSeries = append(ts.DataSeries, du)
+	}
+	ts.Sort_Deltas_Stats()
+	return ts
+}
+
+// sameJSON compare via JSON : NaN et NaN sont égaux, les temps comparés en UTC.
+func sameJSON(t *testing.T, got, want any) {
+	t.Helper()
+	g, _ := json.Marshal(got)
+	w, _ := json.Marshal(want)
+	if string(g) != string(w) {
+		t.Fatalf("round trip differs:\n got %.300s\nwant %.300s", g, w)
+	}
+}
+
+func TestSeriesRoundTrip(t *testing.T) {
+	ts := daySeries("power")
+	for _, in := range []*timeseries.TimeSeriesJSON{
+		ts.ToJSON(),
+		ts.ToJSONDownsampled(timeseries.DownsampleLTTB, 500),
+		{Name: "bare", Chron: []time.Time{ts.DataSeries[0].Chron}, Meas: []timeseries.JSONFloat64{1}},
+		(&timeseries.TimeSeries{Name: "empty"}).ToJSON(),
+	} {
+		b, err := EncodeSeries(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := Decode(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Container != nil || m.Series == nil {
+			t.Fatalf("kind: %+v", m)
+		}
+		sameJSON(t, m.Series, in)
+	}
+}
+
+func TestContainerRoundTripAndSize(t *testing.T) {
+	tsc := timeseries.TsContainer{
+		Name: "today", Unit: "kW",
+		Ts:     map[string]*timeseries.TimeSeries{"power": daySeries("power"), "power2": daySeries("power2")},
+		Errors: map[string]string{"energy": "no data"},
+	}
+	in := tsc.ToJSON()
+	b, err := EncodeContainer(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameJSON(t, m.Container, in)
+	if !reflect.DeepEqual(m.Container.Errors, in.Errors) {
+		t.Fatalf("errors: %v", m.Container.Errors)
+	}
+
+	js, _ := json.Marshal(in)
+	if len(b)*10 > len(js) {
+		t.Fatalf("TSW %d bytes vs JSON %d bytes: expected at least 10x smaller", len(b), len(js))
+	}
+	t.Logf("TSW %d bytes, JSON %d bytes", len(b), len(js))
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	b, err := EncodeSeries(daySeries("power").ToJSON())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, in := range map[string][]byte{
+		"empty":     nil,
+		"magic":     append([]byte("JSON"), b[4:]...),
+		"version":   append(append([]byte(magic), 9), b[5:]...),
+		"truncated": b[:len(b)/2],
+		"trailing":  append(append([]byte(nil), b...), 0),
+	} {
+		if _, err := Decode(in); !errors.Is(err, ErrFormat) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	if _, err := EncodeSeries(&timeseries.TimeSeriesJSON{Chron: make([]time.Time, 2), Meas: make([]timeseries.JSONFloat64, 1)}); !errors.Is(err, timeseries.ErrSize) {
+		t.Fatalf("length mismatch: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package types
+
+import "time"
+
+// Types de règles d'alerte supportés par le moteur
+const (
+	AlertKindThreshold    = "threshold"    // valeur au-dessus/au-dessous d'un seuil pendant une durée
+	AlertKindRateOfChange = "rateofchange" // variation (unités/seconde) au-dessus d'un seuil
+	AlertKindNoData       = "nodata"       // aucun point reçu depuis une durée
+)
+
+// Opérateurs pour les règles "threshold"
+const (
+	AlertOpAbove = "above"
+	AlertOpBelow = "below"
+)
+
+// Cycle de vie d'un événement d'alerte : open -> acknowledged -> resolved
+const (
+	AlertStateOpen         = "open"
+	AlertStateAcknowledged = "acknowledged"
+	AlertStateResolved     = "resolved"
+)
+
+type AlertRule struct {
+	ID              int64     `json:"id" db:"id"`
+	Name            string    `json:"name" db:"name"`
+	Device          string    `json:"device" db:"device"`
+	DataSource      string    `json:"datasource" db:"datasource"`
+	Kind            string    `json:"kind" db:"kind"`
+	Operator        string    `json:"operator,omitempty" db:"operator"`
+	Threshold       float64   `json:"threshold" db:"threshold"`
+	DurationSeconds int64     `json:"durationSeconds" db:"duration_seconds"`
+	Enabled         bool      `json:"enabled" db:"enabled"`
+	CreatedAt       time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt       time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type AlertEvent struct {
+	ID             int64      `json:"id" db:"id"`
+	RuleID         int64      `json:"ruleId" db:"rule_id"`
+	Device         string     `json:"device" db:"device"`
+	DataSource     string     `json:"datasource" db:"datasource"`
+	State          string     `json:"state" db:"state"`
+	Message        string     `json:"message" db:"message"`
+	LastValue      *float64   `json:"lastValue,omitempty" db:"last_value"`
+	OpenedAt       time.Time  `json:"openedAt" db:"opened_at"`
+	AcknowledgedAt *time.Time `json:"acknowledgedAt,omitempty" db:"acknowledged_at"`
+	ResolvedAt     *time.Time `json:"resolvedAt,omitempty" db:"resolved_at"`
+	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
+}
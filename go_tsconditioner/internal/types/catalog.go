@@ -0,0 +1,23 @@
+package types
+
+import "time"
+
+// Nature d'une datasource : compteur cumulatif ou grandeur instantanée
+const (
+	DataSourceKindGauge   = "gauge"
+	DataSourceKindCounter = "counter"
+)
+
+// DataSourceMeta décrit une datasource d'un device (catalogue SQLite).
+type DataSourceMeta struct {
+	Device         string    `json:"device" db:"device"`
+	DataSource     string    `json:"datasource" db:"datasource"`
+	Label          string    `json:"label" db:"label"`
+	Unit           string    `json:"unit" db:"unit"`                  // unité d'ingénierie ("kW", "°C", ...)
+	PhysMin        *float64  `json:"physMin,omitempty" db:"phys_min"` // plage physique du capteur
+	PhysMax        *float64  `json:"physMax,omitempty" db:"phys_max"`
+	CadenceSeconds *int64    `json:"cadenceSeconds,omitempty" db:"cadence_seconds"` // période d'émission attendue
+	Kind           string    `json:"kind" db:"kind"`                                // "gauge" | "counter"
+	Timezone       string    `json:"timezone" db:"timezone"`                        // ex: "Europe/Luxembourg"
+	UpdatedAt      time.Time `json:"updatedAt" db:"updated_at"`
+}
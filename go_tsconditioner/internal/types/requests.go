@@ -27,6 +27,10 @@ type PolishingRequest struct {
 	Percent2    float64 `json:"percent2"`
 	Lvl2        float64 `json:"lvl2"`
 	Interp      string  `json:"interp"`
+	MaxPoints   int     `json:"maxPoints"`  // optionnel : réduit uniquement la vue renvoyée
+	Downsample  string  `json:"downsample"` // "lttb" (défaut) | "m4"
+	Device      string  `json:"device"`     // optionnel : fiche catalogue pour les bornes fixedOutbounds
+	DataSource  string  `json:"datasource"` // optionnel
 }
 type OneDeviceOneDatasourceRequest struct {
 	Device     string    `json:"device" binding:"required"`
@@ -34,4 +38,61 @@ type OneDeviceOneDatasourceRequest struct {
 	From       time.Time `json:"from"`
 	To         time.Time `json:"to"`
 	Limit      int       `json:"limit"`
+	MaxPoints  int       `json:"maxPoints"`  // optionnel : réduit uniquement la vue renvoyée
+	Downsample string    `json:"downsample"` // "lttb" (défaut) | "m4"
+}
+type AlertRuleRequest struct {
+	Name            string  `json:"name"`
+	Device          string  `json:"device" binding:"required"`
+	DataSource      string  `json:"datasource" binding:"required"`
+	Kind            string  `json:"kind" binding:"required"` // "threshold", "rateofchange", "nodata"
+	Operator        string  `json:"operator"`                // "above" | "below" (threshold uniquement)
+	Threshold       float64 `json:"threshold"`
+	DurationSeconds int64   `json:"durationSeconds" binding:"required"`
+	Enabled         *bool   `json:"enabled"` // optionnel, défaut true
+}
+type SaveTimeSeriesRequest struct {
+	MemId      uint64            `json:"memId" binding:"required"`
+	Name       string            `json:"name"` // optionnel, défaut : nom de la série en mémoire
+	Device     string            `json:"device" binding:"required"`
+	Serial     string            `json:"serial"`
+	DataSource string            `json:"datasource" binding:"required"`
+	Polishing  *PolishingRequest `json:"polishing"` // paramètres exacts ayant produit la série
+}
+type AggregatedRangeRequest struct {
+	Device        string    `json:"device" binding:"required"`
+	DataSource    string    `json:"datasource" binding:"required"`
+	From          time.Time `json:"from" binding:"required"`
+	To            time.Time `json:"to" binding:"required"`
+	TargetPoints  int       `json:"targetPoints"`  // nombre de buckets visé (défaut 1000)
+	BucketSeconds int64     `json:"bucketSeconds"` // optionnel : force la taille du bucket
+}
+type CatalogEntryRequest struct {
+	Label          string   `json:"label"`
+	Unit           string   `json:"unit"`
+	PhysMin        *float64 `json:"physMin"`
+	PhysMax        *float64 `json:"physMax"`
+	CadenceSeconds *int64   `json:"cadenceSeconds"`
+	Kind           string   `json:"kind"` // "gauge" (défaut) | "counter"
+	Timezone       string   `json:"timezone"`
+}
+type EnergyRequest struct {
+	MemId         uint64 `json:"memId" binding:"required"`
+	Op            string `json:"op" binding:"required"` // "integrate" (puissance -> énergie) | "differentiate" (compteur -> puissance)
+	Method        string `json:"method"`                // integrate : "trapezoid" (défaut) | "step"
+	MaxGapSeconds int64  `json:"maxGapSeconds"`         // optionnel : intervalle plus long = trou
+}
+type DegreeDaysRequest struct {
+	Device          string     `json:"device" binding:"required"`
+	Consumption     string     `json:"consumption" binding:"required"` // datasource de consommation
+	Temperature     string     `json:"temperature" binding:"required"` // datasource de température extérieure
+	From            time.Time  `json:"from" binding:"required"`
+	To              time.Time  `json:"to" binding:"required"`
+	ConsumptionKind string     `json:"consumptionKind"` // "counter" | "power" ; défaut : catalogue, sinon "counter"
+	Model           string     `json:"model"`           // "heating" (défaut) | "cooling" | "both"
+	BaseHeat        *float64   `json:"baseHeat"`        // °C, optionnel : recherchée sinon
+	BaseCool        *float64   `json:"baseCool"`
+	Timezone        string     `json:"timezone"`   // défaut : catalogue de la température, sinon heure locale
+	NormalFrom      *time.Time `json:"normalFrom"` // période de référence pour la normalisation (défaut [from, to])
+	NormalTo        *time.Time `json:"normalTo"`
 }
@@ -0,0 +1,13 @@
+package types
+
+// SavedTimeSeriesInfo décrit une série persistée dans la table timeseries.
+type SavedTimeSeriesInfo struct {
+	ID         int64             `json:"id"`
+	Name       string            `json:"name"`
+	Unit       string            `json:"unit,omitempty"`
+	Device     string            `json:"device"`
+	Serial     *string           `json:"serial,omitempty"`
+	DataSource string            `json:"datasource"`
+	Points     int               `json:"points"`
+	Polishing  *PolishingRequest `json:"polishing,omitempty"`
+}